@@ -33,6 +33,10 @@ type EmqxBrokerSpec struct {
 
 	// ServiceTemplate defines a logical set of ports and a policy by which to access them
 	ServiceTemplate ServiceTemplate `json:"serviceTemplate,omitempty"`
+
+	// Indicates that the EMQX cluster is paused and will not be reconciled by the operator,
+	// only the status of the EMQX custom resource is updated.
+	Paused bool `json:"paused,omitempty"`
 }
 
 func (s *EmqxBrokerSpec) GetReplicas() *int32 {
@@ -66,6 +70,14 @@ func (s *EmqxBrokerSpec) SetServiceTemplate(serviceTemplate ServiceTemplate) {
 	s.ServiceTemplate = serviceTemplate
 }
 
+func (s *EmqxBrokerSpec) GetPaused() bool {
+	return s.Paused
+}
+
+func (s *EmqxBrokerSpec) SetPaused(paused bool) {
+	s.Paused = paused
+}
+
 // EmqxBrokerStatus defines the observed state of EmqxBroker
 type EmqxBrokerStatus struct {
 	// Represents the latest available observations of a EMQX current state.
@@ -78,6 +90,14 @@ type EmqxBrokerStatus struct {
 	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
 
 	CurrentStatefulSetVersion string `json:"currentStatefulSetVersion,omitempty"`
+	// Active alarms of the EMQX cluster
+	Alarms []EmqxAlarm `json:"alarms,omitempty"`
+	// The EMQX versions running on the nodes, more than one version is separated by commas during the update
+	CurrentVersion string `json:"currentVersion,omitempty"`
+	// The ready and desired replicas, like 2/3
+	Ready string `json:"ready,omitempty"`
+	// The number of MQTT connections of all EMQX nodes
+	Connections int64 `json:"connections,omitempty"`
 }
 
 func (s *EmqxBrokerStatus) GetReplicas() int32 {
@@ -112,6 +132,38 @@ func (s *EmqxBrokerStatus) SetCurrentStatefulSetVersion(version string) {
 	s.CurrentStatefulSetVersion = version
 }
 
+func (s *EmqxBrokerStatus) GetAlarms() []EmqxAlarm {
+	return s.Alarms
+}
+
+func (s *EmqxBrokerStatus) SetAlarms(alarms []EmqxAlarm) {
+	s.Alarms = alarms
+}
+
+func (s *EmqxBrokerStatus) GetCurrentVersion() string {
+	return s.CurrentVersion
+}
+
+func (s *EmqxBrokerStatus) SetCurrentVersion(version string) {
+	s.CurrentVersion = version
+}
+
+func (s *EmqxBrokerStatus) GetReady() string {
+	return s.Ready
+}
+
+func (s *EmqxBrokerStatus) SetReady(ready string) {
+	s.Ready = ready
+}
+
+func (s *EmqxBrokerStatus) GetConnections() int64 {
+	return s.Connections
+}
+
+func (s *EmqxBrokerStatus) SetConnections(connections int64) {
+	s.Connections = connections
+}
+
 func (s *EmqxBrokerStatus) GetConditions() []Condition {
 	return s.Conditions
 }
@@ -129,6 +181,11 @@ func (s *EmqxBrokerStatus) AddCondition(condType ConditionType, status corev1.Co
 //+kubebuilder:subresource:status
 //+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas
 //+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.currentVersion"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready"
+//+kubebuilder:printcolumn:name="Connections",type="integer",JSONPath=".status.connections"
+//+kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.currentStatefulSetVersion"
+//+kubebuilder:printcolumn:name="Image Version",type="string",JSONPath=".spec.template.spec.emqxContainer.image.version",priority=1
 //+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.status==\"True\")].type"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -69,6 +69,10 @@ type EmqxEnterpriseSpec struct {
 
 	// ServiceTemplate defines a logical set of ports and a policy by which to access them
 	ServiceTemplate ServiceTemplate `json:"serviceTemplate,omitempty"`
+
+	// Indicates that the EMQX cluster is paused and will not be reconciled by the operator,
+	// only the status of the EMQX custom resource is updated.
+	Paused bool `json:"paused,omitempty"`
 }
 
 func (s *EmqxEnterpriseSpec) GetReplicas() *int32 {
@@ -102,6 +106,14 @@ func (s *EmqxEnterpriseSpec) SetServiceTemplate(serviceTemplate ServiceTemplate)
 	s.ServiceTemplate = serviceTemplate
 }
 
+func (s *EmqxEnterpriseSpec) GetPaused() bool {
+	return s.Paused
+}
+
+func (s *EmqxEnterpriseSpec) SetPaused(paused bool) {
+	s.Paused = paused
+}
+
 // EmqxEnterpriseStatus defines the observed state of EmqxEnterprise
 type EmqxEnterpriseStatus struct {
 	// Represents the latest available observations of a EMQX current state.
@@ -114,6 +126,14 @@ type EmqxEnterpriseStatus struct {
 	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
 
 	CurrentStatefulSetVersion string `json:"currentStatefulSetVersion,omitempty"`
+	// Active alarms of the EMQX cluster
+	Alarms []EmqxAlarm `json:"alarms,omitempty"`
+	// The EMQX versions running on the nodes, more than one version is separated by commas during the update
+	CurrentVersion string `json:"currentVersion,omitempty"`
+	// The ready and desired replicas, like 2/3
+	Ready string `json:"ready,omitempty"`
+	// The number of MQTT connections of all EMQX nodes
+	Connections int64 `json:"connections,omitempty"`
 
 	EmqxBlueGreenUpdateStatus *EmqxBlueGreenUpdateStatus `json:"blueGreenUpdateStatus,omitempty"`
 }
@@ -150,6 +170,38 @@ func (s *EmqxEnterpriseStatus) SetEmqxNodes(nodes []EmqxNode) {
 	s.EmqxNodes = nodes
 }
 
+func (s *EmqxEnterpriseStatus) GetAlarms() []EmqxAlarm {
+	return s.Alarms
+}
+
+func (s *EmqxEnterpriseStatus) SetAlarms(alarms []EmqxAlarm) {
+	s.Alarms = alarms
+}
+
+func (s *EmqxEnterpriseStatus) GetCurrentVersion() string {
+	return s.CurrentVersion
+}
+
+func (s *EmqxEnterpriseStatus) SetCurrentVersion(version string) {
+	s.CurrentVersion = version
+}
+
+func (s *EmqxEnterpriseStatus) GetReady() string {
+	return s.Ready
+}
+
+func (s *EmqxEnterpriseStatus) SetReady(ready string) {
+	s.Ready = ready
+}
+
+func (s *EmqxEnterpriseStatus) GetConnections() int64 {
+	return s.Connections
+}
+
+func (s *EmqxEnterpriseStatus) SetConnections(connections int64) {
+	s.Connections = connections
+}
+
 func (s *EmqxEnterpriseStatus) GetConditions() []Condition {
 	return s.Conditions
 }
@@ -167,6 +219,11 @@ func (s *EmqxEnterpriseStatus) AddCondition(condType ConditionType, status corev
 //+kubebuilder:subresource:status
 //+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas
 //+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.currentVersion"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready"
+//+kubebuilder:printcolumn:name="Connections",type="integer",JSONPath=".status.connections"
+//+kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.currentStatefulSetVersion"
+//+kubebuilder:printcolumn:name="Image Version",type="string",JSONPath=".spec.template.spec.emqxContainer.image.version",priority=1
 //+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.status==\"True\")].type"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -131,7 +131,11 @@ type RebalanceState struct {
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Instance",type="string",JSONPath=".spec.instanceName"
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Coordinator",type="string",JSONPath=".status.rebalanceStates[*].coordinator_node"
+// +kubebuilder:printcolumn:name="Started",type="date",JSONPath=".status.startedTime"
+// +kubebuilder:printcolumn:name="Completed",type="date",JSONPath=".status.completedTime",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // Rebalance is the Schema for the rebalances API
 type Rebalance struct {
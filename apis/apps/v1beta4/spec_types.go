@@ -18,6 +18,9 @@ type EmqxSpec interface {
 
 	GetServiceTemplate() ServiceTemplate
 	SetServiceTemplate(ServiceTemplate)
+
+	GetPaused() bool
+	SetPaused(bool)
 }
 
 type ServiceTemplate struct {
@@ -201,6 +204,31 @@ type EmqxContainer struct {
 	// EMQX bootstrap user
 	// Cannot be updated.
 	BootstrapAPIKeys []BootstrapAPIKey `json:"bootstrapAPIKeys,omitempty"`
+	// ManagementAPI describes how the operator requests the EMQX management API,
+	// by default it is requested by the HTTP management listener on port 8081.
+	ManagementAPI *ManagementAPI `json:"managementAPI,omitempty"`
+}
+
+type ManagementAPI struct {
+	// The scheme of the EMQX management API, enum: "http" "https".
+	// The HTTPS management listener must be enabled by management.listener.https in emqxConfig,
+	// it is requested on port 8082 if the port is not set.
+	// Defaults to http.
+	//+kubebuilder:validation:Enum=http;https
+	Scheme string `json:"scheme,omitempty"`
+	// The TLS settings to request the EMQX management API over HTTPS.
+	TLS *ManagementAPITLS `json:"tls,omitempty"`
+}
+
+type ManagementAPITLS struct {
+	// The name of the secret that contains the CA bundle in ca.crt to verify the certificate of EMQX,
+	// and the client certificate and key in tls.crt and tls.key if EMQX verifies the client.
+	// The system CA bundle is used if it is not set.
+	SecretName string `json:"secretName,omitempty"`
+	// The server name to verify the certificate of EMQX.
+	ServerName string `json:"serverName,omitempty"`
+	// Skip verifying the certificate of EMQX, not recommended.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
 }
 
 type BootstrapAPIKey struct {
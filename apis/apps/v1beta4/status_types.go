@@ -36,6 +36,8 @@ type ConditionType string
 const (
 	ConditionRunning           ConditionType = "Running"
 	ConditionBlueGreenUpdating ConditionType = "BlueGreenUpdating"
+	ConditionReconcilePaused   ConditionType = "ReconcilePaused"
+	ConditionAlarmsActive      ConditionType = "AlarmsActive"
 )
 
 // +kubebuilder:object:generate=false
@@ -48,6 +50,14 @@ type EmqxStatus interface {
 	SetEmqxNodes(nodes []EmqxNode)
 	GetCurrentStatefulSetVersion() string
 	SetCurrentStatefulSetVersion(version string)
+	GetAlarms() []EmqxAlarm
+	SetAlarms(alarms []EmqxAlarm)
+	GetCurrentVersion() string
+	SetCurrentVersion(version string)
+	GetReady() string
+	SetReady(ready string)
+	GetConnections() int64
+	SetConnections(connections int64)
 	GetConditions() []Condition
 	AddCondition(condType ConditionType, status corev1.ConditionStatus, reason, message string)
 }
@@ -65,6 +75,15 @@ type EmqxNode struct {
 	Connections int64 `json:"connections,omitempty"`
 }
 
+type EmqxAlarm struct {
+	// The node where the alarm is activated
+	Node string `json:"node,omitempty"`
+	// Alarm name
+	Name string `json:"name,omitempty"`
+	// Alarm message
+	Message string `json:"message,omitempty"`
+}
+
 type EmqxEvacuationStats struct {
 	InitialSessions  *int32 `json:"initial_sessions,omitempty"`
 	InitialConnected *int32 `json:"initial_connected,omitempty"`
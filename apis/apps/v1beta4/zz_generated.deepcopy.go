@@ -58,6 +58,21 @@ func (in *Condition) DeepCopy() *Condition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmqxAlarm) DeepCopyInto(out *EmqxAlarm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmqxAlarm.
+func (in *EmqxAlarm) DeepCopy() *EmqxAlarm {
+	if in == nil {
+		return nil
+	}
+	out := new(EmqxAlarm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EmqxBlueGreenUpdate) DeepCopyInto(out *EmqxBlueGreenUpdate) {
 	*out = *in
@@ -201,6 +216,11 @@ func (in *EmqxBrokerStatus) DeepCopyInto(out *EmqxBrokerStatus) {
 		*out = make([]EmqxNode, len(*in))
 		copy(*out, *in)
 	}
+	if in.Alarms != nil {
+		in, out := &in.Alarms, &out.Alarms
+		*out = make([]EmqxAlarm, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmqxBrokerStatus.
@@ -322,6 +342,11 @@ func (in *EmqxContainer) DeepCopyInto(out *EmqxContainer) {
 		*out = make([]BootstrapAPIKey, len(*in))
 		copy(*out, *in)
 	}
+	if in.ManagementAPI != nil {
+		in, out := &in.ManagementAPI, &out.ManagementAPI
+		*out = new(ManagementAPI)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmqxContainer.
@@ -441,6 +466,11 @@ func (in *EmqxEnterpriseStatus) DeepCopyInto(out *EmqxEnterpriseStatus) {
 		*out = make([]EmqxNode, len(*in))
 		copy(*out, *in)
 	}
+	if in.Alarms != nil {
+		in, out := &in.Alarms, &out.Alarms
+		*out = make([]EmqxAlarm, len(*in))
+		copy(*out, *in)
+	}
 	if in.EmqxBlueGreenUpdateStatus != nil {
 		in, out := &in.EmqxBlueGreenUpdateStatus, &out.EmqxBlueGreenUpdateStatus
 		*out = new(EmqxBlueGreenUpdateStatus)
@@ -756,6 +786,41 @@ func (in *EvacuationStrategy) DeepCopy() *EvacuationStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagementAPI) DeepCopyInto(out *ManagementAPI) {
+	*out = *in
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ManagementAPITLS)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagementAPI.
+func (in *ManagementAPI) DeepCopy() *ManagementAPI {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagementAPI)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagementAPITLS) DeepCopyInto(out *ManagementAPITLS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagementAPITLS.
+func (in *ManagementAPITLS) DeepCopy() *ManagementAPITLS {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagementAPITLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Rebalance) DeepCopyInto(out *Rebalance) {
 	*out = *in
@@ -14,6 +14,18 @@ const (
 	PodTemplateHashLabelKey string = "apps.emqx.io/pod-template-hash"
 )
 
+const (
+	// annotations
+	// The hash of the pod template the pod was created from, used by the RollingUpdate strategy
+	// to find the pods that still run an outdated template.
+	PodTemplateRevisionAnnotationKey string = "apps.emqx.io/pod-template-revision"
+	// The revision of the new replicaSet whose update is resumed from the canary step.
+	ResumeUpdateAnnotationKey string = "apps.emqx.io/resume-update"
+	// Changing the value rotates the API key the operator uses to request the EMQX management API,
+	// like a timestamp set by a CronJob.
+	RotateAPIKeyAnnotationKey string = "apps.emqx.io/rotate-api-key"
+)
+
 const (
 	// https://kubernetes.io/docs/concepts/workloads/pods/pod-lifecycle/#pod-readiness-gate
 	PodOnServing corev1.PodConditionType = "apps.emqx.io/on-serving"
@@ -19,6 +19,7 @@ package v2alpha2
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type ServiceTemplate struct {
@@ -51,6 +52,11 @@ type EMQXReplicantTemplateSpec struct {
 	// If specified, the pod's tolerations.
 	// The pod this Toleration is attached to tolerates any taint that matches the triple <key,value,effect> using the matching operator .
 	ToleRations []corev1.Toleration `json:"toleRations,omitempty"`
+	// TopologySpreadConstraints describes how a group of pods ought to spread across topology
+	// domains. Scheduler will schedule pods in a way which abides by the constraints.
+	// All topologySpreadConstraints are ANDed.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
 	// Replicas is the desired number of replicas of the given Template.
 	// These are replicas in the sense that they are instantiations of the
 	// same Template, but individual replicas also have a consistent identity.
@@ -147,6 +153,21 @@ type EMQXReplicantTemplateSpec struct {
 	// Actions that the management system should take in response to container lifecycle events.
 	// Cannot be updated.
 	Lifecycle *corev1.Lifecycle `json:"lifecycle,omitempty" protobuf:"bytes,12,opt,name=lifecycle"`
+	// PodDisruptionBudget limits the number of pods that are down simultaneously from voluntary disruptions.
+	// If not set, the operator derives it from the replicas.
+	// More info: https://kubernetes.io/docs/tasks/run-application/configure-pdb/
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+}
+
+type PodDisruptionBudgetSpec struct {
+	// An eviction is allowed if at least "minAvailable" pods will still be available after the eviction.
+	// Value can be an absolute number (ex: 5) or a percentage of desired nodes (ex: 10%).
+	// Mutually exclusive with maxUnavailable.
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// An eviction is allowed if at most "maxUnavailable" pods are unavailable after the eviction.
+	// Value can be an absolute number (ex: 5) or a percentage of desired nodes (ex: 10%).
+	// Mutually exclusive with minAvailable.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
 }
 
 type EMQXCoreTemplateSpec struct {
@@ -179,6 +200,26 @@ type BootstrapAPIKey struct {
 	Secret string `json:"secret"`
 }
 
+type ManagementAPI struct {
+	// The scheme of the EMQX management API, enum: "http" "https".
+	// Defaults to https if the HTTP dashboard listener is disabled and the HTTPS dashboard listener is enabled in the bootstrap config, or http otherwise.
+	//+kubebuilder:validation:Enum=http;https
+	Scheme string `json:"scheme,omitempty"`
+	// The TLS settings to request the EMQX management API over HTTPS.
+	TLS *ManagementAPITLS `json:"tls,omitempty"`
+}
+
+type ManagementAPITLS struct {
+	// The name of the secret that contains the CA bundle in ca.crt to verify the certificate of EMQX,
+	// and the client certificate and key in tls.crt and tls.key if EMQX verifies the client.
+	// The system CA bundle is used if it is not set.
+	SecretName string `json:"secretName,omitempty"`
+	// The server name to verify the certificate of EMQX, the operator requests the EMQX nodes by the pod IPs.
+	ServerName string `json:"serverName,omitempty"`
+	// Skip verifying the certificate of EMQX, not recommended.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
 type EvacuationStrategy struct {
 	//+kubebuilder:validation:Minimum=0
 	WaitTakeover int32 `json:"waitTakeover,omitempty"`
@@ -190,14 +231,60 @@ type EvacuationStrategy struct {
 	SessEvictRate int32 `json:"sessEvictRate,omitempty"`
 }
 
+type RollingUpdateStrategy struct {
+	// The maximum number of EMQX nodes that can be unavailable during the update.
+	// Value can be an absolute number (ex: 5) or a percentage of desired nodes (ex: 10%).
+	// Absolute number is calculated from percentage by rounding down.
+	// This can not be 0 if MaxSurge is 0.
+	// Defaults to 1.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// The maximum number of EMQX replicant nodes that can be scheduled above the desired number of nodes.
+	// Value can be an absolute number (ex: 5) or a percentage of desired nodes (ex: 10%).
+	// Absolute number is calculated from percentage by rounding up.
+	// Just work in EMQX replicant nodes, the EMQX core nodes have stable network identities and are never surged.
+	// Defaults to 0.
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+}
+
+type CanaryStrategy struct {
+	// The number of EMQX replicant nodes of the new revision to bring up before the update is held.
+	// Value can be an absolute number (ex: 1) or a percentage of desired nodes (ex: 10%).
+	// Absolute number is calculated from percentage by rounding up.
+	// Defaults to 1.
+	Replicas *intstr.IntOrString `json:"replicas,omitempty"`
+}
+
+const (
+	// Create a new statefulSet and replicaSet for the new pod template, then scale down the old ones.
+	RecreateUpdateStrategyType string = "Recreate"
+	// Update the pods of the existing statefulSet and replicaSet in place, a bounded number at a time.
+	RollingUpdateStrategyType string = "RollingUpdate"
+)
+
 type UpdateStrategy struct {
-	//+kubebuilder:validation:Enum=Recreate
+	//+kubebuilder:validation:Enum=Recreate;RollingUpdate
 	//+kubebuilder:default=Recreate
 	Type string `json:"type,omitempty"`
+	// Rolling update config params. Present only if Type = RollingUpdate.
+	RollingUpdate *RollingUpdateStrategy `json:"rollingUpdate,omitempty"`
+	// Canary config params for the EMQX replicant nodes.
+	// The new replicaSet is scaled up to the canary replicas, then the update is held until it is resumed by annotating
+	// the EMQX custom resource with `apps.emqx.io/resume-update: <revision of the new replicaSet>`.
+	// Just work in Recreate update strategy.
+	Canary *CanaryStrategy `json:"canary,omitempty"`
 	// Number of seconds before evacuation connection start.
 	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
 	// Number of seconds before evacuation connection timeout.
 	EvacuationStrategy EvacuationStrategy `json:"evacuationStrategy,omitempty"`
+	// The maximum time in seconds for a new revision of EMQX nodes to become ready before it is considered to be failed.
+	// The failed revision will be surfaced with a condition of type Progressing, status False and reason ProgressDeadlineExceeded.
+	// Defaults to no deadline.
+	//+kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// Roll back to the last ready revision when the new revision exceeds the progress deadline,
+	// the workload of the failed revision will be deleted.
+	// Just work in Recreate update strategy and requires progressDeadlineSeconds.
+	AutoRollback bool `json:"autoRollback,omitempty"`
 }
 
 // EMQXSpec defines the desired state of EMQX
@@ -216,14 +303,32 @@ type EMQXSpec struct {
 	// More info: https://kubernetes.io/docs/concepts/containers/images#specifying-imagepullsecrets-on-a-pod
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 
+	// Indicates that the EMQX cluster is paused and will not be reconciled by the operator,
+	// only the status of the EMQX custom resource is updated.
+	Paused bool `json:"paused,omitempty"`
+
+	// Indicates that the EMQX nodes should be spread across zones and hosts,
+	// the operator injects default topology spread constraints keyed on the instance and db-role labels
+	// for the topology keys that are not set in topologySpreadConstraints of the template.
+	ZoneAware bool `json:"zoneAware,omitempty"`
+
 	// UpdateStrategy is the object that describes the EMQX blue-green update strategy
 	UpdateStrategy UpdateStrategy `json:"updateStrategy,omitempty"`
+	// The number of old statefulSets and replicaSets to retain to allow rollback.
+	// Only the old statefulSets and replicaSets that have been scaled down to 0 will be deleted.
+	// Defaults to 3.
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:default:=3
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
 	// EMQX bootstrap user
 	// Cannot be updated.
 	BootstrapAPIKeys []BootstrapAPIKey `json:"bootstrapAPIKeys,omitempty"`
 	// EMQX bootstrap config, HOCON style, like emqx.conf
 	// Cannot be updated.
 	BootstrapConfig string `json:"bootstrapConfig,omitempty"`
+	// ManagementAPI describes how the operator requests the EMQX management API,
+	// by default it is requested by the dashboard listener in the bootstrap config.
+	ManagementAPI *ManagementAPI `json:"managementAPI,omitempty"`
 
 	DashboardServiceTemplate corev1.Service `json:"dashboardServiceTemplate,omitempty"`
 	// ListenersServiceTemplate is the object that describes the EMQX listener service that will be created
@@ -244,6 +349,13 @@ type EMQXSpec struct {
 //+kubebuilder:storageversion
 //+kubebuilder:subresource:status
 //+kubebuilder:subresource:scale:specpath=.spec.replicantTemplate.spec.replicas,statuspath=.status.replicantNodeReplicas
+//+kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.currentVersion"
+//+kubebuilder:printcolumn:name="Core",type="string",JSONPath=".status.coreNodesStatus.ready"
+//+kubebuilder:printcolumn:name="Replicant",type="string",JSONPath=".status.replicantNodesStatus.ready"
+//+kubebuilder:printcolumn:name="Connections",type="integer",JSONPath=".status.connections"
+//+kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.coreNodesStatus.currentRevision"
+//+kubebuilder:printcolumn:name="Replicant Revision",type="string",JSONPath=".status.replicantNodesStatus.currentRevision",priority=1
+//+kubebuilder:printcolumn:name="Image",type="string",JSONPath=".spec.image",priority=1
 //+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.status==\"True\")].type"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -58,6 +58,7 @@ func (r *EMQX) Default() {
 	r.defaultContainerPort()
 	r.defaultProbe()
 	r.defaultSecurityContext()
+	r.defaultUpdateStrategy()
 }
 
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
@@ -87,6 +88,16 @@ func (r *EMQX) ValidateCreate() error {
 		return err
 	}
 
+	if err := r.validateUpdateStrategy(); err != nil {
+		emqxlog.Error(err, "validate create failed")
+		return err
+	}
+
+	if err := r.validatePodDisruptionBudget(); err != nil {
+		emqxlog.Error(err, "validate create failed")
+		return err
+	}
+
 	return nil
 }
 
@@ -127,6 +138,16 @@ func (r *EMQX) ValidateUpdate(old runtime.Object) error {
 		return err
 	}
 
+	if err := r.validateUpdateStrategy(); err != nil {
+		emqxlog.Error(err, "validate update failed")
+		return err
+	}
+
+	if err := r.validatePodDisruptionBudget(); err != nil {
+		emqxlog.Error(err, "validate update failed")
+		return err
+	}
+
 	return nil
 }
 
@@ -138,6 +159,71 @@ func (r *EMQX) ValidateDelete() error {
 	return nil
 }
 
+func (r *EMQX) validateUpdateStrategy() error {
+	if r.Spec.UpdateStrategy.AutoRollback && r.Spec.UpdateStrategy.ProgressDeadlineSeconds == nil {
+		return emperror.New("autoRollback requires progressDeadlineSeconds")
+	}
+	if r.Spec.UpdateStrategy.AutoRollback && r.Spec.UpdateStrategy.Type == RollingUpdateStrategyType {
+		return emperror.New("autoRollback just work in Recreate update strategy")
+	}
+
+	if canary := r.Spec.UpdateStrategy.Canary; canary != nil {
+		if r.Spec.UpdateStrategy.Type == RollingUpdateStrategyType {
+			return emperror.New("canary just work in Recreate update strategy")
+		}
+		if canary.Replicas != nil {
+			replicas, err := intstr.GetScaledValueFromIntOrPercent(canary.Replicas, 100, true)
+			if err != nil || replicas <= 0 {
+				return emperror.Errorf("invalid canary replicas: %s", canary.Replicas.String())
+			}
+		}
+	}
+
+	if r.Spec.UpdateStrategy.Type != RollingUpdateStrategyType || r.Spec.UpdateStrategy.RollingUpdate == nil {
+		return nil
+	}
+
+	var maxUnavailable, maxSurge int
+	var err error
+	if r.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable != nil {
+		maxUnavailable, err = intstr.GetScaledValueFromIntOrPercent(r.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable, 100, false)
+		if err != nil || maxUnavailable < 0 {
+			return emperror.Errorf("invalid maxUnavailable: %s", r.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable.String())
+		}
+	}
+	if r.Spec.UpdateStrategy.RollingUpdate.MaxSurge != nil {
+		maxSurge, err = intstr.GetScaledValueFromIntOrPercent(r.Spec.UpdateStrategy.RollingUpdate.MaxSurge, 100, true)
+		if err != nil || maxSurge < 0 {
+			return emperror.Errorf("invalid maxSurge: %s", r.Spec.UpdateStrategy.RollingUpdate.MaxSurge.String())
+		}
+	}
+	if maxUnavailable == 0 && maxSurge == 0 {
+		return emperror.New("maxUnavailable and maxSurge of the rolling update strategy cannot both be 0")
+	}
+	return nil
+}
+
+func (r *EMQX) validatePodDisruptionBudget() error {
+	pdbList := map[string]*PodDisruptionBudgetSpec{
+		"coreTemplate": r.Spec.CoreTemplate.Spec.PodDisruptionBudget,
+	}
+	if r.Spec.ReplicantTemplate != nil {
+		pdbList["replicantTemplate"] = r.Spec.ReplicantTemplate.Spec.PodDisruptionBudget
+	}
+	for template, pdb := range pdbList {
+		if pdb == nil {
+			continue
+		}
+		if pdb.MinAvailable != nil && pdb.MaxUnavailable != nil {
+			return emperror.Errorf("minAvailable and maxUnavailable of the %s podDisruptionBudget cannot both be set", template)
+		}
+		if pdb.MinAvailable == nil && pdb.MaxUnavailable == nil {
+			return emperror.Errorf("one of minAvailable and maxUnavailable of the %s podDisruptionBudget must be set", template)
+		}
+	}
+	return nil
+}
+
 func (r *EMQX) defaultNames() {
 	if r.Name == "" {
 		r.Name = "emqx"
@@ -358,3 +444,21 @@ func (r *EMQX) defaultSecurityContext() {
 		}
 	}
 }
+
+func (r *EMQX) defaultUpdateStrategy() {
+	if r.Spec.UpdateStrategy.Type != RollingUpdateStrategyType {
+		return
+	}
+
+	if r.Spec.UpdateStrategy.RollingUpdate == nil {
+		r.Spec.UpdateStrategy.RollingUpdate = &RollingUpdateStrategy{}
+	}
+	if r.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable == nil {
+		maxUnavailable := intstr.FromInt(1)
+		r.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable = &maxUnavailable
+	}
+	if r.Spec.UpdateStrategy.RollingUpdate.MaxSurge == nil {
+		maxSurge := intstr.FromInt(0)
+		r.Spec.UpdateStrategy.RollingUpdate.MaxSurge = &maxSurge
+	}
+}
@@ -60,6 +60,60 @@ func TestValidateCreate(t *testing.T) {
 
 	instance.Spec.BootstrapConfig = `sql = "SELECT * FROM "t/#""`
 	assert.Nil(t, instance.ValidateCreate())
+
+	instance.Spec.UpdateStrategy = UpdateStrategy{
+		Type: RollingUpdateStrategyType,
+		RollingUpdate: &RollingUpdateStrategy{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "0%"},
+			MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: 0},
+		},
+	}
+	assert.ErrorContains(t, instance.ValidateCreate(), "maxUnavailable and maxSurge of the rolling update strategy cannot both be 0")
+
+	instance.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable = &intstr.IntOrString{Type: intstr.String, StrVal: "fake"}
+	assert.ErrorContains(t, instance.ValidateCreate(), "invalid maxUnavailable")
+
+	instance.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable = &intstr.IntOrString{Type: intstr.String, StrVal: "25%"}
+	assert.Nil(t, instance.ValidateCreate())
+
+	instance.Spec.UpdateStrategy = UpdateStrategy{
+		Type:         RecreateUpdateStrategyType,
+		AutoRollback: true,
+	}
+	assert.ErrorContains(t, instance.ValidateCreate(), "autoRollback requires progressDeadlineSeconds")
+
+	instance.Spec.UpdateStrategy.ProgressDeadlineSeconds = pointer.Int32(600)
+	assert.Nil(t, instance.ValidateCreate())
+
+	instance.Spec.UpdateStrategy.Type = RollingUpdateStrategyType
+	assert.ErrorContains(t, instance.ValidateCreate(), "autoRollback just work in Recreate update strategy")
+
+	instance.Spec.UpdateStrategy = UpdateStrategy{
+		Type: RecreateUpdateStrategyType,
+		Canary: &CanaryStrategy{
+			Replicas: &intstr.IntOrString{Type: intstr.Int, IntVal: 0},
+		},
+	}
+	assert.ErrorContains(t, instance.ValidateCreate(), "invalid canary replicas")
+
+	instance.Spec.UpdateStrategy.Canary.Replicas = &intstr.IntOrString{Type: intstr.String, StrVal: "10%"}
+	assert.Nil(t, instance.ValidateCreate())
+
+	instance.Spec.UpdateStrategy.Type = RollingUpdateStrategyType
+	assert.ErrorContains(t, instance.ValidateCreate(), "canary just work in Recreate update strategy")
+
+	instance.Spec.UpdateStrategy = UpdateStrategy{Type: RecreateUpdateStrategyType}
+	instance.Spec.CoreTemplate.Spec.PodDisruptionBudget = &PodDisruptionBudgetSpec{}
+	assert.ErrorContains(t, instance.ValidateCreate(), "one of minAvailable and maxUnavailable of the coreTemplate podDisruptionBudget must be set")
+
+	instance.Spec.CoreTemplate.Spec.PodDisruptionBudget = &PodDisruptionBudgetSpec{
+		MinAvailable:   &intstr.IntOrString{Type: intstr.Int, IntVal: 2},
+		MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+	}
+	assert.ErrorContains(t, instance.ValidateCreate(), "minAvailable and maxUnavailable of the coreTemplate podDisruptionBudget cannot both be set")
+
+	instance.Spec.CoreTemplate.Spec.PodDisruptionBudget.MaxUnavailable = nil
+	assert.Nil(t, instance.ValidateCreate())
 }
 
 func TestValidateUpdate(t *testing.T) {
@@ -516,3 +570,35 @@ func TestDefaultSecurityContext(t *testing.T) {
 		SupplementalGroups:  []int64{1000},
 	}, *instance.Spec.ReplicantTemplate.Spec.PodSecurityContext)
 }
+
+func TestDefaultUpdateStrategy(t *testing.T) {
+	t.Run("should not set rolling update for recreate", func(t *testing.T) {
+		instance := &EMQX{}
+		instance.Spec.UpdateStrategy.Type = RecreateUpdateStrategyType
+		instance.defaultUpdateStrategy()
+		assert.Nil(t, instance.Spec.UpdateStrategy.RollingUpdate)
+	})
+
+	t.Run("should set default rolling update", func(t *testing.T) {
+		instance := &EMQX{}
+		instance.Spec.UpdateStrategy.Type = RollingUpdateStrategyType
+		instance.defaultUpdateStrategy()
+		assert.Equal(t, &RollingUpdateStrategy{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+			MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: 0},
+		}, instance.Spec.UpdateStrategy.RollingUpdate)
+	})
+
+	t.Run("should not override rolling update", func(t *testing.T) {
+		instance := &EMQX{}
+		instance.Spec.UpdateStrategy.Type = RollingUpdateStrategyType
+		instance.Spec.UpdateStrategy.RollingUpdate = &RollingUpdateStrategy{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "50%"},
+		}
+		instance.defaultUpdateStrategy()
+		assert.Equal(t, &RollingUpdateStrategy{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "50%"},
+			MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: 0},
+		}, instance.Spec.UpdateStrategy.RollingUpdate)
+	})
+}
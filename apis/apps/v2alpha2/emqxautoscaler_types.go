@@ -0,0 +1,125 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AutoscalerMetricConnections scales by the average number of connected MQTT clients per replicant node
+	AutoscalerMetricConnections string = "Connections"
+	// AutoscalerMetricSessions scales by the average number of MQTT sessions per replicant node
+	AutoscalerMetricSessions string = "Sessions"
+)
+
+const (
+	// ScalingActive indicates that the autoscaler is able to fetch the metric of the EMQX cluster and compute the desired replicas
+	ScalingActive string = "ScalingActive"
+	// ScalingLimited indicates that the desired replicas is limited by minReplicas or maxReplicas
+	ScalingLimited string = "ScalingLimited"
+)
+
+// EMQXAutoscalerSpec defines the desired state of EMQXAutoscaler
+type EMQXAutoscalerSpec struct {
+	// InstanceName represents the name of EMQX custom resource in the same namespace,
+	// the EMQX custom resource must have replicant nodes.
+	//+kubebuilder:validation:Required
+	InstanceName string `json:"instanceName"`
+	// MinReplicas is the lower limit for the number of replicant nodes that the autoscaler can scale down to.
+	// Defaults to 1.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the upper limit for the number of replicant nodes that the autoscaler can scale up to.
+	// It cannot be less that minReplicas.
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// Metric is the metric of replicant nodes used for scaling, can be "Connections" or "Sessions".
+	// Connections means the live connections of EMQX node, Sessions means the sessions of EMQX node.
+	// Defaults to "Connections".
+	//+kubebuilder:validation:Enum=Connections;Sessions
+	//+kubebuilder:default:=Connections
+	Metric string `json:"metric,omitempty"`
+	// TargetAverageValue is the target value of the average of the metric across all replicant nodes.
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:Minimum=1
+	TargetAverageValue int64 `json:"targetAverageValue"`
+	// ScaleUpStabilizationWindowSeconds is the number of seconds for which past recommendations should be
+	// considered while scaling up, the lowest recommendation in the window is used.
+	// Defaults to 0, scale up immediately.
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:default:=0
+	ScaleUpStabilizationWindowSeconds *int32 `json:"scaleUpStabilizationWindowSeconds,omitempty"`
+	// ScaleDownStabilizationWindowSeconds is the number of seconds for which past recommendations should be
+	// considered while scaling down, the highest recommendation in the window is used.
+	// Defaults to 300 seconds.
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:default:=300
+	ScaleDownStabilizationWindowSeconds *int32 `json:"scaleDownStabilizationWindowSeconds,omitempty"`
+	// CooldownSeconds is the minimum number of seconds between two scaling operations.
+	// Defaults to 60 seconds.
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:default:=60
+	CooldownSeconds *int32 `json:"cooldownSeconds,omitempty"`
+}
+
+// EMQXAutoscalerStatus defines the observed state of EMQXAutoscaler
+type EMQXAutoscalerStatus struct {
+	// CurrentReplicas is the current number of replicant nodes, as last seen by the autoscaler.
+	CurrentReplicas int32 `json:"currentReplicas,omitempty"`
+	// DesiredReplicas is the desired number of replicant nodes, as last calculated by the autoscaler.
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+	// CurrentAverageValue is the current average of the metric across all replicant nodes.
+	CurrentAverageValue int64 `json:"currentAverageValue,omitempty"`
+	// LastScaleTime is the last time the autoscaler scaled the replicant nodes.
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+	// Represents the latest available observations of the autoscaler's current state.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Instance",type="string",JSONPath=".spec.instanceName"
+//+kubebuilder:printcolumn:name="Min",type="integer",JSONPath=".spec.minReplicas"
+//+kubebuilder:printcolumn:name="Max",type="integer",JSONPath=".spec.maxReplicas"
+//+kubebuilder:printcolumn:name="Current",type="integer",JSONPath=".status.currentReplicas"
+//+kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".status.desiredReplicas"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// EMQXAutoscaler is the Schema for the emqxautoscalers API
+type EMQXAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EMQXAutoscalerSpec   `json:"spec,omitempty"`
+	Status EMQXAutoscalerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// EMQXAutoscalerList contains a list of EMQXAutoscaler
+type EMQXAutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EMQXAutoscaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EMQXAutoscaler{}, &EMQXAutoscalerList{})
+}
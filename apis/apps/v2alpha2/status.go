@@ -20,6 +20,7 @@ import (
 	"sort"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EMQXStatus defines the observed state of EMQX
@@ -29,6 +30,32 @@ type EMQXStatus struct {
 
 	CoreNodesStatus      EMQXNodesStatus  `json:"coreNodesStatus,omitempty"`
 	ReplicantNodesStatus *EMQXNodesStatus `json:"replicantNodesStatus,omitempty"`
+
+	// Evacuation progress of the EMQX nodes during blue-green update, only for EMQX Enterprise
+	NodeEvacuationsStatus []NodeEvacuationStatus `json:"nodeEvacuationsStatus,omitempty"`
+
+	// The active alarms of the EMQX cluster, like high memory usage and license expiry
+	Alarms []EMQXAlarm `json:"alarms,omitempty"`
+
+	// A summary of the cluster-wide statistics of the EMQX cluster
+	Stats *EMQXStats `json:"stats,omitempty"`
+
+	// The EMQX versions running on the nodes, more than one version is separated by commas during the update
+	CurrentVersion string `json:"currentVersion,omitempty"`
+	// The number of connected MQTT clients of all EMQX nodes
+	Connections int64 `json:"connections,omitempty"`
+
+	// The API key the operator uses to request the EMQX management API, set after it is rotated
+	APIKeyRotation *APIKeyRotationStatus `json:"apiKeyRotation,omitempty"`
+}
+
+type APIKeyRotationStatus struct {
+	// The name of the API key in EMQX
+	Name string `json:"name,omitempty"`
+	// The value of the rotate-api-key annotation the last rotation was triggered by
+	Trigger string `json:"trigger,omitempty"`
+	// The time the old API key was revoked
+	LastRotationTime metav1.Time `json:"lastRotationTime,omitempty"`
 }
 
 type EMQXNodesStatus struct {
@@ -38,6 +65,17 @@ type EMQXNodesStatus struct {
 	ReadyReplicas   int32      `json:"readyReplicas,omitempty"`
 	CurrentRevision string     `json:"currentRevision,omitempty"`
 	CollisionCount  *int32     `json:"collisionCount,omitempty"`
+	// The ready and desired replicas, like 2/3
+	Ready string `json:"ready,omitempty"`
+	// The revision that exceeded the progress deadline and was rolled back,
+	// it will not be rolled out again until the pod template is changed.
+	FailedRevision string `json:"failedRevision,omitempty"`
+	// The revisions of the old statefulSets or replicaSets that are retained, sorted from oldest to newest
+	RetainedRevisions []string `json:"retainedRevisions,omitempty"`
+	// The names of the pods that are running but have not joined the EMQX cluster
+	PodsNotInCluster []string `json:"podsNotInCluster,omitempty"`
+	// The names of the EMQX nodes in the cluster that are not backed by any pod
+	NodesWithoutPod []string `json:"nodesWithoutPod,omitempty"`
 }
 
 type EMQXNode struct {
@@ -60,6 +98,76 @@ type EMQXNode struct {
 	// In EMQX's API of `/api/v5/nodes`, the `live_connections` field means the number of connected MQTT clients.
 	// THe `live_connections` just work in EMQX 5.1 or later.
 	Connections int64 `json:"live_connections,omitempty"`
+	// The name of the pod where the EMQX node is running.
+	PodName string `json:"podName,omitempty"`
+	// The UID of the pod where the EMQX node is running.
+	PodUID types.UID `json:"podUID,omitempty"`
+	// The IP of the pod where the EMQX node is running.
+	PodIP string `json:"podIP,omitempty"`
+	// The name of the Kubernetes node where the EMQX node is running.
+	KubernetesNode string `json:"kubernetesNode,omitempty"`
+	// The pod template hash of the pod where the EMQX node is running.
+	Revision string `json:"revision,omitempty"`
+	// The zone of the Kubernetes node where the EMQX node is running, from the topology.kubernetes.io/zone label.
+	Zone string `json:"zone,omitempty"`
+}
+
+type EMQXStats struct {
+	// Rate of the messages received by the cluster, units: messages/second
+	MessagesReceivedRate int64 `json:"messagesReceivedRate,omitempty"`
+	// Rate of the messages sent by the cluster, units: messages/second
+	MessagesSentRate int64 `json:"messagesSentRate,omitempty"`
+	// Rate of the messages dropped by the cluster, units: messages/second
+	MessagesDroppedRate int64 `json:"messagesDroppedRate,omitempty"`
+	// Number of subscriptions
+	Subscriptions int64 `json:"subscriptions,omitempty"`
+	// Number of topics
+	Topics int64 `json:"topics,omitempty"`
+	// Number of retained messages
+	RetainedMessages int64 `json:"retainedMessages,omitempty"`
+	// The time when the statistics were collected
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+type EMQXAlarm struct {
+	// The node where the alarm is activated, example: emqx@127.0.0.1
+	Node string `json:"node,omitempty"`
+	// Alarm name, example: high_system_memory_usage
+	Name string `json:"name,omitempty"`
+	// Alarm message
+	Message string `json:"message,omitempty"`
+	// The time when the alarm was activated, example: 2023-05-01T08:00:00.000+00:00
+	ActivateAt string `json:"activate_at,omitempty"`
+}
+
+type NodeEvacuationStatus struct {
+	// Evacuated node name, example: emqx@127.0.0.1
+	Node string `json:"node,omitempty"`
+	// Evacuation statistics
+	Stats NodeEvacuationStats `json:"stats,omitempty"`
+	// Evacuation state, enum: "waiting_takeover" "evicting_conns" "waiting_session" "evicting_sessions" "prohibiting"
+	State string `json:"state,omitempty"`
+	// Session recipients
+	SessionRecipients []string `json:"session_recipients,omitempty"`
+	// Sessions goal
+	SessionGoal int32 `json:"session_goal,omitempty"`
+	// Session eviction rate, units: sessions/second
+	SessionEvictionRate int32 `json:"session_eviction_rate,omitempty"`
+	// Connection goal
+	ConnectionGoal int32 `json:"connection_goal,omitempty"`
+	// Connection eviction rate, units: connections/second
+	ConnectionEvictionRate int32 `json:"connection_eviction_rate,omitempty"`
+}
+
+type NodeEvacuationStats struct {
+	// Initial number of sessions on the evacuated node
+	InitialSessions *int32 `json:"initial_sessions,omitempty"`
+	// Initial number of connected clients on the evacuated node
+	InitialConnected *int32 `json:"initial_connected,omitempty"`
+	// Current number of sessions on the evacuated node
+	CurrentSessions *int32 `json:"current_sessions,omitempty"`
+	// Current number of connected clients on the evacuated node
+	CurrentConnected *int32 `json:"current_connected,omitempty"`
 }
 
 const (
@@ -70,6 +178,28 @@ const (
 	ReplicantNodesReady       string = "ReplicantNodesReady"
 	Available                 string = "Available"
 	Ready                     string = "Ready"
+	// The EMQX cluster has been ready, but some EMQX nodes are not ready or have left the cluster now
+	Degraded string = "Degraded"
+)
+
+const (
+	// The reasons of the Degraded condition, and of the Available and Ready conditions when the cluster is degraded.
+	// The cluster is not available when the core nodes are not ready or the management API is unreachable.
+	CoreNodesNotReadyReason        string = "CoreNodesNotReady"
+	ReplicantNodesNotReadyReason   string = "ReplicantNodesNotReady"
+	NodeNotInClusterReason         string = "NodeNotInCluster"
+	ManagementAPIUnreachableReason string = "ManagementAPIUnreachable"
+)
+
+const (
+	Progressing     string = "Progressing"
+	UpdatePaused    string = "UpdatePaused"
+	ReconcilePaused string = "ReconcilePaused"
+	AlarmsActive    string = "AlarmsActive"
+	// The operator stops requesting the EMQX management API for a while after the requests keep failing
+	ManagementAPIUnavailable string = "ManagementAPIUnavailable"
+
+	ProgressDeadlineExceededReason string = "ProgressDeadlineExceeded"
 )
 
 func (s *EMQXStatus) SetNodes(nodes []EMQXNode) {
@@ -89,12 +219,24 @@ func (s *EMQXStatus) SetNodes(nodes []EMQXNode) {
 	}
 	s.CoreNodesStatus.Nodes = coreNodes
 	s.CoreNodesStatus.ReadyReplicas = int32(len(coreNodes))
+	s.CoreNodesStatus.NodesWithoutPod = getNodesWithoutPod(coreNodes)
 	if s.ReplicantNodesStatus != nil {
 		s.ReplicantNodesStatus.Nodes = replNodes
 		s.ReplicantNodesStatus.ReadyReplicas = int32(len(replNodes))
+		s.ReplicantNodesStatus.NodesWithoutPod = getNodesWithoutPod(replNodes)
 	}
 }
 
+func getNodesWithoutPod(nodes []EMQXNode) []string {
+	var list []string
+	for _, node := range nodes {
+		if node.PodName == "" {
+			list = append(list, node.Node)
+		}
+	}
+	return list
+}
+
 func (s *EMQXStatus) SetCondition(c metav1.Condition) {
 	c.LastTransitionTime = metav1.Now()
 	pos, _ := s.GetCondition(c.Type)
@@ -108,16 +250,26 @@ func (s *EMQXStatus) SetCondition(c metav1.Condition) {
 	})
 }
 
+// GetLastTrueCondition returns the latest true condition of the EMQX cluster status, like CoreNodesProgressing, Ready,
+// the conditions that do not describe the cluster status, like UpdatePaused, are ignored.
 func (s *EMQXStatus) GetLastTrueCondition() *metav1.Condition {
 	for i := range s.Conditions {
 		c := s.Conditions[i]
-		if c.Status == metav1.ConditionTrue {
+		if c.Status == metav1.ConditionTrue && isClusterStatusCondition(c.Type) {
 			return &c
 		}
 	}
 	return nil
 }
 
+func isClusterStatusCondition(conditionType string) bool {
+	switch conditionType {
+	case Initialized, CoreNodesProgressing, CoreNodesReady, ReplicantNodesProgressing, ReplicantNodesReady, Available, Ready, Degraded:
+		return true
+	}
+	return false
+}
+
 func (s *EMQXStatus) GetCondition(conditionType string) (int, *metav1.Condition) {
 	for i := range s.Conditions {
 		c := s.Conditions[i]
@@ -30,9 +30,10 @@ func TestSetNodes(t *testing.T) {
 
 	nodes := []EMQXNode{
 		{
-			Node:   "emqx-0",
-			Role:   "core",
-			Uptime: 10000,
+			Node:    "emqx-0",
+			Role:    "core",
+			Uptime:  10000,
+			PodName: "emqx-core-0",
 		},
 		{
 			Node:   "emqx-1",
@@ -60,9 +61,10 @@ func TestSetNodes(t *testing.T) {
 			Uptime: 10,
 		},
 		{
-			Node:   "emqx-0",
-			Role:   "core",
-			Uptime: 10000,
+			Node:    "emqx-0",
+			Role:    "core",
+			Uptime:  10000,
+			PodName: "emqx-core-0",
 		},
 	}, status.CoreNodesStatus.Nodes)
 
@@ -79,6 +81,9 @@ func TestSetNodes(t *testing.T) {
 			Uptime: 10000,
 		},
 	}, status.ReplicantNodesStatus.Nodes)
+
+	assert.Equal(t, []string{"emqx-1"}, status.CoreNodesStatus.NodesWithoutPod)
+	assert.Equal(t, []string{"emqx-3", "emqx-2"}, status.ReplicantNodesStatus.NodesWithoutPod)
 }
 
 func TestSetCondition(t *testing.T) {
@@ -168,6 +173,15 @@ func TestGetLastTrueCondition(t *testing.T) {
 
 	c := status.GetLastTrueCondition()
 	assert.Equal(t, Initialized, c.Type)
+
+	status.Conditions = append([]metav1.Condition{
+		{
+			Type:   UpdatePaused,
+			Status: metav1.ConditionTrue,
+		},
+	}, status.Conditions...)
+	c = status.GetLastTrueCondition()
+	assert.Equal(t, Initialized, c.Type)
 }
 
 func TestGetCondition(t *testing.T) {
@@ -128,13 +128,47 @@ func AddLabel(labels map[string]string, labelKey, labelValue string) map[string]
 }
 
 func GetDashboardServicePort(instance *EMQX) (*corev1.ServicePort, error) {
+	return getDashboardListenerPort(instance, "http")
+}
+
+// GetDashboardHTTPSServicePort returns the port of the HTTPS dashboard listener in the bootstrap config
+func GetDashboardHTTPSServicePort(instance *EMQX) (*corev1.ServicePort, error) {
+	return getDashboardListenerPort(instance, "https")
+}
+
+// GetManagementAPIScheme returns the scheme to request the EMQX management API, it is set in spec.managementAPI,
+// or https if only the HTTPS dashboard listener is enabled in the bootstrap config.
+func GetManagementAPIScheme(instance *EMQX) string {
+	if instance.Spec.ManagementAPI != nil && instance.Spec.ManagementAPI.Scheme != "" {
+		return instance.Spec.ManagementAPI.Scheme
+	}
+	hoconConfig, err := hocon.ParseString(instance.Spec.BootstrapConfig)
+	if err != nil {
+		return "http"
+	}
+	if !isDashboardListenerEnabled(hoconConfig, "http") && isDashboardListenerEnabled(hoconConfig, "https") {
+		return "https"
+	}
+	return "http"
+}
+
+func isDashboardListenerEnabled(hoconConfig *hocon.Config, listener string) bool {
+	bind := strings.Trim(hoconConfig.GetString("dashboard.listeners."+listener+".bind"), `"`)
+	if bind == "" || bind == "0" {
+		return false
+	}
+	enable := strings.Trim(hoconConfig.GetString("dashboard.listeners."+listener+".enable"), `"`)
+	return enable != "false"
+}
+
+func getDashboardListenerPort(instance *EMQX, listener string) (*corev1.ServicePort, error) {
 	hoconConfig, err := hocon.ParseString(instance.Spec.BootstrapConfig)
 	if err != nil {
 		return nil, emperror.Wrapf(err, "failed to parse %s", instance.Spec.BootstrapConfig)
 	}
-	dashboardPort := strings.Trim(hoconConfig.GetString("dashboard.listeners.http.bind"), `"`)
+	dashboardPort := strings.Trim(hoconConfig.GetString("dashboard.listeners."+listener+".bind"), `"`)
 	if dashboardPort == "" {
-		return nil, emperror.Errorf("failed to get dashboard.listeners.http.bind in %s", hoconConfig.String())
+		return nil, emperror.Errorf("failed to get dashboard.listeners.%s.bind in %s", listener, hoconConfig.String())
 	}
 
 	_, strPort, err := net.SplitHostPort(dashboardPort)
@@ -144,7 +178,7 @@ func GetDashboardServicePort(instance *EMQX) (*corev1.ServicePort, error) {
 	port, _ := strconv.Atoi(strPort)
 
 	return &corev1.ServicePort{
-		Name:       "dashboard-listeners-http-bind",
+		Name:       "dashboard-listeners-" + listener + "-bind",
 		Protocol:   corev1.ProtocolTCP,
 		Port:       int32(port),
 		TargetPort: intstr.FromInt(port),
@@ -80,6 +80,49 @@ func TestGetDashboardServicePort(t *testing.T) {
 	})
 }
 
+func TestGetDashboardHTTPSServicePort(t *testing.T) {
+	instance := &EMQX{}
+	instance.Spec.BootstrapConfig = `dashboard.listeners.https.bind = "0.0.0.0:18084"`
+	got, err := GetDashboardHTTPSServicePort(instance)
+	assert.Nil(t, err)
+	assert.Equal(t, &corev1.ServicePort{
+		Name:       "dashboard-listeners-https-bind",
+		Protocol:   corev1.ProtocolTCP,
+		Port:       int32(18084),
+		TargetPort: intstr.FromInt(18084),
+	}, got)
+}
+
+func TestGetManagementAPIScheme(t *testing.T) {
+	t.Run("HTTP listener", func(t *testing.T) {
+		instance := &EMQX{}
+		instance.Spec.BootstrapConfig = `dashboard.listeners.http.bind = 18083`
+		assert.Equal(t, "http", GetManagementAPIScheme(instance))
+	})
+
+	t.Run("HTTP and HTTPS listeners", func(t *testing.T) {
+		instance := &EMQX{}
+		instance.Spec.BootstrapConfig = `dashboard.listeners.http.bind = 18083, dashboard.listeners.https.bind = 18084`
+		assert.Equal(t, "http", GetManagementAPIScheme(instance))
+	})
+
+	t.Run("HTTP listener is disabled", func(t *testing.T) {
+		instance := &EMQX{}
+		instance.Spec.BootstrapConfig = `dashboard.listeners.http.bind = 0, dashboard.listeners.https.bind = 18084`
+		assert.Equal(t, "https", GetManagementAPIScheme(instance))
+
+		instance.Spec.BootstrapConfig = `dashboard.listeners.http {bind = 18083, enable = false}, dashboard.listeners.https.bind = 18084`
+		assert.Equal(t, "https", GetManagementAPIScheme(instance))
+	})
+
+	t.Run("scheme in spec", func(t *testing.T) {
+		instance := &EMQX{}
+		instance.Spec.BootstrapConfig = `dashboard.listeners.http.bind = 18083`
+		instance.Spec.ManagementAPI = &ManagementAPI{Scheme: "https"}
+		assert.Equal(t, "https", GetManagementAPIScheme(instance))
+	})
+}
+
 func TestMergeServicePorts(t *testing.T) {
 	t.Run("duplicate name", func(t *testing.T) {
 		ports1 := []corev1.ServicePort{
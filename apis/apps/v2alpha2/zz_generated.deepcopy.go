@@ -25,8 +25,25 @@ import (
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIKeyRotationStatus) DeepCopyInto(out *APIKeyRotationStatus) {
+	*out = *in
+	in.LastRotationTime.DeepCopyInto(&out.LastRotationTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIKeyRotationStatus.
+func (in *APIKeyRotationStatus) DeepCopy() *APIKeyRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(APIKeyRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BootstrapAPIKey) DeepCopyInto(out *BootstrapAPIKey) {
 	*out = *in
@@ -42,6 +59,26 @@ func (in *BootstrapAPIKey) DeepCopy() *BootstrapAPIKey {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryStrategy) DeepCopyInto(out *CanaryStrategy) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryStrategy.
+func (in *CanaryStrategy) DeepCopy() *CanaryStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EMQX) DeepCopyInto(out *EMQX) {
 	*out = *in
@@ -69,6 +106,141 @@ func (in *EMQX) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EMQXAlarm) DeepCopyInto(out *EMQXAlarm) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXAlarm.
+func (in *EMQXAlarm) DeepCopy() *EMQXAlarm {
+	if in == nil {
+		return nil
+	}
+	out := new(EMQXAlarm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EMQXAutoscaler) DeepCopyInto(out *EMQXAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXAutoscaler.
+func (in *EMQXAutoscaler) DeepCopy() *EMQXAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(EMQXAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EMQXAutoscaler) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EMQXAutoscalerList) DeepCopyInto(out *EMQXAutoscalerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]EMQXAutoscaler, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXAutoscalerList.
+func (in *EMQXAutoscalerList) DeepCopy() *EMQXAutoscalerList {
+	if in == nil {
+		return nil
+	}
+	out := new(EMQXAutoscalerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EMQXAutoscalerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EMQXAutoscalerSpec) DeepCopyInto(out *EMQXAutoscalerSpec) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleUpStabilizationWindowSeconds != nil {
+		in, out := &in.ScaleUpStabilizationWindowSeconds, &out.ScaleUpStabilizationWindowSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleDownStabilizationWindowSeconds != nil {
+		in, out := &in.ScaleDownStabilizationWindowSeconds, &out.ScaleDownStabilizationWindowSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CooldownSeconds != nil {
+		in, out := &in.CooldownSeconds, &out.CooldownSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXAutoscalerSpec.
+func (in *EMQXAutoscalerSpec) DeepCopy() *EMQXAutoscalerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EMQXAutoscalerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EMQXAutoscalerStatus) DeepCopyInto(out *EMQXAutoscalerStatus) {
+	*out = *in
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXAutoscalerStatus.
+func (in *EMQXAutoscalerStatus) DeepCopy() *EMQXAutoscalerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EMQXAutoscalerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EMQXCoreTemplate) DeepCopyInto(out *EMQXCoreTemplate) {
 	*out = *in
@@ -163,6 +335,21 @@ func (in *EMQXNodesStatus) DeepCopyInto(out *EMQXNodesStatus) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.RetainedRevisions != nil {
+		in, out := &in.RetainedRevisions, &out.RetainedRevisions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodsNotInCluster != nil {
+		in, out := &in.PodsNotInCluster, &out.PodsNotInCluster
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodesWithoutPod != nil {
+		in, out := &in.NodesWithoutPod, &out.NodesWithoutPod
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXNodesStatus.
@@ -214,6 +401,13 @@ func (in *EMQXReplicantTemplateSpec) DeepCopyInto(out *EMQXReplicantTemplateSpec
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Replicas != nil {
 		in, out := &in.Replicas, &out.Replicas
 		*out = new(int32)
@@ -307,6 +501,11 @@ func (in *EMQXReplicantTemplateSpec) DeepCopyInto(out *EMQXReplicantTemplateSpec
 		*out = new(v1.Lifecycle)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(PodDisruptionBudgetSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXReplicantTemplateSpec.
@@ -327,12 +526,22 @@ func (in *EMQXSpec) DeepCopyInto(out *EMQXSpec) {
 		*out = make([]v1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
-	out.UpdateStrategy = in.UpdateStrategy
+	in.UpdateStrategy.DeepCopyInto(&out.UpdateStrategy)
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
 	if in.BootstrapAPIKeys != nil {
 		in, out := &in.BootstrapAPIKeys, &out.BootstrapAPIKeys
 		*out = make([]BootstrapAPIKey, len(*in))
 		copy(*out, *in)
 	}
+	if in.ManagementAPI != nil {
+		in, out := &in.ManagementAPI, &out.ManagementAPI
+		*out = new(ManagementAPI)
+		(*in).DeepCopyInto(*out)
+	}
 	in.DashboardServiceTemplate.DeepCopyInto(&out.DashboardServiceTemplate)
 	in.ListenersServiceTemplate.DeepCopyInto(&out.ListenersServiceTemplate)
 	in.CoreTemplate.DeepCopyInto(&out.CoreTemplate)
@@ -353,6 +562,22 @@ func (in *EMQXSpec) DeepCopy() *EMQXSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EMQXStats) DeepCopyInto(out *EMQXStats) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXStats.
+func (in *EMQXStats) DeepCopy() *EMQXStats {
+	if in == nil {
+		return nil
+	}
+	out := new(EMQXStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EMQXStatus) DeepCopyInto(out *EMQXStatus) {
 	*out = *in
@@ -369,6 +594,28 @@ func (in *EMQXStatus) DeepCopyInto(out *EMQXStatus) {
 		*out = new(EMQXNodesStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NodeEvacuationsStatus != nil {
+		in, out := &in.NodeEvacuationsStatus, &out.NodeEvacuationsStatus
+		*out = make([]NodeEvacuationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Alarms != nil {
+		in, out := &in.Alarms, &out.Alarms
+		*out = make([]EMQXAlarm, len(*in))
+		copy(*out, *in)
+	}
+	if in.Stats != nil {
+		in, out := &in.Stats, &out.Stats
+		*out = new(EMQXStats)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.APIKeyRotation != nil {
+		in, out := &in.APIKeyRotation, &out.APIKeyRotation
+		*out = new(APIKeyRotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EMQXStatus.
@@ -396,6 +643,147 @@ func (in *EvacuationStrategy) DeepCopy() *EvacuationStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagementAPI) DeepCopyInto(out *ManagementAPI) {
+	*out = *in
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ManagementAPITLS)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagementAPI.
+func (in *ManagementAPI) DeepCopy() *ManagementAPI {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagementAPI)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagementAPITLS) DeepCopyInto(out *ManagementAPITLS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagementAPITLS.
+func (in *ManagementAPITLS) DeepCopy() *ManagementAPITLS {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagementAPITLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeEvacuationStats) DeepCopyInto(out *NodeEvacuationStats) {
+	*out = *in
+	if in.InitialSessions != nil {
+		in, out := &in.InitialSessions, &out.InitialSessions
+		*out = new(int32)
+		**out = **in
+	}
+	if in.InitialConnected != nil {
+		in, out := &in.InitialConnected, &out.InitialConnected
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CurrentSessions != nil {
+		in, out := &in.CurrentSessions, &out.CurrentSessions
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CurrentConnected != nil {
+		in, out := &in.CurrentConnected, &out.CurrentConnected
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeEvacuationStats.
+func (in *NodeEvacuationStats) DeepCopy() *NodeEvacuationStats {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeEvacuationStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeEvacuationStatus) DeepCopyInto(out *NodeEvacuationStatus) {
+	*out = *in
+	in.Stats.DeepCopyInto(&out.Stats)
+	if in.SessionRecipients != nil {
+		in, out := &in.SessionRecipients, &out.SessionRecipients
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeEvacuationStatus.
+func (in *NodeEvacuationStatus) DeepCopy() *NodeEvacuationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeEvacuationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetSpec) DeepCopyInto(out *PodDisruptionBudgetSpec) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetSpec.
+func (in *PodDisruptionBudgetSpec) DeepCopy() *PodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollingUpdateStrategy) DeepCopyInto(out *RollingUpdateStrategy) {
+	*out = *in
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpdateStrategy.
+func (in *RollingUpdateStrategy) DeepCopy() *RollingUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(RollingUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceTemplate) DeepCopyInto(out *ServiceTemplate) {
 	*out = *in
@@ -416,7 +804,22 @@ func (in *ServiceTemplate) DeepCopy() *ServiceTemplate {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
 	*out = *in
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(RollingUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanaryStrategy)
+		(*in).DeepCopyInto(*out)
+	}
 	out.EvacuationStrategy = in.EvacuationStrategy
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateStrategy.
@@ -2,7 +2,6 @@ package v1beta4
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -11,6 +10,7 @@ import (
 	emperror "emperror.dev/errors"
 	"github.com/cisco-open/k8s-objectmatcher/patch"
 	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -36,7 +36,7 @@ func (a addEmqxStatefulSet) reconcile(ctx context.Context, instance appsv1beta4.
 	}
 
 	if isEnterprise, enterprise := a.isEmqxEnterprise(instance); isEnterprise {
-		return a.handleBlueGreenUpdate(enterprise)
+		return a.handleBlueGreenUpdate(ctx, enterprise)
 	}
 
 	return subResult{}
@@ -49,7 +49,7 @@ func (a *addEmqxStatefulSet) isEmqxEnterprise(instance appsv1beta4.Emqx) (bool,
 }
 
 // Handle Emqx BlueGreen Update
-func (a *addEmqxStatefulSet) handleBlueGreenUpdate(enterprise *appsv1beta4.EmqxEnterprise) subResult {
+func (a *addEmqxStatefulSet) handleBlueGreenUpdate(ctx context.Context, enterprise *appsv1beta4.EmqxEnterprise) subResult {
 	if enterprise.Status.EmqxBlueGreenUpdateStatus == nil {
 		return subResult{}
 	}
@@ -64,7 +64,7 @@ func (a *addEmqxStatefulSet) handleBlueGreenUpdate(enterprise *appsv1beta4.EmqxE
 		return subResult{result: ctrl.Result{RequeueAfter: time.Duration(delay) * time.Second}}
 	}
 
-	if err := a.syncStatefulSet(enterprise); err != nil {
+	if err := a.syncStatefulSet(ctx, enterprise); err != nil {
 		return subResult{err: emperror.Wrap(err, "failed to sync statefulset")}
 	}
 	return subResult{}
@@ -114,7 +114,7 @@ func (a addEmqxStatefulSet) getNewStatefulSet(instance appsv1beta4.Emqx, sts *ap
 	}
 }
 
-func (a addEmqxStatefulSet) syncStatefulSet(enterprise *appsv1beta4.EmqxEnterprise) error {
+func (a addEmqxStatefulSet) syncStatefulSet(ctx context.Context, enterprise *appsv1beta4.EmqxEnterprise) error {
 	if enterprise.Status.EmqxBlueGreenUpdateStatus == nil {
 		return nil
 	}
@@ -171,7 +171,7 @@ func (a addEmqxStatefulSet) syncStatefulSet(enterprise *appsv1beta4.EmqxEnterpri
 		emqxNodeName := getEmqxNodeName(enterprise, pods[0])
 
 		a.EventRecorder.Event(enterprise, corev1.EventTypeNormal, "Evacuate", fmt.Sprintf("Evacuate node %s start", emqxNodeName))
-		if err := a.startEvacuateNodeByAPI(enterprise, podMap[currentSts.UID], emqxNodeName); err != nil {
+		if err := a.startEvacuateNodeByAPI(ctx, enterprise, podMap[currentSts.UID], emqxNodeName); err != nil {
 			return emperror.Wrapf(err, "Evacuate node %s failed: %s", emqxNodeName, err.Error())
 		}
 	}
@@ -210,7 +210,7 @@ func (a addEmqxStatefulSet) canBeScaledDown(enterprise *appsv1beta4.EmqxEnterpri
 }
 
 // Request API
-func (a addEmqxStatefulSet) startEvacuateNodeByAPI(instance appsv1beta4.Emqx, migrateToPods []*corev1.Pod, nodeName string) error {
+func (a addEmqxStatefulSet) startEvacuateNodeByAPI(ctx context.Context, instance appsv1beta4.Emqx, migrateToPods []*corev1.Pod, nodeName string) error {
 	enterprise, ok := instance.(*appsv1beta4.EmqxEnterprise)
 	if !ok {
 		return emperror.New("failed to evacuate node, only support emqx enterprise")
@@ -222,22 +222,12 @@ func (a addEmqxStatefulSet) startEvacuateNodeByAPI(instance appsv1beta4.Emqx, mi
 		migrateTo = append(migrateTo, emqxNodeName)
 	}
 
-	body := map[string]interface{}{
-		"conn_evict_rate": enterprise.Spec.EmqxBlueGreenUpdate.EvacuationStrategy.ConnEvictRate,
-		"sess_evict_rate": enterprise.Spec.EmqxBlueGreenUpdate.EvacuationStrategy.SessEvictRate,
-		"migrate_to":      migrateTo,
-	}
-	if enterprise.Spec.EmqxBlueGreenUpdate.EvacuationStrategy.WaitTakeover > 0 {
-		body["wait_takeover"] = enterprise.Spec.EmqxBlueGreenUpdate.EvacuationStrategy.WaitTakeover
-	}
-
-	b, err := json.Marshal(body)
-	if err != nil {
-		return emperror.Wrap(err, "marshal body failed")
-	}
-
-	_, _, err = a.Requester.Request("POST", "api/v4/load_rebalance/"+nodeName+"/evacuation/start", b)
-	return err
+	return emqxapi.NewV4(a.Requester).StartEvacuation(ctx, nodeName, emqxapi.EvacuationRequest{
+		ConnEvictRate: enterprise.Spec.EmqxBlueGreenUpdate.EvacuationStrategy.ConnEvictRate,
+		SessEvictRate: enterprise.Spec.EmqxBlueGreenUpdate.EvacuationStrategy.SessEvictRate,
+		WaitTakeover:  enterprise.Spec.EmqxBlueGreenUpdate.EvacuationStrategy.WaitTakeover,
+		MigrateTo:     migrateTo,
+	})
 }
 
 // Extract the pod name from the node string
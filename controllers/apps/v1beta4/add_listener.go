@@ -2,7 +2,6 @@ package v1beta4
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net"
 	"regexp"
@@ -11,8 +10,8 @@ import (
 
 	emperror "emperror.dev/errors"
 	"github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
-	"github.com/tidwall/gjson"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -45,7 +44,7 @@ func (a addListener) reconcile(ctx context.Context, instance v1beta4.Emqx, _ ...
 	}
 
 	// ignore error, because if statefulSet is not created, the listener port will be not found
-	listenerPorts, _ := a.getListenerPortsByAPI()
+	listenerPorts, _ := a.getListenerPortsByAPI(ctx)
 
 	resources := []client.Object{}
 	svc := generateListenerService(instance, listenerPorts)
@@ -131,20 +130,10 @@ func generateEndpoints(svc *corev1.Service, pods []*corev1.Pod) *corev1.Endpoint
 	}
 }
 
-func (a addListener) getListenerPortsByAPI() ([]corev1.ServicePort, error) {
-	type emqxListener struct {
-		Protocol string `json:"protocol"`
-		ListenOn string `json:"listen_on"`
-	}
-
-	type emqxListeners struct {
-		Node      string         `json:"node"`
-		Listeners []emqxListener `json:"listeners"`
-	}
-
-	intersection := func(listeners1 []emqxListener, listeners2 []emqxListener) []emqxListener {
+func (a addListener) getListenerPortsByAPI(ctx context.Context) ([]corev1.ServicePort, error) {
+	intersection := func(listeners1 []emqxapi.V4Listener, listeners2 []emqxapi.V4Listener) []emqxapi.V4Listener {
 		hSection := map[string]struct{}{}
-		ans := make([]emqxListener, 0)
+		ans := make([]emqxapi.V4Listener, 0)
 		for _, listener := range listeners1 {
 			hSection[listener.ListenOn] = struct{}{}
 		}
@@ -158,18 +147,12 @@ func (a addListener) getListenerPortsByAPI() ([]corev1.ServicePort, error) {
 		return ans
 	}
 
-	_, body, err := a.Requester.Request("GET", "api/v4/listeners", nil)
+	listenerList, err := emqxapi.NewV4(a.Requester).Listeners(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	listenerList := []emqxListeners{}
-	data := gjson.GetBytes(body, "data")
-	if err := json.Unmarshal([]byte(data.Raw), &listenerList); err != nil {
-		return nil, emperror.Wrap(err, "failed to unmarshal node statuses")
-	}
-
-	var listeners []emqxListener
+	var listeners []emqxapi.V4Listener
 	if len(listenerList) == 1 {
 		listeners = listenerList[0].Listeners
 	} else {
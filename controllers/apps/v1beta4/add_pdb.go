@@ -0,0 +1,51 @@
+package v1beta4
+
+import (
+	"context"
+
+	emperror "emperror.dev/errors"
+	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type addPdb struct {
+	*EmqxReconciler
+}
+
+func (a addPdb) reconcile(ctx context.Context, instance appsv1beta4.Emqx, _ ...any) subResult {
+	pdb := generatePodDisruptionBudget(instance)
+	if err := a.CreateOrUpdateList(instance, a.Scheme, []client.Object{pdb}); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to create or update PDB")}
+	}
+	return subResult{}
+}
+
+// generatePodDisruptionBudget generates the PDB of the EMQX nodes, at most a minority of the nodes can be disrupted
+// at the same time, and at least one. The pods of all the statefulSets are selected, since the blue-green update
+// scales down the old statefulSet by itself rather than evicting the pods.
+func generatePodDisruptionBudget(instance appsv1beta4.Emqx) *policyv1.PodDisruptionBudget {
+	maxUnavailable := int32(1)
+	if replicas := instance.GetSpec().GetReplicas(); replicas != nil && (*replicas-1)/2 > maxUnavailable {
+		maxUnavailable = (*replicas - 1) / 2
+	}
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1",
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: instance.GetNamespace(),
+			Name:      instance.GetName(),
+			Labels:    instance.GetLabels(),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: instance.GetLabels(),
+			},
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: maxUnavailable},
+		},
+	}
+}
@@ -0,0 +1,24 @@
+package v1beta4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func TestGeneratePodDisruptionBudget(t *testing.T) {
+	emqx := instance.DeepCopy()
+
+	got := generatePodDisruptionBudget(emqx)
+	assert.Equal(t, "default", got.Namespace)
+	assert.Equal(t, "emqx", got.Name)
+	assert.Equal(t, map[string]string{"foo": "bar"}, got.Spec.Selector.MatchLabels)
+	assert.Nil(t, got.Spec.MinAvailable)
+
+	for replicas, maxUnavailable := range map[int32]int32{1: 1, 3: 1, 4: 1, 5: 2, 7: 3} {
+		emqx.Spec.Replicas = pointer.Int32(replicas)
+		assert.Equal(t, &intstr.IntOrString{Type: intstr.Int, IntVal: maxUnavailable}, generatePodDisruptionBudget(emqx).Spec.MaxUnavailable)
+	}
+}
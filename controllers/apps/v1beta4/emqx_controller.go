@@ -35,6 +35,7 @@ import (
 	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
 	innerErr "github.com/emqx/emqx-operator/internal/errors"
 	"github.com/emqx/emqx-operator/internal/handler"
+	"github.com/emqx/emqx-operator/internal/metrics"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -44,6 +45,12 @@ import (
 
 const EmqxContainerName string = "emqx"
 
+// The kinds of the EMQX custom resources, the objects got from the API server usually have no kind
+const (
+	emqxBrokerKind     = "EmqxBroker"
+	emqxEnterpriseKind = "EmqxEnterprise"
+)
+
 // subResult provides a wrapper around different results from a subreconciler.
 type subResult struct {
 	cont   bool // continue to next sub reconciler
@@ -81,6 +88,19 @@ func (r *EmqxReconciler) Do(ctx context.Context, instance appsv1beta4.Emqx) (ctr
 		return ctrl.Result{}, nil
 	}
 
+	if instance.GetSpec().GetPaused() {
+		return r.paused(ctx, instance)
+	}
+	for _, c := range instance.GetStatus().GetConditions() {
+		if c.Type == appsv1beta4.ConditionReconcilePaused && c.Status == corev1.ConditionTrue {
+			instance.GetStatus().AddCondition(appsv1beta4.ConditionReconcilePaused, corev1.ConditionFalse, "ReconcileResumed", "Reconciliation is resumed")
+			if err := r.Client.Status().Update(ctx, instance); err != nil {
+				return ctrl.Result{}, emperror.Wrap(err, "failed to update emqx status")
+			}
+			break
+		}
+	}
+
 	requester, err := newRequesterBySvc(r.Client, instance)
 	if err != nil {
 		if k8sErrors.IsNotFound(emperror.Cause(err)) {
@@ -99,16 +119,19 @@ func (r *EmqxReconciler) Do(ctx context.Context, instance appsv1beta4.Emqx) (ctr
 		addEmqxPlugins{EmqxReconciler: r},
 		addEmqxResources{EmqxReconciler: r, Requester: requester},
 		addEmqxStatefulSet{EmqxReconciler: r, Requester: requester},
+		addPdb{EmqxReconciler: r},
 		addListener{EmqxReconciler: r, Requester: requester},
 		updateEmqxStatus{EmqxReconciler: r, Requester: requester},
 		updatePodConditions{EmqxReconciler: r, Requester: requester},
 	}
 	for i := range subReconcilers {
+		start := time.Now()
 		if reflect.ValueOf(subResult).FieldByName("args").IsValid() {
 			subResult = subReconcilers[i].reconcile(ctx, instance, subResult.args)
 		} else {
 			subResult = subReconcilers[i].reconcile(ctx, instance)
 		}
+		metrics.ObserveSubReconciler(getKind(instance), instance.GetNamespace(), instance.GetName(), subReconcilers[i], time.Since(start))
 		subResult, err := r.processResult(subResult, instance)
 		if err != nil || !subResult.IsZero() {
 			return subResult, err
@@ -118,6 +141,25 @@ func (r *EmqxReconciler) Do(ctx context.Context, instance appsv1beta4.Emqx) (ctr
 	return ctrl.Result{RequeueAfter: 20 * time.Second}, nil
 }
 
+// paused only updates the status of the EMQX custom resource, the resources of the EMQX cluster are left untouched
+func (r *EmqxReconciler) paused(ctx context.Context, instance appsv1beta4.Emqx) (ctrl.Result, error) {
+	instance.GetStatus().AddCondition(appsv1beta4.ConditionReconcilePaused, corev1.ConditionTrue, "ReconcilePaused", "Reconciliation is paused")
+
+	requester, err := newRequesterBySvc(r.Client, instance)
+	if err != nil {
+		if err := r.Client.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, emperror.Wrap(err, "failed to update emqx status")
+		}
+		return ctrl.Result{RequeueAfter: 20 * time.Second}, nil
+	}
+
+	subResult := updateEmqxStatus{EmqxReconciler: r, Requester: requester}.reconcile(ctx, instance)
+	if result, err := r.processResult(subResult, instance); err != nil || !result.IsZero() {
+		return result, err
+	}
+	return ctrl.Result{RequeueAfter: 20 * time.Second}, nil
+}
+
 func (r *EmqxReconciler) processResult(subResult subResult, instance appsv1beta4.Emqx) (ctrl.Result, error) {
 	if subResult.cont {
 		if subResult.err != nil {
@@ -133,35 +175,90 @@ func (r *EmqxReconciler) processResult(subResult subResult, instance appsv1beta4
 	return subResult.result, subResult.err
 }
 
-func newRequesterBySvc(client client.Client, instance appsv1beta4.Emqx) (innerReq.RequesterInterface, error) {
-	username, password, err := getBootstrapUser(context.Background(), client, instance)
-	if err != nil {
-		return nil, err
+// getKind returns the kind of the EmqxBroker or EmqxEnterprise
+func getKind(instance appsv1beta4.Emqx) string {
+	if _, ok := instance.(*appsv1beta4.EmqxEnterprise); ok {
+		return emqxEnterpriseKind
 	}
+	return emqxBrokerKind
+}
 
+func newRequesterBySvc(client client.Client, instance appsv1beta4.Emqx) (innerReq.RequesterInterface, error) {
 	names := appsv1beta4.Names{Object: instance}
-	return &innerReq.Requester{
-		// TODO: the telepersence is not support `$service.$namespace.svc` format in Linux
-		// Host:     fmt.Sprintf("%s.%s.svc:8081", names.HeadlessSvc(), instance.GetNamespace()),
-		Host:     fmt.Sprintf("%s.%s.svc.cluster.local:8081", names.HeadlessSvc(), instance.GetNamespace()),
-		Username: username,
-		Password: password,
-	}, nil
+	// TODO: the telepersence is not support `$service.$namespace.svc` format in Linux
+	// return newRequesterByHost(client, instance, fmt.Sprintf("%s.%s.svc", names.HeadlessSvc(), instance.GetNamespace()))
+	return newRequesterByHost(client, instance, fmt.Sprintf("%s.%s.svc.cluster.local", names.HeadlessSvc(), instance.GetNamespace()))
 }
 
 func newRequesterByPod(client client.Client, instance appsv1beta4.Emqx, pod *corev1.Pod) (innerReq.RequesterInterface, error) {
+	return newRequesterByHost(client, instance, pod.Status.PodIP)
+}
+
+func newRequesterByHost(client client.Client, instance appsv1beta4.Emqx, host string) (innerReq.RequesterInterface, error) {
 	username, password, err := getBootstrapUser(context.Background(), client, instance)
 	if err != nil {
 		return nil, err
 	}
 
+	scheme, port := getManagementAPIListener(instance)
+	var tlsConfig *innerReq.TLSConfig
+	if scheme == "https" {
+		if tlsConfig, err = getManagementAPITLSConfig(context.Background(), client, instance); err != nil {
+			return nil, err
+		}
+	}
+
 	return &innerReq.Requester{
-		Host:     fmt.Sprintf("%s:8081", pod.Status.PodIP),
-		Username: username,
-		Password: password,
+		Scheme:    scheme,
+		Host:      fmt.Sprintf("%s:%s", host, port),
+		Username:  username,
+		Password:  password,
+		TLS:       tlsConfig,
+		Kind:      getKind(instance),
+		Namespace: instance.GetNamespace(),
+		Instance:  instance.GetName(),
 	}, nil
 }
 
+// getManagementAPIListener returns the scheme and port of the management listener to request the EMQX management API
+func getManagementAPIListener(instance appsv1beta4.Emqx) (scheme, port string) {
+	managementAPI := instance.GetSpec().GetTemplate().Spec.EmqxContainer.ManagementAPI
+	if managementAPI == nil || managementAPI.Scheme != "https" {
+		return "http", "8081"
+	}
+
+	port = "8082"
+	if bind := instance.GetSpec().GetTemplate().Spec.EmqxContainer.EmqxConfig["management.listener.https"]; bind != "" {
+		// The listener can be bound to a port or an address, like 8082 or 0.0.0.0:8082
+		port = bind[strings.LastIndex(bind, ":")+1:]
+	}
+	return "https", port
+}
+
+// getManagementAPITLSConfig returns the TLS config to request the EMQX management API over HTTPS,
+// the certificates are read from the secret in the managementAPI of the EMQX container.
+func getManagementAPITLSConfig(ctx context.Context, client client.Client, instance appsv1beta4.Emqx) (*innerReq.TLSConfig, error) {
+	tlsConfig := &innerReq.TLSConfig{}
+	managementAPI := instance.GetSpec().GetTemplate().Spec.EmqxContainer.ManagementAPI
+	if managementAPI == nil || managementAPI.TLS == nil {
+		return tlsConfig, nil
+	}
+	tlsConfig.ServerName = managementAPI.TLS.ServerName
+	tlsConfig.InsecureSkipVerify = managementAPI.TLS.InsecureSkipVerify
+
+	if secretName := managementAPI.TLS.SecretName; secretName != "" {
+		secret := &corev1.Secret{}
+		if err := client.Get(ctx, types.NamespacedName{Namespace: instance.GetNamespace(), Name: secretName}, secret); err != nil {
+			// Do not wrap the error, the not found error of the bootstrap user secret is handled by the caller
+			return nil, emperror.Errorf("failed to get TLS secret %s: %s", secretName, err)
+		}
+		tlsConfig.CACert = secret.Data["ca.crt"]
+		tlsConfig.ClientCert = secret.Data[corev1.TLSCertKey]
+		tlsConfig.ClientKey = secret.Data[corev1.TLSPrivateKeyKey]
+	}
+	return tlsConfig, nil
+}
+
 func getBootstrapUser(ctx context.Context, client client.Client, instance appsv1beta4.Emqx) (username, password string, err error) {
 	bootstrapUser := &corev1.Secret{}
 	if err = client.Get(ctx, types.NamespacedName{
@@ -0,0 +1,114 @@
+package v1beta4
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cisco-open/k8s-objectmatcher/patch"
+	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/handler"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetManagementAPIListener(t *testing.T) {
+	instance := &appsv1beta4.EmqxEnterprise{}
+
+	t.Run("default", func(t *testing.T) {
+		scheme, port := getManagementAPIListener(instance)
+		assert.Equal(t, "http", scheme)
+		assert.Equal(t, "8081", port)
+	})
+
+	t.Run("https", func(t *testing.T) {
+		instance.Spec.Template.Spec.EmqxContainer.ManagementAPI = &appsv1beta4.ManagementAPI{Scheme: "https"}
+		scheme, port := getManagementAPIListener(instance)
+		assert.Equal(t, "https", scheme)
+		assert.Equal(t, "8082", port)
+	})
+
+	t.Run("https listener in emqxConfig", func(t *testing.T) {
+		instance.Spec.Template.Spec.EmqxContainer.EmqxConfig = map[string]string{
+			"management.listener.https": "0.0.0.0:18082",
+		}
+		scheme, port := getManagementAPIListener(instance)
+		assert.Equal(t, "https", scheme)
+		assert.Equal(t, "18082", port)
+	})
+}
+
+func TestReconcilePaused(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = appsv1beta4.AddToScheme(scheme)
+
+	instance := &appsv1beta4.EmqxBroker{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "emqx"},
+		Spec:       appsv1beta4.EmqxBrokerSpec{Paused: true},
+	}
+	instance.Default()
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance).Build()
+	r := &EmqxReconciler{
+		Handler: &handler.Handler{
+			Client:  k8sClient,
+			Patcher: &handler.Patcher{Annotator: patch.NewAnnotator(handler.LastAppliedAnnotation)},
+		},
+		Scheme:        scheme,
+		EventRecorder: record.NewFakeRecorder(10),
+	}
+
+	getCondition := func(instance appsv1beta4.Emqx) *appsv1beta4.Condition {
+		for _, c := range instance.GetStatus().GetConditions() {
+			if c.Type == appsv1beta4.ConditionReconcilePaused {
+				return c.DeepCopy()
+			}
+		}
+		return nil
+	}
+
+	t.Run("paused", func(t *testing.T) {
+		got := &appsv1beta4.EmqxBroker{}
+		assert.Nil(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(instance), got))
+		result, err := r.Do(context.Background(), got)
+		assert.Nil(t, err)
+		assert.Equal(t, ctrl.Result{RequeueAfter: 20 * time.Second}, result)
+
+		assert.Nil(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(instance), got))
+		condition := getCondition(got)
+		assert.NotNil(t, condition)
+		assert.Equal(t, corev1.ConditionTrue, condition.Status)
+
+		// The resources of the EMQX cluster are left untouched
+		secrets := &corev1.SecretList{}
+		assert.Nil(t, k8sClient.List(context.Background(), secrets))
+		assert.Empty(t, secrets.Items)
+		statefulSets := &appsv1.StatefulSetList{}
+		assert.Nil(t, k8sClient.List(context.Background(), statefulSets))
+		assert.Empty(t, statefulSets.Items)
+	})
+
+	t.Run("resumed", func(t *testing.T) {
+		got := &appsv1beta4.EmqxBroker{}
+		assert.Nil(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(instance), got))
+		got.Spec.Paused = false
+		assert.Nil(t, k8sClient.Update(context.Background(), got))
+
+		_, err := r.Do(context.Background(), got)
+		assert.Nil(t, err)
+
+		assert.Nil(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(instance), got))
+		condition := getCondition(got)
+		assert.NotNil(t, condition)
+		assert.Equal(t, corev1.ConditionFalse, condition.Status)
+		assert.Equal(t, "ReconcileResumed", condition.Reason)
+	})
+}
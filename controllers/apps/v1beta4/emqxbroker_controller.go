@@ -25,6 +25,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/metrics"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
 )
 
 // EmqxBrokerReconciler reconciles a EmqxBroker object
@@ -49,6 +51,8 @@ func (r *EmqxBrokerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	instance := &appsv1beta4.EmqxBroker{}
 	if err := r.Client.Get(ctx, req.NamespacedName, instance); err != nil {
 		if k8sErrors.IsNotFound(err) {
+			metrics.DeleteInstance(emqxBrokerKind, req.Namespace, req.Name)
+			innerReq.ForgetCluster(emqxBrokerKind, req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
@@ -25,6 +25,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/metrics"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
 )
 
 // EmqxEnterpriseReconciler reconciles a EmqxEnterprise object
@@ -49,6 +51,8 @@ func (r *EmqxEnterpriseReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	instance := &appsv1beta4.EmqxEnterprise{}
 	if err := r.Client.Get(ctx, req.NamespacedName, instance); err != nil {
 		if k8sErrors.IsNotFound(err) {
+			metrics.DeleteInstance(emqxEnterpriseKind, req.Namespace, req.Name)
+			innerReq.ForgetCluster(emqxEnterpriseKind, req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
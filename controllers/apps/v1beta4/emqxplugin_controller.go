@@ -22,7 +22,6 @@ import (
 	"sort"
 	"time"
 
-	emperror "emperror.dev/errors"
 	json "github.com/json-iterator/go"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -38,6 +37,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/emqx/emqx-operator/internal/emqxapi"
 	innerErr "github.com/emqx/emqx-operator/internal/errors"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 
@@ -45,19 +45,6 @@ import (
 	"github.com/emqx/emqx-operator/internal/handler"
 )
 
-type pluginByAPIReturn struct {
-	Name        string
-	Version     string
-	Description string
-	Active      bool
-	Type        string
-}
-
-type pluginListByAPIReturn struct {
-	Node    string
-	Plugins []pluginByAPIReturn
-}
-
 // EmqxPluginReconciler reconciles a EmqxPlugin object
 type EmqxPluginReconciler struct {
 	*handler.Handler
@@ -112,7 +99,7 @@ func (r *EmqxPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 					return ctrl.Result{}, err
 				}
 
-				err = r.unloadPluginByAPI(requester, instance.Spec.PluginName)
+				err = r.unloadPluginByAPI(ctx, requester, instance.Spec.PluginName)
 				if err != nil {
 					if innerErr.IsCommonError(err) {
 						return ctrl.Result{RequeueAfter: time.Second}, nil
@@ -165,7 +152,7 @@ func (r *EmqxPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return ctrl.Result{}, err
 		}
 
-		err = r.checkPluginStatusByAPI(requester, instance.Spec.PluginName)
+		err = r.checkPluginStatusByAPI(ctx, requester, instance.Spec.PluginName)
 		if err != nil {
 			if innerErr.IsCommonError(err) {
 				return ctrl.Result{RequeueAfter: time.Second}, nil
@@ -190,8 +177,9 @@ func (r *EmqxPluginReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
-func (r *EmqxPluginReconciler) checkPluginStatusByAPI(requester innerReq.RequesterInterface, pluginName string) error {
-	list, err := r.getPluginsByAPI(requester)
+func (r *EmqxPluginReconciler) checkPluginStatusByAPI(ctx context.Context, requester innerReq.RequesterInterface, pluginName string) error {
+	c := emqxapi.NewV4(requester)
+	list, err := c.Plugins(ctx)
 	if err != nil {
 		return err
 	}
@@ -199,7 +187,7 @@ func (r *EmqxPluginReconciler) checkPluginStatusByAPI(requester innerReq.Request
 		for _, plugin := range node.Plugins {
 			if plugin.Name == pluginName {
 				if !plugin.Active {
-					err := r.doLoadPluginByAPI(requester, node.Node, plugin.Name, "reload")
+					err := c.ReloadPlugin(ctx, node.Node, plugin.Name)
 					if err != nil {
 						return err
 					}
@@ -210,15 +198,16 @@ func (r *EmqxPluginReconciler) checkPluginStatusByAPI(requester innerReq.Request
 	return nil
 }
 
-func (r *EmqxPluginReconciler) unloadPluginByAPI(requester innerReq.RequesterInterface, pluginName string) error {
-	list, err := r.getPluginsByAPI(requester)
+func (r *EmqxPluginReconciler) unloadPluginByAPI(ctx context.Context, requester innerReq.RequesterInterface, pluginName string) error {
+	c := emqxapi.NewV4(requester)
+	list, err := c.Plugins(ctx)
 	if err != nil {
 		return err
 	}
 	for _, node := range list {
 		for _, plugin := range node.Plugins {
 			if plugin.Name == pluginName {
-				err := r.doLoadPluginByAPI(requester, node.Node, plugin.Name, "unload")
+				err := c.UnloadPlugin(ctx, node.Node, plugin.Name)
 				if err != nil {
 					return err
 				}
@@ -228,35 +217,6 @@ func (r *EmqxPluginReconciler) unloadPluginByAPI(requester innerReq.RequesterInt
 	return nil
 }
 
-func (r *EmqxPluginReconciler) doLoadPluginByAPI(requester innerReq.RequesterInterface, nodeName, pluginName, reloadOrUnload string) error {
-	resp, _, err := requester.Request("PUT", fmt.Sprintf("api/v4/nodes/%s/plugins/%s/%s", nodeName, pluginName, reloadOrUnload), nil)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 200 {
-		return emperror.Errorf("request api failed: %s", resp.Status)
-	}
-	return nil
-}
-
-func (r *EmqxPluginReconciler) getPluginsByAPI(requester innerReq.RequesterInterface) ([]pluginListByAPIReturn, error) {
-	var data []pluginListByAPIReturn
-	resp, body, err := requester.Request("GET", "api/v4/plugins", nil)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != 200 {
-		return nil, emperror.Errorf("request api failed: %s", resp.Status)
-	}
-
-	err = json.Unmarshal([]byte(gjson.GetBytes(body, "data").String()), &data)
-	if err != nil {
-		return nil, err
-	}
-
-	return data, nil
-}
-
 func (r *EmqxPluginReconciler) checkPluginConfig(plugin *appsv1beta4.EmqxPlugin, emqx appsv1beta4.Emqx) (bool, error) {
 	pluginConfigStr := generateConfigStr(plugin)
 
@@ -0,0 +1,36 @@
+package v1beta4
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emqx/emqx-operator/internal/emqxapi/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPluginStatusByAPI(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+	s.AddNode(fake.Node{Name: "emqx@10.0.0.1"}, fake.Node{Name: "emqx@10.0.0.2"})
+	s.AddPlugin(fake.Plugin{Name: "emqx_prometheus", Version: "4.4.14"}, false)
+	s.AddPlugin(fake.Plugin{Name: "emqx_management", Version: "4.4.14"}, true)
+	r := &EmqxPluginReconciler{}
+
+	t.Run("load plugin", func(t *testing.T) {
+		assert.Nil(t, r.checkPluginStatusByAPI(context.Background(), s.Requester(), "emqx_prometheus"))
+		assert.True(t, s.IsPluginRunning("emqx_prometheus", "emqx@10.0.0.1"))
+		assert.True(t, s.IsPluginRunning("emqx_prometheus", "emqx@10.0.0.2"))
+	})
+
+	t.Run("unload plugin", func(t *testing.T) {
+		assert.Nil(t, r.unloadPluginByAPI(context.Background(), s.Requester(), "emqx_prometheus"))
+		assert.False(t, s.IsPluginRunning("emqx_prometheus", "emqx@10.0.0.1"))
+		assert.False(t, s.IsPluginRunning("emqx_prometheus", "emqx@10.0.0.2"))
+		assert.True(t, s.IsPluginRunning("emqx_management", "emqx@10.0.0.1"))
+	})
+
+	t.Run("plugin not found", func(t *testing.T) {
+		assert.Nil(t, r.checkPluginStatusByAPI(context.Background(), s.Requester(), "emqx_fake"))
+		assert.NotContains(t, s.Requests(), "PUT api/v4/nodes/emqx@10.0.0.1/plugins/emqx_fake/reload")
+	})
+}
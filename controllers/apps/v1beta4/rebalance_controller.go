@@ -18,7 +18,6 @@ package v1beta4
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -36,8 +35,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
-	"github.com/tidwall/gjson"
 )
 
 // RebalanceReconciler reconciles a Rebalance object
@@ -116,7 +115,7 @@ func (r *RebalanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	if !rebalance.DeletionTimestamp.IsZero() {
 		if rebalance.Status.Phase == appsv1beta4.RebalancePhaseProcessing {
-			_ = stopRebalance(requester, rebalance)
+			_ = stopRebalance(ctx, requester, rebalance)
 		}
 		controllerutil.RemoveFinalizer(rebalance, finalizer)
 		return ctrl.Result{}, r.Client.Update(ctx, rebalance)
@@ -129,7 +128,7 @@ func (r *RebalanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
-	rebalanceStatusHandler(rebalance, emqx, requester, startRebalance, getRebalanceStatus)
+	rebalanceStatusHandler(ctx, rebalance, emqx, requester, startRebalance, getRebalanceStatus)
 	if err := r.Client.Status().Update(ctx, rebalance); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -173,16 +172,16 @@ func (r *RebalanceReconciler) getReadyPod(emqxEnterprise *appsv1beta4.EmqxEnterp
 }
 
 // Rebalance Handler
-type GetRebalanceStatusFunc func(requester innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error)
-type StartRebalanceFunc func(requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) error
-type StopRebalanceFunc func(requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance) error
+type GetRebalanceStatusFunc func(ctx context.Context, requester innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error)
+type StartRebalanceFunc func(ctx context.Context, requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) error
+type StopRebalanceFunc func(ctx context.Context, requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance) error
 
-func rebalanceStatusHandler(rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise,
+func rebalanceStatusHandler(ctx context.Context, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise,
 	requester innerReq.RequesterInterface, startFun StartRebalanceFunc, getRebalanceStatusFun GetRebalanceStatusFunc,
 ) {
 	switch rebalance.Status.Phase {
 	case "":
-		if err := startFun(requester, rebalance, emqx); err != nil {
+		if err := startFun(ctx, requester, rebalance, emqx); err != nil {
 			_ = rebalance.Status.SetFailed(appsv1beta4.RebalanceCondition{
 				Type:    appsv1beta4.RebalanceConditionFailed,
 				Status:  corev1.ConditionTrue,
@@ -195,7 +194,7 @@ func rebalanceStatusHandler(rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.
 			Status: corev1.ConditionTrue,
 		})
 	case appsv1beta4.RebalancePhaseProcessing:
-		rebalanceStates, err := getRebalanceStatusFun(requester)
+		rebalanceStates, err := getRebalanceStatusFun(ctx, requester)
 		if err != nil {
 			_ = rebalance.Status.SetFailed(appsv1beta4.RebalanceCondition{
 				Type:    appsv1beta4.RebalanceConditionFailed,
@@ -224,87 +223,50 @@ func rebalanceStatusHandler(rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.
 	}
 }
 
-func startRebalance(requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) error {
+func startRebalance(ctx context.Context, requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) error {
 	emqxNodeName := emqx.Status.EmqxNodes[0].Node
-
-	bytes := getRequestBytes(rebalance, emqx)
-	resp, respBody, err := requester.Request("POST", "api/v4/load_rebalance/"+emqxNodeName+"/start", bytes)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != 200 {
-		return emperror.Errorf("request api failed: %s", resp.Status)
-	}
-
-	code := gjson.GetBytes(respBody, "code")
-	if code.String() == "400" {
-		message := gjson.GetBytes(respBody, "message")
-		return emperror.New(message.String())
-	}
-	return nil
+	return emqxapi.NewV4(requester).StartRebalance(ctx, emqxNodeName, getRebalanceRequest(rebalance, emqx))
 }
 
-func getRebalanceStatus(requester innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error) {
-	resp, body, err := requester.Request("GET", "api/v4/load_rebalance/global_status", nil)
+func getRebalanceStatus(ctx context.Context, requester innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error) {
+	status, err := emqxapi.NewV4(requester).RebalanceStatus(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != 200 {
-		return nil, emperror.Errorf("request api failed: %s", resp.Status)
-	}
-	rebalanceStates := []appsv1beta4.RebalanceState{}
-	data := gjson.GetBytes(body, "rebalances")
-	if err := json.Unmarshal([]byte(data.Raw), &rebalanceStates); err != nil {
-		return nil, emperror.Wrap(err, "failed to unmarshal rebalances")
-	}
-	return rebalanceStates, nil
+	return status.Rebalances, nil
 }
 
-func stopRebalance(requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance) error {
+func stopRebalance(ctx context.Context, requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance) error {
 	// stop rebalance should use coordinatorNode as path parameter
 	emqxNodeName := rebalance.Status.RebalanceStates[0].CoordinatorNode
-	resp, respBody, err := requester.Request("POST", "api/v4/load_rebalance/"+emqxNodeName+"/stop", nil)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 200 {
-		return emperror.Errorf("request api failed: %s", resp.Status)
-	}
-	code := gjson.GetBytes(respBody, "code")
-	if code.String() == "400" {
-		message := gjson.GetBytes(respBody, "message")
-		return emperror.New(message.String())
-	}
-	return nil
+	return emqxapi.NewV4(requester).StopRebalance(ctx, emqxNodeName)
 }
 
-func getRequestBytes(rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) []byte {
+func getRebalanceRequest(rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) emqxapi.RebalanceRequest {
 	nodes := []string{}
 	for _, emqxNode := range emqx.Status.EmqxNodes {
 		nodes = append(nodes, emqxNode.Node)
 	}
 
-	body := map[string]interface{}{
-		"conn_evict_rate":    rebalance.Spec.RebalanceStrategy.ConnEvictRate,
-		"sess_evict_rate":    rebalance.Spec.RebalanceStrategy.SessEvictRate,
-		"wait_takeover":      rebalance.Spec.RebalanceStrategy.WaitTakeover,
-		"wait_health_check":  rebalance.Spec.RebalanceStrategy.WaitHealthCheck,
-		"abs_conn_threshold": rebalance.Spec.RebalanceStrategy.AbsConnThreshold,
-		"abs_sess_threshold": rebalance.Spec.RebalanceStrategy.AbsSessThreshold,
-		"nodes":              nodes,
+	req := emqxapi.RebalanceRequest{
+		ConnEvictRate:    rebalance.Spec.RebalanceStrategy.ConnEvictRate,
+		SessEvictRate:    rebalance.Spec.RebalanceStrategy.SessEvictRate,
+		WaitTakeover:     rebalance.Spec.RebalanceStrategy.WaitTakeover,
+		WaitHealthCheck:  rebalance.Spec.RebalanceStrategy.WaitHealthCheck,
+		AbsConnThreshold: rebalance.Spec.RebalanceStrategy.AbsConnThreshold,
+		AbsSessThreshold: rebalance.Spec.RebalanceStrategy.AbsSessThreshold,
+		Nodes:            nodes,
 	}
 
 	if len(rebalance.Spec.RebalanceStrategy.RelConnThreshold) > 0 {
 		relConnThreshold, _ := strconv.ParseFloat(rebalance.Spec.RebalanceStrategy.RelConnThreshold, 64)
-		body["rel_conn_threshold"] = relConnThreshold
+		req.RelConnThreshold = &relConnThreshold
 	}
 
 	if len(rebalance.Spec.RebalanceStrategy.RelSessThreshold) > 0 {
 		relSessThreshold, _ := strconv.ParseFloat(rebalance.Spec.RebalanceStrategy.RelSessThreshold, 64)
-		body["rel_sess_threshold"] = relSessThreshold
+		req.RelSessThreshold = &relSessThreshold
 	}
 
-	bytes, _ := json.Marshal(body)
-	return bytes
+	return req
 }
@@ -1,6 +1,7 @@
 package v1beta4
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"testing"
 
 	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/emqxapi/fake"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,10 +21,12 @@ type fakeRequester struct {
 	// Pod     *corev1.Pod
 }
 
-func (f *fakeRequester) GetHost() string     { return "" }
-func (f *fakeRequester) GetUsername() string { return "" }
-func (f *fakeRequester) GetPassword() string { return "" }
-func (f *fakeRequester) Request(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+func (f *fakeRequester) GetScheme() string                 { return "http" }
+func (f *fakeRequester) GetHost() string                   { return "" }
+func (f *fakeRequester) GetUsername() string               { return "" }
+func (f *fakeRequester) GetPassword() string               { return "" }
+func (f *fakeRequester) GetTLSConfig() *innerReq.TLSConfig { return nil }
+func (f *fakeRequester) Request(_ context.Context, method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
 	return f.request(method, path, body)
 }
 
@@ -40,7 +44,7 @@ func TestGetRebalanceStatus(t *testing.T) {
 			return
 		}
 
-		_, err := getRebalanceStatus(f)
+		_, err := getRebalanceStatus(context.Background(), f)
 		assert.Nil(t, err)
 	})
 
@@ -48,7 +52,7 @@ func TestGetRebalanceStatus(t *testing.T) {
 		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
 			return nil, nil, errors.New("fake error")
 		}
-		_, err := getRebalanceStatus(f)
+		_, err := getRebalanceStatus(context.Background(), f)
 		assert.Error(t, err, "fake error")
 	})
 
@@ -56,16 +60,16 @@ func TestGetRebalanceStatus(t *testing.T) {
 		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
 			return &http.Response{StatusCode: http.StatusBadRequest}, nil, nil
 		}
-		_, err := getRebalanceStatus(f)
-		assert.ErrorContains(t, err, "request api failed")
+		_, err := getRebalanceStatus(context.Background(), f)
+		assert.ErrorContains(t, err, "status: 400 Bad Request")
 	})
 
 	t.Run("check request return unexpected JSON", func(t *testing.T) {
 		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
 			return &http.Response{StatusCode: http.StatusOK}, nil, nil
 		}
-		_, err := getRebalanceStatus(f)
-		assert.ErrorContains(t, err, "failed to unmarshal rebalances")
+		_, err := getRebalanceStatus(context.Background(), f)
+		assert.ErrorContains(t, err, "failed to unmarshal response of API api/v4/load_rebalance/global_status")
 	})
 }
 
@@ -114,14 +118,15 @@ func TestStartRebalance(t *testing.T) {
 		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
 			assert.Equal(t, "POST", method)
 			assert.Equal(t, startPath, path)
-			assert.Equal(t, getRequestBytes(rebalance, emqx), body)
+			expectedBytes, _ := json.Marshal(getRebalanceRequest(rebalance, emqx))
+			assert.Equal(t, expectedBytes, body)
 			resp = &http.Response{StatusCode: http.StatusOK}
 			respBody = []byte(`{"data":[],"code":0}`)
 			err = nil
 			return
 		}
 
-		err := startRebalance(f, rebalance, emqx)
+		err := startRebalance(context.Background(), f, rebalance, emqx)
 		assert.Nil(t, err)
 	})
 
@@ -130,8 +135,8 @@ func TestStartRebalance(t *testing.T) {
 			return &http.Response{StatusCode: http.StatusBadRequest}, nil, nil
 		}
 
-		err := startRebalance(f, rebalance, emqx)
-		assert.ErrorContains(t, err, "request api failed")
+		err := startRebalance(context.Background(), f, rebalance, emqx)
+		assert.ErrorContains(t, err, "status: 400 Bad Request")
 	})
 
 	t.Run("check request start rebalance err", func(t *testing.T) {
@@ -141,7 +146,7 @@ func TestStartRebalance(t *testing.T) {
 			err = nil
 			return
 		}
-		err := startRebalance(f, rebalance, emqx)
+		err := startRebalance(context.Background(), f, rebalance, emqx)
 		assert.ErrorContains(t, err, "fake error")
 	})
 
@@ -149,7 +154,7 @@ func TestStartRebalance(t *testing.T) {
 		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
 			return nil, nil, errors.New("fake error")
 		}
-		err := startRebalance(f, rebalance, emqx)
+		err := startRebalance(context.Background(), f, rebalance, emqx)
 		assert.Error(t, err, "fake error")
 	})
 }
@@ -179,7 +184,7 @@ func TestStopRebalance(t *testing.T) {
 			err = nil
 			return
 		}
-		err := stopRebalance(f, rebalance)
+		err := stopRebalance(context.Background(), f, rebalance)
 		assert.Nil(t, err)
 	})
 
@@ -188,8 +193,8 @@ func TestStopRebalance(t *testing.T) {
 			return &http.Response{StatusCode: http.StatusBadRequest}, nil, nil
 		}
 
-		err := stopRebalance(f, rebalance)
-		assert.ErrorContains(t, err, "request api failed")
+		err := stopRebalance(context.Background(), f, rebalance)
+		assert.ErrorContains(t, err, "status: 400 Bad Request")
 	})
 
 	t.Run("check request stop rebalance err", func(t *testing.T) {
@@ -199,7 +204,7 @@ func TestStopRebalance(t *testing.T) {
 			err = nil
 			return
 		}
-		err := stopRebalance(f, rebalance)
+		err := stopRebalance(context.Background(), f, rebalance)
 		assert.ErrorContains(t, err, "rebalance is disabled")
 	})
 
@@ -207,12 +212,12 @@ func TestStopRebalance(t *testing.T) {
 		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
 			return nil, nil, errors.New("fake error")
 		}
-		err := stopRebalance(f, rebalance)
+		err := stopRebalance(context.Background(), f, rebalance)
 		assert.Error(t, err, "fake error")
 	})
 }
 
-func TestGetRequestBytes(t *testing.T) {
+func TestGetRebalanceRequest(t *testing.T) {
 	rebalance := &appsv1beta4.Rebalance{
 		Spec: appsv1beta4.RebalanceSpec{
 			RebalanceStrategy: appsv1beta4.RebalanceStrategy{
@@ -229,7 +234,7 @@ func TestGetRequestBytes(t *testing.T) {
 	}
 
 	t.Run("check get request bytes with full rebalanceStrategy", func(t *testing.T) {
-		bytes := getRequestBytes(rebalance, &appsv1beta4.EmqxEnterprise{})
+		bytes, _ := json.Marshal(getRebalanceRequest(rebalance, &appsv1beta4.EmqxEnterprise{}))
 
 		body := map[string]interface{}{
 			"conn_evict_rate":    rebalance.Spec.RebalanceStrategy.ConnEvictRate,
@@ -248,13 +253,13 @@ func TestGetRequestBytes(t *testing.T) {
 		body["rel_sess_threshold"] = relSessThreshold
 
 		expectedBytes, _ := json.Marshal(body)
-		assert.Equal(t, expectedBytes, bytes)
+		assert.JSONEq(t, string(expectedBytes), string(bytes))
 	})
 
 	t.Run("check get request bytes without relConnThreshold", func(t *testing.T) {
 		r := rebalance.DeepCopy()
 		r.Spec.RebalanceStrategy.RelConnThreshold = ""
-		bytes := getRequestBytes(r, &appsv1beta4.EmqxEnterprise{})
+		bytes, _ := json.Marshal(getRebalanceRequest(r, &appsv1beta4.EmqxEnterprise{}))
 
 		body := map[string]interface{}{
 			"conn_evict_rate":    rebalance.Spec.RebalanceStrategy.ConnEvictRate,
@@ -270,13 +275,13 @@ func TestGetRequestBytes(t *testing.T) {
 		body["rel_sess_threshold"] = relSessThreshold
 
 		expectedBytes, _ := json.Marshal(body)
-		assert.Equal(t, expectedBytes, bytes)
+		assert.JSONEq(t, string(expectedBytes), string(bytes))
 	})
 
 	t.Run("check get request bytes without relSessThreshold", func(t *testing.T) {
 		r := rebalance.DeepCopy()
 		r.Spec.RebalanceStrategy.RelSessThreshold = ""
-		bytes := getRequestBytes(r, &appsv1beta4.EmqxEnterprise{})
+		bytes, _ := json.Marshal(getRebalanceRequest(r, &appsv1beta4.EmqxEnterprise{}))
 
 		body := map[string]interface{}{
 			"conn_evict_rate":    rebalance.Spec.RebalanceStrategy.ConnEvictRate,
@@ -292,7 +297,7 @@ func TestGetRequestBytes(t *testing.T) {
 		body["rel_conn_threshold"] = relConnThreshold
 
 		expectedBytes, _ := json.Marshal(body)
-		assert.Equal(t, expectedBytes, bytes)
+		assert.JSONEq(t, string(expectedBytes), string(bytes))
 	})
 }
 
@@ -305,24 +310,24 @@ func TestRebalanceStatusHandler(t *testing.T) {
 	}
 	emqxEnterprise := &appsv1beta4.EmqxEnterprise{}
 	f := &fakeRequester{}
-	defStartFun := func(requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) error {
+	defStartFun := func(_ context.Context, requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) error {
 		return nil
 	}
-	defGetFun := func(innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error) {
+	defGetFun := func(context.Context, innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error) {
 		return []appsv1beta4.RebalanceState{}, nil
 	}
 	t.Run("check start rebalance failed", func(t *testing.T) {
 		r := rebalance.DeepCopy()
 
-		startFun := func(requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) error {
+		startFun := func(_ context.Context, requester innerReq.RequesterInterface, rebalance *appsv1beta4.Rebalance, emqx *appsv1beta4.EmqxEnterprise) error {
 			return errors.New("fake error")
 		}
-		rebalanceStatusHandler(r, emqxEnterprise, f, startFun, defGetFun)
+		rebalanceStatusHandler(context.Background(), r, emqxEnterprise, f, startFun, defGetFun)
 		assert.Equal(t, appsv1beta4.RebalancePhaseFailed, r.Status.Phase)
 	})
 	t.Run("check start rebalance success", func(t *testing.T) {
 		r := rebalance.DeepCopy()
-		rebalanceStatusHandler(r, emqxEnterprise, f, defStartFun, defGetFun)
+		rebalanceStatusHandler(context.Background(), r, emqxEnterprise, f, defStartFun, defGetFun)
 		assert.Equal(t, appsv1beta4.RebalancePhaseProcessing, r.Status.Phase)
 	})
 
@@ -330,11 +335,11 @@ func TestRebalanceStatusHandler(t *testing.T) {
 		r := rebalance.DeepCopy()
 		r.Status.Phase = appsv1beta4.RebalancePhaseProcessing
 
-		getFun := func(innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error) {
+		getFun := func(context.Context, innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error) {
 			return nil, errors.New("fake error")
 		}
 
-		rebalanceStatusHandler(r, emqxEnterprise, f, defStartFun, getFun)
+		rebalanceStatusHandler(context.Background(), r, emqxEnterprise, f, defStartFun, getFun)
 		assert.Equal(t, appsv1beta4.RebalancePhaseFailed, r.Status.Phase)
 	})
 
@@ -342,7 +347,7 @@ func TestRebalanceStatusHandler(t *testing.T) {
 		r := rebalance.DeepCopy()
 		r.Status.Phase = appsv1beta4.RebalancePhaseProcessing
 
-		rebalanceStatusHandler(r, emqxEnterprise, f, defStartFun, defGetFun)
+		rebalanceStatusHandler(context.Background(), r, emqxEnterprise, f, defStartFun, defGetFun)
 		assert.Equal(t, appsv1beta4.RebalancePhaseCompleted, r.Status.Phase)
 	})
 
@@ -350,7 +355,7 @@ func TestRebalanceStatusHandler(t *testing.T) {
 		r := rebalance.DeepCopy()
 		r.Status.Phase = appsv1beta4.RebalancePhaseProcessing
 
-		getFun := func(innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error) {
+		getFun := func(context.Context, innerReq.RequesterInterface) ([]appsv1beta4.RebalanceState, error) {
 			return []appsv1beta4.RebalanceState{
 				{
 					State: "processing",
@@ -358,7 +363,7 @@ func TestRebalanceStatusHandler(t *testing.T) {
 			}, nil
 		}
 
-		rebalanceStatusHandler(r, emqxEnterprise, f, defStartFun, getFun)
+		rebalanceStatusHandler(context.Background(), r, emqxEnterprise, f, defStartFun, getFun)
 		assert.Equal(t, appsv1beta4.RebalancePhaseProcessing, r.Status.Phase)
 		assert.Equal(t, "processing", r.Status.RebalanceStates[0].State)
 	})
@@ -369,7 +374,7 @@ func TestRebalanceStatusHandler(t *testing.T) {
 		r.Status.RebalanceStates = []appsv1beta4.RebalanceState{
 			{State: "fake"},
 		}
-		rebalanceStatusHandler(r, emqxEnterprise, f, defStartFun, defGetFun)
+		rebalanceStatusHandler(context.Background(), r, emqxEnterprise, f, defStartFun, defGetFun)
 		assert.Nil(t, r.Status.RebalanceStates)
 	})
 
@@ -379,7 +384,53 @@ func TestRebalanceStatusHandler(t *testing.T) {
 		r.Status.RebalanceStates = []appsv1beta4.RebalanceState{
 			{State: "fake"},
 		}
-		rebalanceStatusHandler(r, emqxEnterprise, f, defStartFun, defGetFun)
+		rebalanceStatusHandler(context.Background(), r, emqxEnterprise, f, defStartFun, defGetFun)
 		assert.Nil(t, r.Status.RebalanceStates)
 	})
 }
+
+func TestRebalanceFlow(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+	s.AddNode(
+		fake.Node{Name: "emqx-ee@emqx-ee-0.emqx-ee-headless.default.svc.cluster.local", Edition: "Enterprise", Connections: 60},
+		fake.Node{Name: "emqx-ee@emqx-ee-1.emqx-ee-headless.default.svc.cluster.local", Edition: "Enterprise"},
+		fake.Node{Name: "emqx-ee@emqx-ee-2.emqx-ee-headless.default.svc.cluster.local", Edition: "Enterprise"},
+	)
+	emqx := &appsv1beta4.EmqxEnterprise{}
+	for _, node := range s.Nodes() {
+		emqx.Status.EmqxNodes = append(emqx.Status.EmqxNodes, appsv1beta4.EmqxNode{Node: node.Name})
+	}
+	rebalance := &appsv1beta4.Rebalance{
+		Spec: appsv1beta4.RebalanceSpec{
+			RebalanceStrategy: appsv1beta4.RebalanceStrategy{
+				ConnEvictRate:    20,
+				SessEvictRate:    20,
+				AbsConnThreshold: 5,
+			},
+		},
+	}
+
+	rebalanceStatusHandler(context.Background(), rebalance, emqx, s.Requester(), startRebalance, getRebalanceStatus)
+	assert.Equal(t, appsv1beta4.RebalancePhaseProcessing, rebalance.Status.Phase)
+
+	for i := 0; i < 10 && rebalance.Status.Phase == appsv1beta4.RebalancePhaseProcessing; i++ {
+		s.Step()
+		rebalanceStatusHandler(context.Background(), rebalance, emqx, s.Requester(), startRebalance, getRebalanceStatus)
+		if rebalance.Status.Phase == appsv1beta4.RebalancePhaseProcessing {
+			assert.Equal(t, emqx.Status.EmqxNodes[0].Node, rebalance.Status.RebalanceStates[0].CoordinatorNode)
+		}
+	}
+	assert.Equal(t, appsv1beta4.RebalancePhaseCompleted, rebalance.Status.Phase)
+	for _, node := range s.Nodes() {
+		assert.Equal(t, int64(20), node.Connections)
+	}
+
+	t.Run("start rebalance failed", func(t *testing.T) {
+		r := rebalance.DeepCopy()
+		r.Status = appsv1beta4.RebalanceStatus{}
+		rebalanceStatusHandler(context.Background(), r, emqx, s.Requester(), startRebalance, getRebalanceStatus)
+		assert.Equal(t, appsv1beta4.RebalancePhaseFailed, r.Status.Phase)
+		assert.Contains(t, r.Status.Conditions[0].Message, "nothing_to_balance")
+	})
+}
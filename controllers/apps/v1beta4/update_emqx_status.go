@@ -2,12 +2,15 @@ package v1beta4
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 
 	emperror "emperror.dev/errors"
 	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/emqx/emqx-operator/internal/metrics"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
-	"github.com/tidwall/gjson"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -18,10 +21,13 @@ type updateEmqxStatus struct {
 }
 
 func (s updateEmqxStatus) reconcile(ctx context.Context, instance appsv1beta4.Emqx, _ ...any) subResult {
-	if err := s.updateReadyReplicas(instance); err != nil {
+	if err := s.updateReadyReplicas(ctx, instance); err != nil {
 		return subResult{cont: true, err: emperror.Wrap(err, "failed to update ready replicas")}
 	}
-	if err := s.updateCondition(instance); err != nil {
+	if err := s.updateAlarms(ctx, instance); err != nil {
+		s.EventRecorder.Event(instance, corev1.EventTypeWarning, "FailedToGetAlarms", err.Error())
+	}
+	if err := s.updateCondition(ctx, instance); err != nil {
 		return subResult{cont: true, err: emperror.Wrap(err, "failed to update condition")}
 	}
 	if err := s.Client.Status().Update(ctx, instance); err != nil {
@@ -31,25 +37,97 @@ func (s updateEmqxStatus) reconcile(ctx context.Context, instance appsv1beta4.Em
 	return subResult{}
 }
 
-func (s updateEmqxStatus) updateReadyReplicas(instance appsv1beta4.Emqx) error {
-	emqxNodes, err := s.getNodeStatusesByAPI()
+func (s updateEmqxStatus) updateReadyReplicas(ctx context.Context, instance appsv1beta4.Emqx) error {
+	emqxNodes, err := emqxapi.NewV4(s.Requester).Nodes(ctx)
 	if err != nil {
 		return emperror.Wrap(err, "failed to get node statuses")
 	}
 
 	readyReplicas := int32(0)
+	connections := int64(0)
+	var versions []string
+	seen := map[string]bool{}
 	for _, node := range emqxNodes {
 		if node.NodeStatus == "Running" {
 			readyReplicas++
 		}
+		connections += node.Connections
+		if node.Version != "" && !seen[node.Version] {
+			seen[node.Version] = true
+			versions = append(versions, node.Version)
+		}
 	}
+	sort.Strings(versions)
+	// EMQX 4 has no db role, all nodes are exported as core nodes
+	metrics.SetNodesGauges(getKind(instance), instance.GetNamespace(), instance.GetName(), "core", readyReplicas, connections, 0)
 	instance.GetStatus().SetEmqxNodes(emqxNodes)
 	instance.GetStatus().SetReadyReplicas(readyReplicas)
 	instance.GetStatus().SetReplicas(*instance.GetSpec().GetReplicas())
+	instance.GetStatus().SetReady(fmt.Sprintf("%d/%d", readyReplicas, *instance.GetSpec().GetReplicas()))
+	instance.GetStatus().SetCurrentVersion(strings.Join(versions, ","))
+	instance.GetStatus().SetConnections(connections)
 	return nil
 }
 
-func (s updateEmqxStatus) updateCondition(instance appsv1beta4.Emqx) error {
+// updateAlarms sets the active alarms and the AlarmsActive condition, the alarms activated or deactivated
+// since the last reconciliation are recorded as events.
+func (s updateEmqxStatus) updateAlarms(ctx context.Context, instance appsv1beta4.Emqx) error {
+	alarms, err := emqxapi.NewV4(s.Requester).Alarms(ctx)
+	if err != nil {
+		return emperror.Wrap(err, "failed to get alarms")
+	}
+	sort.Slice(alarms, func(i, j int) bool {
+		if alarms[i].Node != alarms[j].Node {
+			return alarms[i].Node < alarms[j].Node
+		}
+		return alarms[i].Name < alarms[j].Name
+	})
+
+	activated, deactivated := diffAlarms(instance.GetStatus().GetAlarms(), alarms)
+	for _, alarm := range activated {
+		s.EventRecorder.Eventf(instance, corev1.EventTypeWarning, "AlarmActivated", "Alarm %s is activated on node %s: %s", alarm.Name, alarm.Node, alarm.Message)
+	}
+	for _, alarm := range deactivated {
+		s.EventRecorder.Eventf(instance, corev1.EventTypeWarning, "AlarmDeactivated", "Alarm %s is deactivated on node %s", alarm.Name, alarm.Node)
+	}
+	instance.GetStatus().SetAlarms(alarms)
+
+	if len(alarms) == 0 {
+		instance.GetStatus().AddCondition(appsv1beta4.ConditionAlarmsActive, corev1.ConditionFalse, "NoActiveAlarms", "No active alarms")
+		return nil
+	}
+	var names []string
+	for _, alarm := range alarms {
+		names = append(names, fmt.Sprintf("%s on %s", alarm.Name, alarm.Node))
+	}
+	instance.GetStatus().AddCondition(appsv1beta4.ConditionAlarmsActive, corev1.ConditionTrue, "AlarmsActive", "Active alarms: "+strings.Join(names, ", "))
+	return nil
+}
+
+// diffAlarms returns the alarms in new but not in old, and the alarms in old but not in new
+func diffAlarms(old, new []appsv1beta4.EmqxAlarm) (activated, deactivated []appsv1beta4.EmqxAlarm) {
+	contains := func(list []appsv1beta4.EmqxAlarm, alarm appsv1beta4.EmqxAlarm) bool {
+		for _, a := range list {
+			if a.Node == alarm.Node && a.Name == alarm.Name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, alarm := range new {
+		if !contains(old, alarm) {
+			activated = append(activated, alarm)
+		}
+	}
+	for _, alarm := range old {
+		if !contains(new, alarm) {
+			deactivated = append(deactivated, alarm)
+		}
+	}
+	return
+}
+
+func (s updateEmqxStatus) updateCondition(ctx context.Context, instance appsv1beta4.Emqx) error {
 	inClusterStss, err := getInClusterStatefulSets(s.Client, instance)
 	if err != nil {
 		return emperror.Wrap(err, "failed to get in cluster statefulsets")
@@ -108,39 +186,11 @@ func (s updateEmqxStatus) updateCondition(instance appsv1beta4.Emqx) error {
 			enterprise.Status.EmqxBlueGreenUpdateStatus.StartedAt = &now
 		}
 
-		evacuationsStatus, err := s.getEvacuationStatusByAPI()
+		rebalanceStatus, err := emqxapi.NewV4(s.Requester).RebalanceStatus(ctx)
 		if err != nil {
 			return emperror.Wrap(err, "failed to get evacuation status")
 		}
-		enterprise.Status.EmqxBlueGreenUpdateStatus.EvacuationsStatus = evacuationsStatus
+		enterprise.Status.EmqxBlueGreenUpdateStatus.EvacuationsStatus = rebalanceStatus.Evacuations
 	}
 	return nil
 }
-
-// Request API
-func (s updateEmqxStatus) getNodeStatusesByAPI() ([]appsv1beta4.EmqxNode, error) {
-	_, body, err := s.Requester.Request("GET", "api/v4/nodes", nil)
-	if err != nil {
-		return nil, err
-	}
-	emqxNodes := []appsv1beta4.EmqxNode{}
-	data := gjson.GetBytes(body, "data")
-	if err := json.Unmarshal([]byte(data.Raw), &emqxNodes); err != nil {
-		return nil, emperror.Wrap(err, "failed to unmarshal node statuses")
-	}
-	return emqxNodes, nil
-}
-
-func (s updateEmqxStatus) getEvacuationStatusByAPI() ([]appsv1beta4.EmqxEvacuationStatus, error) {
-	_, body, err := s.Requester.Request("GET", "api/v4/load_rebalance/global_status", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	evacuationStatuses := []appsv1beta4.EmqxEvacuationStatus{}
-	data := gjson.GetBytes(body, "evacuations")
-	if err := json.Unmarshal([]byte(data.Raw), &evacuationStatuses); err != nil {
-		return nil, emperror.Wrap(err, "failed to unmarshal node statuses")
-	}
-	return evacuationStatuses, nil
-}
@@ -0,0 +1,89 @@
+package v1beta4
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestUpdateReadyReplicas(t *testing.T) {
+	f := &fakeRequester{
+		request: func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+			assert.Equal(t, "api/v4/nodes", path)
+			resp = &http.Response{StatusCode: http.StatusOK}
+			respBody = []byte(`{"code":0,"data":[
+				{"node":"emqx@10.0.0.1","node_status":"Running","version":"4.4.14","connections":3},
+				{"node":"emqx@10.0.0.2","node_status":"Running","version":"4.4.15","connections":4},
+				{"node":"emqx@10.0.0.3","node_status":"Stopped","version":"4.4.14"}
+			]}`)
+			return
+		},
+	}
+	s := updateEmqxStatus{Requester: f}
+	instance := &appsv1beta4.EmqxEnterprise{
+		Spec: appsv1beta4.EmqxEnterpriseSpec{Replicas: pointer.Int32(3)},
+	}
+
+	assert.Nil(t, s.updateReadyReplicas(context.Background(), instance))
+	assert.Equal(t, int32(2), instance.Status.ReadyReplicas)
+	assert.Equal(t, "2/3", instance.Status.Ready)
+	assert.Equal(t, "4.4.14,4.4.15", instance.Status.CurrentVersion)
+	assert.Equal(t, int64(7), instance.Status.Connections)
+}
+
+func TestUpdateAlarms(t *testing.T) {
+	f := &fakeRequester{}
+	recorder := record.NewFakeRecorder(10)
+	s := updateEmqxStatus{
+		EmqxReconciler: &EmqxReconciler{EventRecorder: recorder},
+		Requester:      f,
+	}
+	instance := &appsv1beta4.EmqxEnterprise{}
+
+	t.Run("alarms activated", func(t *testing.T) {
+		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+			assert.Equal(t, "GET", method)
+			assert.Equal(t, "api/v4/alarms/activated", path)
+			resp = &http.Response{StatusCode: http.StatusOK}
+			respBody = []byte(`{"code":0,"data":[
+				{"node":"emqx@10.0.0.2","alarms":[{"name":"high_system_memory_usage","message":"System memory usage is higher than 70%","duration":1000}]},
+				{"node":"emqx@10.0.0.1","alarms":[]}
+			]}`)
+			return
+		}
+		assert.Nil(t, s.updateAlarms(context.Background(), instance))
+		assert.Equal(t, []appsv1beta4.EmqxAlarm{
+			{Node: "emqx@10.0.0.2", Name: "high_system_memory_usage", Message: "System memory usage is higher than 70%"},
+		}, instance.Status.Alarms)
+		assert.Equal(t, "Warning AlarmActivated Alarm high_system_memory_usage is activated on node emqx@10.0.0.2: System memory usage is higher than 70%", <-recorder.Events)
+		assert.Equal(t, appsv1beta4.ConditionAlarmsActive, instance.Status.Conditions[0].Type)
+		assert.Equal(t, corev1.ConditionTrue, instance.Status.Conditions[0].Status)
+	})
+
+	t.Run("alarms deactivated", func(t *testing.T) {
+		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+			resp = &http.Response{StatusCode: http.StatusOK}
+			respBody = []byte(`{"code":0,"data":[{"node":"emqx@10.0.0.2","alarms":[]}]}`)
+			return
+		}
+		assert.Nil(t, s.updateAlarms(context.Background(), instance))
+		assert.Empty(t, instance.Status.Alarms)
+		assert.Equal(t, "Warning AlarmDeactivated Alarm high_system_memory_usage is deactivated on node emqx@10.0.0.2", <-recorder.Events)
+		assert.Equal(t, corev1.ConditionFalse, instance.Status.Conditions[0].Status)
+	})
+
+	t.Run("unexpected JSON", func(t *testing.T) {
+		f.request = func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+			resp = &http.Response{StatusCode: http.StatusOK}
+			respBody = []byte(`{"code":0,"data":[{"node":"emqx@10.0.0.2","alarms":"fake"}]}`)
+			return
+		}
+		assert.ErrorContains(t, s.updateAlarms(context.Background(), instance), "failed to unmarshal data of API api/v4/alarms/activated")
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	emperror "emperror.dev/errors"
 	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -59,7 +60,7 @@ func (u updatePodConditions) reconcile(ctx context.Context, instance appsv1beta4
 
 		onServerCondition.Status = corev1.ConditionTrue
 		if enterprise, ok := instance.(*appsv1beta4.EmqxEnterprise); ok {
-			s, err := u.checkRebalanceStatus(enterprise, pod.DeepCopy())
+			s, err := u.checkRebalanceStatus(ctx, enterprise, pod.DeepCopy())
 			if err != nil {
 				return subResult{err: err}
 			}
@@ -79,17 +80,25 @@ func (u updatePodConditions) reconcile(ctx context.Context, instance appsv1beta4
 	return subResult{}
 }
 
-func (u updatePodConditions) checkRebalanceStatus(instance *appsv1beta4.EmqxEnterprise, pod *corev1.Pod) (corev1.ConditionStatus, error) {
+func (u updatePodConditions) checkRebalanceStatus(ctx context.Context, instance *appsv1beta4.EmqxEnterprise, pod *corev1.Pod) (corev1.ConditionStatus, error) {
+	_, port := getManagementAPIListener(instance)
 	requester := &innerReq.Requester{
-		Username: u.Requester.GetUsername(),
-		Password: u.Requester.GetPassword(),
-		Host:     fmt.Sprintf("%s:8081", pod.Status.PodIP),
+		Scheme:    u.Requester.GetScheme(),
+		Username:  u.Requester.GetUsername(),
+		Password:  u.Requester.GetPassword(),
+		Host:      fmt.Sprintf("%s:%s", pod.Status.PodIP, port),
+		TLS:       u.Requester.GetTLSConfig(),
+		Kind:      getKind(instance),
+		Namespace: instance.GetNamespace(),
+		Instance:  instance.GetName(),
+		// 503 means the node is being evacuated or rebalanced, it is not retried
+		Retries: -1,
 	}
-	resp, _, err := requester.Request("GET", "api/v4/load_rebalance/availability_check", nil)
+	available, err := emqxapi.NewV4(requester).AvailabilityCheck(ctx)
 	if err != nil {
 		return corev1.ConditionUnknown, emperror.Wrapf(err, "failed to check availability for pod/%s", pod.Name)
 	}
-	if resp.StatusCode != 200 {
+	if !available {
 		return corev1.ConditionFalse, nil
 	}
 	return corev1.ConditionTrue, nil
@@ -3,12 +3,15 @@ package v2alpha2
 import (
 	"context"
 	"fmt"
+	"net"
 	"reflect"
-	"strings"
+	"sort"
+	"time"
 
 	emperror "emperror.dev/errors"
 	"github.com/cisco-open/k8s-objectmatcher/patch"
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -25,8 +28,12 @@ type addCore struct {
 	*EMQXReconciler
 }
 
-func (a *addCore) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _ innerReq.RequesterInterface) subResult {
+func (a *addCore) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface) subResult {
 	preSts := a.getNewStatefulSet(ctx, instance)
+	if preSts.UID == "" && preSts.Labels[appsv2alpha2.PodTemplateHashLabelKey] == instance.Status.CoreNodesStatus.FailedRevision {
+		// The revision has been rolled back, do not roll it out again until the pod template is changed
+		return subResult{}
+	}
 	if preSts.UID == "" {
 		_ = ctrl.SetControllerReference(instance, preSts, a.Scheme)
 		if err := a.Handler.Create(preSts); err != nil {
@@ -40,17 +47,36 @@ func (a *addCore) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _
 			}
 			return subResult{err: emperror.Wrap(err, "failed to create statefulSet")}
 		}
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "CreateNewStatefulSet", fmt.Sprintf("Create new statefulSet %s for EMQX core nodes", preSts.Name))
 		instance.Status.SetCondition(metav1.Condition{
-			Type:    appsv2alpha2.CoreNodesProgressing,
-			Status:  metav1.ConditionTrue,
-			Reason:  "CreateNewStatefulSet",
-			Message: "Create new statefulSet",
+			Type:               appsv2alpha2.CoreNodesProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             "CreateNewStatefulSet",
+			Message:            "Create new statefulSet",
+			ObservedGeneration: instance.Generation,
 		})
-		instance.Status.CoreNodesStatus.CurrentRevision = preSts.Labels[appsv2alpha2.PodTemplateHashLabelKey]
+		instance.Status.CoreNodesStatus.CurrentRevision = getRevision(preSts.Spec.Template.ObjectMeta)
+		instance.Status.CoreNodesStatus.FailedRevision = ""
 		_ = a.Client.Status().Update(ctx, instance)
 	} else {
 		storageSts := &appsv1.StatefulSet{}
 		_ = a.Client.Get(ctx, client.ObjectKeyFromObject(preSts), storageSts)
+		if storageSts.Spec.Replicas != nil && *preSts.Spec.Replicas < *storageSts.Spec.Replicas {
+			// Scale in one EMQX core node at a time, after it has left the cluster
+			left, leaving, err := a.leaveCluster(ctx, instance, r, storageSts)
+			if err != nil {
+				return subResult{err: emperror.Wrap(err, "failed to scale in statefulSet")}
+			}
+			if leaving {
+				// Check whether the node has left the cluster soon
+				return subResult{result: ctrl.Result{RequeueAfter: time.Second}}
+			}
+			if left {
+				preSts.Spec.Replicas = pointer.Int32(*storageSts.Spec.Replicas - 1)
+			} else {
+				preSts.Spec.Replicas = storageSts.Spec.Replicas
+			}
+		}
 		patchResult, _ := a.Patcher.Calculate(storageSts, preSts,
 			patch.IgnoreStatusFields(),
 			patch.IgnoreVolumeClaimTemplateTypeMetaAndStatus(),
@@ -59,18 +85,32 @@ func (a *addCore) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _
 			logger := log.FromContext(ctx)
 			logger.V(1).Info("got different statefulSet for EMQX core nodes, will update statefulSet", "patch", string(patchResult.Patch))
 
-			_ = a.Handler.Update(preSts)
+			if err := a.Handler.Update(preSts); err != nil {
+				return subResult{err: emperror.Wrap(err, "failed to update statefulSet")}
+			}
 			instance.Status.SetCondition(metav1.Condition{
-				Type:    appsv2alpha2.CoreNodesProgressing,
-				Status:  metav1.ConditionTrue,
-				Reason:  "CreateNewStatefulSet",
-				Message: "Create new statefulSet",
+				Type:               appsv2alpha2.CoreNodesProgressing,
+				Status:             metav1.ConditionTrue,
+				Reason:             "CreateNewStatefulSet",
+				Message:            "Create new statefulSet",
+				ObservedGeneration: instance.Generation,
 			})
 			_ = a.Client.Status().Update(ctx, instance)
 		}
+		// The rolling update advances the revision of the current statefulSet in place
+		if revision := getRevision(preSts.Spec.Template.ObjectMeta); revision != instance.Status.CoreNodesStatus.CurrentRevision {
+			instance.Status.CoreNodesStatus.CurrentRevision = revision
+			if err := a.Client.Status().Update(ctx, instance); err != nil {
+				return subResult{err: emperror.Wrap(err, "failed to update status")}
+			}
+		}
+	}
+
+	if err := a.pruneHistory(ctx, instance); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to prune old statefulSets")}
 	}
 
-	if err := a.sync(ctx, instance); err != nil {
+	if err := a.sync(ctx, instance, r); err != nil {
 		return subResult{err: emperror.Wrap(err, "failed to sync replicaSet")}
 	}
 
@@ -84,6 +124,13 @@ func (a *addCore) getNewStatefulSet(ctx context.Context, instance *appsv2alpha2.
 	preSts.Labels = appsv2alpha2.CloneAndAddLabel(preSts.Labels, appsv2alpha2.PodTemplateHashLabelKey, podTemplateSpecHash)
 	preSts.Spec.Template.Labels = appsv2alpha2.CloneAndAddLabel(preSts.Spec.Template.Labels, appsv2alpha2.PodTemplateHashLabelKey, podTemplateSpecHash)
 	preSts.Spec.Selector = appsv2alpha2.CloneSelectorAndAddLabel(preSts.Spec.Selector, appsv2alpha2.PodTemplateHashLabelKey, podTemplateSpecHash)
+	preSts.Spec.Template.Annotations = appsv2alpha2.CloneAndAddLabel(preSts.Spec.Template.Annotations, appsv2alpha2.PodTemplateRevisionAnnotationKey, podTemplateSpecHash)
+	if isRollingUpdate(instance) {
+		// The operator deletes the outdated pods by itself, so that every step can wait for the EMQX node to join the cluster
+		preSts.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+			Type: appsv1.OnDeleteStatefulSetStrategyType,
+		}
+	}
 
 	currentSts, _ := getStateFulSetList(ctx, a.Client, instance)
 	if currentSts == nil {
@@ -103,11 +150,24 @@ func (a *addCore) getNewStatefulSet(ctx context.Context, instance *appsv2alpha2.
 	}
 
 	logger := log.FromContext(ctx)
+	if isRollingUpdate(instance) {
+		logger.V(1).Info("got different pod template for EMQX core nodes, will rolling update statefulSet", "patch", string(patchResult.Patch))
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "NewRevision", fmt.Sprintf("New revision %s of EMQX core nodes, will rolling update statefulSet %s, changed: %s", podTemplateSpecHash, currentSts.Name, summarizePatch(patchResult.Patch)))
+		// The selector of statefulSet is immutable, keep the pod template hash label of the current statefulSet
+		preSts.ObjectMeta = currentSts.ObjectMeta
+		preSts.Spec.Template.Labels = currentSts.Spec.Template.Labels
+		preSts.Spec.Selector = currentSts.Spec.Selector
+		return preSts
+	}
+
 	logger.V(1).Info("got different pod template for EMQX core nodes, will create new statefulSet", "patch", string(patchResult.Patch))
+	if podTemplateSpecHash != instance.Status.CoreNodesStatus.FailedRevision {
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "NewRevision", fmt.Sprintf("New revision %s of EMQX core nodes, will create new statefulSet, changed: %s", podTemplateSpecHash, summarizePatch(patchResult.Patch)))
+	}
 	return preSts
 }
 
-func (a *addCore) sync(ctx context.Context, instance *appsv2alpha2.EMQX) error {
+func (a *addCore) sync(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface) error {
 	if isExistReplicant(instance) {
 		_, oldRsList := getReplicaSetList(ctx, a.Client, instance)
 		if len(oldRsList) != 0 {
@@ -116,44 +176,215 @@ func (a *addCore) sync(ctx context.Context, instance *appsv2alpha2.EMQX) error {
 		}
 	}
 
-	_, oldStsList := getStateFulSetList(ctx, a.Client, instance)
+	currentSts, oldStsList := getStateFulSetList(ctx, a.Client, instance)
 	if len(oldStsList) == 0 {
+		if isRollingUpdate(instance) && currentSts != nil {
+			return a.rollingUpdate(ctx, instance, currentSts)
+		}
 		return nil
 	}
 
 	oldest := oldStsList[0].DeepCopy()
 
-	if a.findCanBeDeletePod(ctx, instance, oldest) != nil {
+	pod, err := a.findCanBeDeletePod(ctx, instance, r, currentSts, oldest)
+	if err != nil {
+		return err
+	}
+	if pod != nil {
 		oldest.Spec.Replicas = pointer.Int32Ptr(oldest.Status.Replicas - 1)
 		if err := a.Client.Update(ctx, oldest); err != nil {
 			return emperror.Wrap(err, "failed to scale down old replicaSet")
 		}
+		if *oldest.Spec.Replicas == 0 {
+			a.EventRecorder.Event(instance, corev1.EventTypeNormal, "RetireRevision", fmt.Sprintf("Old statefulSet %s of revision %s is scaled down to 0", oldest.Name, getRevision(oldest.Spec.Template.ObjectMeta)))
+		}
 		return nil
 	}
 
 	return nil
 }
 
-func (a *addCore) findCanBeDeletePod(ctx context.Context, instance *appsv2alpha2.EMQX, old *appsv1.StatefulSet) *corev1.Pod {
-	if !canBeScaledDown(instance, appsv2alpha2.Ready, getEventList(ctx, a.Clientset, old)) {
+// pruneHistory deletes the oldest statefulSets that have been scaled down, retains at most revisionHistoryLimit old statefulSets
+func (a *addCore) pruneHistory(ctx context.Context, instance *appsv2alpha2.EMQX) error {
+	var scaledDown []*appsv1.StatefulSet
+	for _, obj := range getHistoryStatefulSetList(ctx, a.Client, instance) {
+		if *obj.Spec.Replicas == 0 && obj.Status.Replicas == 0 {
+			scaledDown = append(scaledDown, obj)
+		}
+	}
+
+	logger := log.FromContext(ctx)
+	for i := 0; i < len(scaledDown)-getRevisionHistoryLimit(instance); i++ {
+		logger.V(1).Info("delete old statefulSet for EMQX core nodes", "statefulSet", scaledDown[i].Name)
+		if err := a.Client.Delete(ctx, scaledDown[i]); err != nil && !k8sErrors.IsNotFound(err) {
+			return emperror.Wrapf(err, "failed to delete statefulSet %s", scaledDown[i].Name)
+		}
+	}
+	return nil
+}
+
+func (a *addCore) rollingUpdate(ctx context.Context, instance *appsv2alpha2.EMQX, sts *appsv1.StatefulSet) error {
+	pods, outdated := getOutdatedPods(ctx, a.Client, sts.Namespace, sts.Spec.Selector.MatchLabels, sts.Spec.Template.Annotations[appsv2alpha2.PodTemplateRevisionAnnotationKey])
+	if len(outdated) == 0 {
 		return nil
 	}
+
+	// Like the statefulSet controller, update the pods in reverse ordinal order
+	sort.Slice(outdated, func(i, j int) bool {
+		return getPodOrdinal(outdated[i]) > getPodOrdinal(outdated[j])
+	})
+
+	replicas := *instance.Spec.CoreTemplate.Spec.Replicas
+	maxUnavailable, _ := getRollingUpdateBudget(instance, replicas)
+	logger := log.FromContext(ctx)
+	for _, pod := range selectPodsToRoll(instance.Status.CoreNodesStatus.Nodes, pods, outdated, replicas, maxUnavailable) {
+		logger.V(1).Info("delete outdated pod for rolling update EMQX core nodes", "pod", pod.Name)
+		if err := a.Client.Delete(ctx, pod); err != nil && !k8sErrors.IsNotFound(err) {
+			return emperror.Wrapf(err, "failed to delete pod %s", pod.Name)
+		}
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "RollingUpdate", fmt.Sprintf("Delete outdated pod %s with %d sessions", pod.Name, getPodSession(instance.Status.CoreNodesStatus.Nodes, pod)))
+	}
+	return nil
+}
+
+// leaveCluster makes the EMQX core node with the highest ordinal of the statefulSet leave the cluster before it is scaled in,
+// the clients on the node are evacuated first. It returns left when the node is no longer in the cluster,
+// and leaving when the node is requested to leave, so the caller checks it again soon.
+func (a *addCore) leaveCluster(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface, sts *appsv1.StatefulSet) (left, leaving bool, err error) {
+	pod := &corev1.Pod{}
+	if err := a.Client.Get(ctx, types.NamespacedName{
+		Namespace: sts.Namespace,
+		Name:      fmt.Sprintf("%s-%d", sts.Name, *sts.Spec.Replicas-1),
+	}, pod); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return true, false, nil
+		}
+		return false, false, emperror.Wrap(err, "failed to get pod")
+	}
+
+	node := findEMQXNodeByPod(instance.Status.CoreNodesStatus.Nodes, pod)
+	if node == nil {
+		return true, false, nil
+	}
+	if r == nil {
+		a.EventRecorder.Event(instance, corev1.EventTypeWarning, "LeaveCluster", fmt.Sprintf("Node %s can not leave the cluster, the management API is unavailable", node.Node))
+		return false, false, nil
+	}
+
+	podList := &corev1.PodList{}
+	_ = a.Client.List(ctx, podList, client.InNamespace(sts.Namespace), client.MatchingLabels(sts.Spec.Selector.MatchLabels))
+	var remainPods []corev1.Pod
+	for _, p := range podList.Items {
+		if p.Name != pod.Name && getPodOrdinal(&p) < int(*sts.Spec.Replicas-1) {
+			remainPods = append(remainPods, p)
+		}
+	}
+
+	// The EMQX node can not force leave itself, request the API by the other nodes,
+	// the requester of the cluster is not used, it may fall back to the node itself
+	requester := &innerReq.Pool{
+		Requester: innerReq.Requester{
+			Scheme:    r.GetScheme(),
+			Username:  r.GetUsername(),
+			Password:  r.GetPassword(),
+			TLS:       r.GetTLSConfig(),
+			Kind:      emqxKind,
+			Namespace: instance.Namespace,
+			Instance:  instance.Name,
+		},
+	}
+	for _, p := range remainPods {
+		if p.Status.Phase == corev1.PodRunning && p.Status.PodIP != "" {
+			requester.Endpoints = append(requester.Endpoints, innerReq.Endpoint{
+				Name: p.Name,
+				Host: net.JoinHostPort(p.Status.PodIP, getManagementAPIPort(instance, r.GetScheme())),
+			})
+		}
+	}
+	if len(requester.Endpoints) == 0 {
+		a.EventRecorder.Event(instance, corev1.EventTypeWarning, "LeaveCluster", fmt.Sprintf("Node %s can not leave the cluster, no other running core pod to request", node.Node))
+		return false, false, nil
+	}
+
+	nodes, err := emqxapi.NewV5(requester).Nodes(ctx)
+	if err != nil {
+		return false, false, emperror.Wrap(err, "failed to get nodes")
+	}
+	if !isEMQXNodeInCluster(nodes, node.Node) {
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "LeaveCluster", fmt.Sprintf("Node %s left the cluster", node.Node))
+		return true, false, nil
+	}
+
+	if node.Edition == "Enterprise" && node.Session > 0 {
+		if isEvacuating(instance, node.Node) {
+			return false, false, nil
+		}
+		migrateTo := getEMQXNodeNamesByPods(instance.Status.CoreNodesStatus.Nodes, remainPods)
+		if len(migrateTo) == 0 {
+			return false, false, nil
+		}
+		if err := startEvacuationByAPI(ctx, r, instance, migrateTo, node.Node); err != nil {
+			return false, false, emperror.Wrapf(err, "failed to evacuate node %s", node.Node)
+		}
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "Evacuate", fmt.Sprintf("Evacuate node %s start", node.Node))
+		return false, false, nil
+	}
+
+	if err := emqxapi.NewV5(requester).ForceLeave(ctx, node.Node); err != nil {
+		return false, false, err
+	}
+	a.EventRecorder.Event(instance, corev1.EventTypeNormal, "LeaveCluster", fmt.Sprintf("Node %s is leaving the cluster", node.Node))
+	return false, true, nil
+}
+
+// isEMQXNodeInCluster checks whether the EMQX node is in the nodes of the cluster
+func isEMQXNodeInCluster(nodes []appsv2alpha2.EMQXNode, name string) bool {
+	for _, node := range nodes {
+		if node.Node == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *addCore) findCanBeDeletePod(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface, current, old *appsv1.StatefulSet) (*corev1.Pod, error) {
+	reason, message := getScaleDownWaiting(instance, appsv2alpha2.Ready, getEventList(ctx, a.Clientset, old))
+	if a.scaleDownWaiting.update(instance, old, reason) && reason != "" {
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, reason, fmt.Sprintf("StatefulSet %s: %s", old.Name, message))
+	}
+	if reason != "" {
+		return nil, nil
+	}
 	pod := &corev1.Pod{}
 	_ = a.Client.Get(ctx, types.NamespacedName{
 		Namespace: instance.Namespace,
 		Name:      fmt.Sprintf("%s-%d", old.Name, old.Status.Replicas-1),
 	}, pod)
 
-	for _, node := range instance.Status.CoreNodesStatus.Nodes {
-		host := strings.Split(node.Node[strings.Index(node.Node, "@")+1:], ":")[0]
-		if strings.HasPrefix(host, pod.Name) {
-			if node.Edition == "Enterprise" && node.Session != 0 {
-				return nil
-			}
-			return pod
+	node := findEMQXNodeByPod(instance.Status.CoreNodesStatus.Nodes, pod)
+	if node == nil {
+		return nil, nil
+	}
+	if node.Edition == "Enterprise" && node.Session != 0 {
+		if r == nil || current == nil || isEvacuating(instance, node.Node) {
+			return nil, nil
 		}
+
+		podList := &corev1.PodList{}
+		_ = a.Client.List(ctx, podList, client.InNamespace(current.Namespace), client.MatchingLabels(current.Spec.Selector.MatchLabels))
+		migrateTo := getEMQXNodeNamesByPods(instance.Status.CoreNodesStatus.Nodes, podList.Items)
+		if len(migrateTo) == 0 {
+			return nil, nil
+		}
+
+		if err := startEvacuationByAPI(ctx, r, instance, migrateTo, node.Node); err != nil {
+			return nil, emperror.Wrapf(err, "failed to evacuate node %s", node.Node)
+		}
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "Evacuate", fmt.Sprintf("Evacuate node %s start", node.Node))
+		return nil, nil
 	}
-	return nil
+	a.EventRecorder.Event(instance, corev1.EventTypeNormal, "ScaleDown", fmt.Sprintf("Pod %s of old statefulSet %s is selected for removal, it has %d sessions", pod.Name, old.Name, node.Session))
+	return pod, nil
 }
 
 func generateStatefulSet(instance *appsv2alpha2.EMQX) *appsv1.StatefulSet {
@@ -192,6 +423,10 @@ func generateStatefulSet(instance *appsv2alpha2.EMQX) *appsv1.StatefulSet {
 					NodeName:         instance.Spec.CoreTemplate.Spec.NodeName,
 					NodeSelector:     instance.Spec.CoreTemplate.Spec.NodeSelector,
 					InitContainers:   instance.Spec.CoreTemplate.Spec.InitContainers,
+					TopologySpreadConstraints: getTopologySpreadConstraints(instance,
+						instance.Spec.CoreTemplate.Spec.TopologySpreadConstraints,
+						instance.Spec.CoreTemplate.Labels,
+					),
 					Containers: append([]corev1.Container{
 						{
 							Name:            appsv2alpha2.DefaultContainerName,
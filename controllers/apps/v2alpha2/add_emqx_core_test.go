@@ -1,16 +1,25 @@
 package v2alpha2
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi/fake"
+	"github.com/emqx/emqx-operator/internal/handler"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlFake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var coreLabels = map[string]string{
@@ -326,3 +335,80 @@ func TestGenerateStatefulSet(t *testing.T) {
 		})
 	})
 }
+
+func TestLeaveCluster(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+	defer innerReq.ForgetCluster(emqxKind, "default", "leave-cluster")
+
+	instance := &appsv2alpha2.EMQX{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "leave-cluster"}}
+	instance.Spec.BootstrapConfig = "dashboard.listeners.http.bind = " + s.Port()
+
+	labels := appsv2alpha2.CloneAndAddLabel(coreLabels, appsv2alpha2.PodTemplateHashLabelKey, "fake")
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "emqx-core-fake", Labels: labels},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: pointer.Int32(3),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+		},
+	}
+	objs := []client.Object{sts}
+	for i := 0; i < 3; i++ {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      fmt.Sprintf("emqx-core-fake-%d", i),
+				Labels:    labels,
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "127.0.0.1"},
+		}
+		node := fmt.Sprintf("emqx@%s.emqx-headless.default.svc.cluster.local", pod.Name)
+		s.AddNode(fake.Node{Name: node, Role: "core", Version: "5.1.0", Edition: "Opensource"})
+		instance.Status.CoreNodesStatus.Nodes = append(instance.Status.CoreNodesStatus.Nodes, appsv2alpha2.EMQXNode{
+			Node: node, Role: "core", Edition: "Opensource", PodName: pod.Name, PodUID: pod.UID,
+		})
+		objs = append(objs, pod)
+	}
+
+	k8sClient := ctrlFake.NewClientBuilder().WithObjects(objs...).Build()
+	recorder := record.NewFakeRecorder(100)
+	a := &addCore{&EMQXReconciler{Handler: &handler.Handler{Client: k8sClient}, EventRecorder: recorder}}
+
+	t.Run("no requester", func(t *testing.T) {
+		left, leaving, err := a.leaveCluster(context.Background(), instance, nil, sts)
+		assert.Nil(t, err)
+		assert.False(t, left)
+		assert.False(t, leaving)
+		assert.Contains(t, <-recorder.Events, "Warning LeaveCluster Node emqx@emqx-core-fake-2.emqx-headless.default.svc.cluster.local can not leave the cluster")
+	})
+
+	t.Run("scale in", func(t *testing.T) {
+		for *sts.Spec.Replicas > 1 {
+			pod := fmt.Sprintf("emqx-core-fake-%d", *sts.Spec.Replicas-1)
+			node := fmt.Sprintf("emqx@%s.emqx-headless.default.svc.cluster.local", pod)
+
+			// The node is requested to leave, the replicas are not decreased until it is gone from the cluster
+			left, leaving, err := a.leaveCluster(context.Background(), instance, s.Requester(), sts)
+			assert.Nil(t, err)
+			assert.False(t, left)
+			assert.True(t, leaving)
+			assert.Contains(t, s.Requests(), "DELETE api/v5/cluster/"+node+"/force_leave")
+
+			left, leaving, err = a.leaveCluster(context.Background(), instance, s.Requester(), sts)
+			assert.Nil(t, err)
+			assert.True(t, left)
+			assert.False(t, leaving)
+
+			// The statefulSet controller deletes the pod after the replicas are decreased
+			sts.Spec.Replicas = pointer.Int32(*sts.Spec.Replicas - 1)
+			assert.Nil(t, k8sClient.Delete(context.Background(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: pod}}))
+		}
+
+		var nodes []string
+		for _, node := range s.Nodes() {
+			nodes = append(nodes, node.Name)
+		}
+		assert.Equal(t, []string{"emqx@emqx-core-fake-0.emqx-headless.default.svc.cluster.local"}, nodes)
+	})
+}
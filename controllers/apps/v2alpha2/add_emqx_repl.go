@@ -2,8 +2,8 @@ package v2alpha2
 
 import (
 	"context"
+	"fmt"
 	"sort"
-	"strings"
 
 	emperror "emperror.dev/errors"
 	"github.com/cisco-open/k8s-objectmatcher/patch"
@@ -13,6 +13,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,7 +24,7 @@ type addRepl struct {
 	*EMQXReconciler
 }
 
-func (a *addRepl) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _ innerReq.RequesterInterface) subResult {
+func (a *addRepl) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface) subResult {
 	if instance.Spec.ReplicantTemplate == nil {
 		return subResult{}
 	}
@@ -32,6 +33,10 @@ func (a *addRepl) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _
 	}
 
 	preRs := a.getNewReplicaSet(ctx, instance)
+	if preRs.UID == "" && preRs.Labels[appsv2alpha2.PodTemplateHashLabelKey] == instance.Status.ReplicantNodesStatus.FailedRevision {
+		// The revision has been rolled back, do not roll it out again until the pod template is changed
+		return subResult{}
+	}
 	if preRs.UID == "" {
 		_ = ctrl.SetControllerReference(instance, preRs, a.Scheme)
 		if err := a.Handler.Create(preRs); err != nil {
@@ -45,13 +50,16 @@ func (a *addRepl) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _
 			}
 			return subResult{err: emperror.Wrap(err, "failed to create replicaSet")}
 		}
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "CreateNewReplicaSet", fmt.Sprintf("Create new replicaSet %s for EMQX replicant nodes", preRs.Name))
 		instance.Status.SetCondition(metav1.Condition{
-			Type:    appsv2alpha2.ReplicantNodesProgressing,
-			Status:  metav1.ConditionTrue,
-			Reason:  "CreateNewReplicaSet",
-			Message: "Create new replicaSet",
+			Type:               appsv2alpha2.ReplicantNodesProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             "CreateNewReplicaSet",
+			Message:            "Create new replicaSet",
+			ObservedGeneration: instance.Generation,
 		})
-		instance.Status.ReplicantNodesStatus.CurrentRevision = preRs.Labels[appsv2alpha2.PodTemplateHashLabelKey]
+		instance.Status.ReplicantNodesStatus.CurrentRevision = getRevision(preRs.Spec.Template.ObjectMeta)
+		instance.Status.ReplicantNodesStatus.FailedRevision = ""
 		_ = a.Client.Status().Update(ctx, instance)
 	} else {
 		storageRs := &appsv1.ReplicaSet{}
@@ -64,18 +72,32 @@ func (a *addRepl) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _
 			logger := log.FromContext(ctx)
 			logger.V(1).Info("got different statefulSet for EMQX core nodes, will update statefulSet", "patch", string(patchResult.Patch))
 
-			_ = a.Handler.Update(preRs)
+			if err := a.Handler.Update(preRs); err != nil {
+				return subResult{err: emperror.Wrap(err, "failed to update replicaSet")}
+			}
 			instance.Status.SetCondition(metav1.Condition{
-				Type:    appsv2alpha2.ReplicantNodesProgressing,
-				Status:  metav1.ConditionTrue,
-				Reason:  "CreateNewReplicaSet",
-				Message: "Create new replicaSet",
+				Type:               appsv2alpha2.ReplicantNodesProgressing,
+				Status:             metav1.ConditionTrue,
+				Reason:             "CreateNewReplicaSet",
+				Message:            "Create new replicaSet",
+				ObservedGeneration: instance.Generation,
 			})
 			_ = a.Client.Status().Update(ctx, instance)
 		}
+		// The rolling update advances the revision of the current replicaSet in place
+		if revision := getRevision(preRs.Spec.Template.ObjectMeta); revision != instance.Status.ReplicantNodesStatus.CurrentRevision {
+			instance.Status.ReplicantNodesStatus.CurrentRevision = revision
+			if err := a.Client.Status().Update(ctx, instance); err != nil {
+				return subResult{err: emperror.Wrap(err, "failed to update status")}
+			}
+		}
 	}
 
-	if err := a.sync(ctx, instance); err != nil {
+	if err := a.pruneHistory(ctx, instance); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to prune old replicaSets")}
+	}
+
+	if err := a.sync(ctx, instance, r); err != nil {
 		return subResult{err: emperror.Wrap(err, "failed to sync replicaSet")}
 	}
 
@@ -89,6 +111,7 @@ func (a *addRepl) getNewReplicaSet(ctx context.Context, instance *appsv2alpha2.E
 	preRs.Labels = appsv2alpha2.CloneAndAddLabel(preRs.Labels, appsv2alpha2.PodTemplateHashLabelKey, podTemplateSpecHash)
 	preRs.Spec.Template.Labels = appsv2alpha2.CloneAndAddLabel(preRs.Spec.Template.Labels, appsv2alpha2.PodTemplateHashLabelKey, podTemplateSpecHash)
 	preRs.Spec.Selector = appsv2alpha2.CloneSelectorAndAddLabel(preRs.Spec.Selector, appsv2alpha2.PodTemplateHashLabelKey, podTemplateSpecHash)
+	preRs.Spec.Template.Annotations = appsv2alpha2.CloneAndAddLabel(preRs.Spec.Template.Annotations, appsv2alpha2.PodTemplateRevisionAnnotationKey, podTemplateSpecHash)
 
 	currentRs, _ := getReplicaSetList(ctx, a.Client, instance)
 	if currentRs == nil {
@@ -104,28 +127,133 @@ func (a *addRepl) getNewReplicaSet(ctx context.Context, instance *appsv2alpha2.E
 		preRs.ObjectMeta = currentRs.ObjectMeta
 		preRs.Spec.Template.ObjectMeta = currentRs.Spec.Template.ObjectMeta
 		preRs.Spec.Selector = currentRs.Spec.Selector
+		if isRollingUpdate(instance) {
+			preRs.Spec.Replicas = a.getSurgeReplicas(ctx, instance, preRs)
+		}
+		if a.isCanaryPaused(ctx, instance, podTemplateSpecHash) {
+			preRs.Spec.Replicas = getCanaryReplicas(instance)
+		}
 		return preRs
 	}
 	logger := log.FromContext(ctx)
+	if isRollingUpdate(instance) {
+		logger.V(1).Info("got different pod template for EMQX replicant nodes, will rolling update replicaSet", "patch", string(patchResult.Patch))
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "NewRevision", fmt.Sprintf("New revision %s of EMQX replicant nodes, will rolling update replicaSet %s, changed: %s", podTemplateSpecHash, currentRs.Name, summarizePatch(patchResult.Patch)))
+		// The selector of replicaSet is immutable, keep the pod template hash label of the current replicaSet
+		preRs.ObjectMeta = currentRs.ObjectMeta
+		preRs.Spec.Template.Labels = currentRs.Spec.Template.Labels
+		preRs.Spec.Selector = currentRs.Spec.Selector
+		preRs.Spec.Replicas = a.getSurgeReplicas(ctx, instance, preRs)
+		return preRs
+	}
 	logger.V(1).Info("got different pod template for EMQX replicant nodes, will create new replicaSet", "patch", string(patchResult.Patch))
+	if podTemplateSpecHash != instance.Status.ReplicantNodesStatus.FailedRevision {
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "NewRevision", fmt.Sprintf("New revision %s of EMQX replicant nodes, will create new replicaSet, changed: %s", podTemplateSpecHash, summarizePatch(patchResult.Patch)))
+	}
 
+	if a.isCanaryPaused(ctx, instance, podTemplateSpecHash) {
+		preRs.Spec.Replicas = getCanaryReplicas(instance)
+	}
 	return preRs
 }
 
-func (a *addRepl) sync(ctx context.Context, instance *appsv2alpha2.EMQX) error {
-	_, oldRsList := getReplicaSetList(ctx, a.Client, instance)
+// isCanaryPaused checks whether the update of the EMQX replicant nodes is held at the canary step,
+// the update is held until the revision is resumed by annotation as long as the old replicaSets are not scaled down,
+// so every later revision is held again.
+func (a *addRepl) isCanaryPaused(ctx context.Context, instance *appsv2alpha2.EMQX, revision string) bool {
+	if instance.Spec.UpdateStrategy.Canary == nil || isRollingUpdate(instance) {
+		return false
+	}
+	if instance.Annotations[appsv2alpha2.ResumeUpdateAnnotationKey] == revision {
+		return false
+	}
+
+	list := &appsv1.ReplicaSetList{}
+	_ = a.Client.List(ctx, list,
+		client.InNamespace(instance.Namespace),
+		client.MatchingLabels(instance.Spec.ReplicantTemplate.Labels),
+	)
+	for _, rs := range list.Items {
+		if getRevision(rs.Spec.Template.ObjectMeta) != revision && *rs.Spec.Replicas > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getCanaryReplicas returns the replicas of the new replicaSet at the canary step, percentage is rounded up.
+func getCanaryReplicas(instance *appsv2alpha2.EMQX) *int32 {
+	replicas := *instance.Spec.ReplicantTemplate.Spec.Replicas
+	canary := intstr.FromInt(1)
+	if instance.Spec.UpdateStrategy.Canary.Replicas != nil {
+		canary = *instance.Spec.UpdateStrategy.Canary.Replicas
+	}
+	c, _ := intstr.GetScaledValueFromIntOrPercent(&canary, int(replicas), true)
+	if int32(c) > replicas {
+		c = int(replicas)
+	}
+	if c < 1 {
+		c = 1
+	}
+	return pointer.Int32(int32(c))
+}
+
+// getSurgeReplicas returns the replicas of the replicaSet during the rolling update,
+// the replicaSet is scaled up by maxSurge while there are outdated pods, so the new pods
+// can be created before the outdated pods are deleted.
+func (a *addRepl) getSurgeReplicas(ctx context.Context, instance *appsv2alpha2.EMQX, rs *appsv1.ReplicaSet) *int32 {
+	replicas := *instance.Spec.ReplicantTemplate.Spec.Replicas
+	_, outdated := getOutdatedPods(ctx, a.Client, rs.Namespace, rs.Spec.Selector.MatchLabels, rs.Spec.Template.Annotations[appsv2alpha2.PodTemplateRevisionAnnotationKey])
+	_, maxSurge := getRollingUpdateBudget(instance, replicas)
+	if int32(len(outdated)) < maxSurge {
+		maxSurge = int32(len(outdated))
+	}
+	return pointer.Int32(replicas + maxSurge)
+}
+
+func (a *addRepl) sync(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface) error {
+	currentRs, oldRsList := getReplicaSetList(ctx, a.Client, instance)
+	if currentRs != nil && a.isCanaryPaused(ctx, instance, getRevision(currentRs.Spec.Template.ObjectMeta)) {
+		if !instance.Status.IsConditionTrue(appsv2alpha2.UpdatePaused) {
+			instance.Status.SetCondition(metav1.Condition{
+				Type:   appsv2alpha2.UpdatePaused,
+				Status: metav1.ConditionTrue,
+				Reason: "CanaryPaused",
+				Message: fmt.Sprintf("Update of EMQX replicant nodes is held at the canary step, annotate %s=%s to resume",
+					appsv2alpha2.ResumeUpdateAnnotationKey, getRevision(currentRs.Spec.Template.ObjectMeta)),
+				ObservedGeneration: instance.Generation,
+			})
+			if err := a.Client.Status().Update(ctx, instance); err != nil {
+				return emperror.Wrap(err, "failed to update status")
+			}
+		}
+		return nil
+	}
+	if instance.Status.IsConditionTrue(appsv2alpha2.UpdatePaused) {
+		instance.Status.RemoveCondition(appsv2alpha2.UpdatePaused)
+		if err := a.Client.Status().Update(ctx, instance); err != nil {
+			return emperror.Wrap(err, "failed to update status")
+		}
+	}
+
 	if len(oldRsList) == 0 {
+		if isRollingUpdate(instance) && currentRs != nil {
+			return a.rollingUpdate(ctx, instance, currentRs)
+		}
 		return nil
 	}
 
 	oldest := oldRsList[0].DeepCopy()
 
-	if pod := a.findCanBeDeletePod(ctx, instance, oldest); pod != nil {
+	pod, err := a.findCanBeDeletePod(ctx, instance, r, currentRs, oldest)
+	if err != nil {
+		return err
+	}
+	if pod != nil {
 		if pod.Annotations == nil {
 			pod.Annotations = make(map[string]string)
 		}
-		// https://kubernetes.io/docs/concepts/workloads/controllers/replicaset/#pod-deletion-cost
-		pod.Annotations["controller.kubernetes.io/pod-deletion-cost"] = "-99999"
+		pod.Annotations[podDeletionCostAnnotation] = "-99999"
 		if err := a.Client.Patch(ctx, pod, client.MergeFrom(pod)); err != nil {
 			return emperror.Wrap(err, "failed patch pod deletion cost")
 		}
@@ -134,48 +262,115 @@ func (a *addRepl) sync(ctx context.Context, instance *appsv2alpha2.EMQX) error {
 		if err := a.Client.Update(ctx, oldest); err != nil {
 			return emperror.Wrap(err, "failed to scale down old replicaSet")
 		}
+		if *oldest.Spec.Replicas == 0 {
+			a.EventRecorder.Event(instance, corev1.EventTypeNormal, "RetireRevision", fmt.Sprintf("Old replicaSet %s of revision %s is scaled down to 0", oldest.Name, getRevision(oldest.Spec.Template.ObjectMeta)))
+		}
 		return nil
 	}
 	return nil
 }
 
-func (a *addRepl) findCanBeDeletePod(ctx context.Context, instance *appsv2alpha2.EMQX, old *appsv1.ReplicaSet) *corev1.Pod {
-	if !canBeScaledDown(instance, appsv2alpha2.Ready, getEventList(ctx, a.Clientset, old)) {
+// pruneHistory deletes the oldest replicaSets that have been scaled down, retains at most revisionHistoryLimit old replicaSets
+func (a *addRepl) pruneHistory(ctx context.Context, instance *appsv2alpha2.EMQX) error {
+	var scaledDown []*appsv1.ReplicaSet
+	for _, obj := range getHistoryReplicaSetList(ctx, a.Client, instance) {
+		if *obj.Spec.Replicas == 0 && obj.Status.Replicas == 0 {
+			scaledDown = append(scaledDown, obj)
+		}
+	}
+
+	logger := log.FromContext(ctx)
+	for i := 0; i < len(scaledDown)-getRevisionHistoryLimit(instance); i++ {
+		logger.V(1).Info("delete old replicaSet for EMQX replicant nodes", "replicaSet", scaledDown[i].Name)
+		if err := a.Client.Delete(ctx, scaledDown[i]); err != nil && !k8sErrors.IsNotFound(err) {
+			return emperror.Wrapf(err, "failed to delete replicaSet %s", scaledDown[i].Name)
+		}
+	}
+	return nil
+}
+
+func (a *addRepl) rollingUpdate(ctx context.Context, instance *appsv2alpha2.EMQX, rs *appsv1.ReplicaSet) error {
+	pods, outdated := getOutdatedPods(ctx, a.Client, rs.Namespace, rs.Spec.Selector.MatchLabels, rs.Spec.Template.Annotations[appsv2alpha2.PodTemplateRevisionAnnotationKey])
+	if len(outdated) == 0 {
 		return nil
 	}
 
+	// Update the pods with the fewest sessions first
+	nodes := instance.Status.ReplicantNodesStatus.Nodes
+	sort.Slice(outdated, func(i, j int) bool {
+		return getPodSession(nodes, outdated[i]) < getPodSession(nodes, outdated[j])
+	})
+
+	replicas := *instance.Spec.ReplicantTemplate.Spec.Replicas
+	maxUnavailable, _ := getRollingUpdateBudget(instance, replicas)
+	logger := log.FromContext(ctx)
+	for _, pod := range selectPodsToRoll(nodes, pods, outdated, replicas, maxUnavailable) {
+		logger.V(1).Info("delete outdated pod for rolling update EMQX replicant nodes", "pod", pod.Name)
+		if err := a.Client.Delete(ctx, pod); err != nil && !k8sErrors.IsNotFound(err) {
+			return emperror.Wrapf(err, "failed to delete pod %s", pod.Name)
+		}
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "RollingUpdate", fmt.Sprintf("Delete outdated pod %s with %d sessions", pod.Name, getPodSession(nodes, pod)))
+	}
+	return nil
+}
+
+func (a *addRepl) findCanBeDeletePod(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface, current, old *appsv1.ReplicaSet) (*corev1.Pod, error) {
+	reason, message := getScaleDownWaiting(instance, appsv2alpha2.Ready, getEventList(ctx, a.Clientset, old))
+	if a.scaleDownWaiting.update(instance, old, reason) && reason != "" {
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, reason, fmt.Sprintf("ReplicaSet %s: %s", old.Name, message))
+	}
+	if reason != "" {
+		return nil, nil
+	}
+
 	type podSessionCount struct {
-		pod     *corev1.Pod
-		edition string
-		session int64
+		pod  *corev1.Pod
+		node *appsv2alpha2.EMQXNode
 	}
 	var podSessionCountList []*podSessionCount
 
 	list := &corev1.PodList{}
 	_ = a.Client.List(ctx, list, client.InNamespace(old.Namespace), client.MatchingLabels(old.Spec.Selector.MatchLabels))
 
-	for _, node := range instance.Status.ReplicantNodesStatus.Nodes {
-		for _, pod := range list.Items {
-			host := strings.Split(node.Node[strings.Index(node.Node, "@")+1:], ":")[0]
-			if pod.Status.PodIP == host {
-				podSessionCountList = append(podSessionCountList, &podSessionCount{
-					pod:     pod.DeepCopy(),
-					edition: node.Edition,
-					session: node.Session,
-				})
-			}
+	for _, pod := range list.Items {
+		if node := findEMQXNodeByPod(instance.Status.ReplicantNodesStatus.Nodes, &pod); node != nil {
+			podSessionCountList = append(podSessionCountList, &podSessionCount{
+				pod:  pod.DeepCopy(),
+				node: node,
+			})
 		}
 	}
+	if len(podSessionCountList) == 0 {
+		return nil, nil
+	}
 
 	sort.Slice(podSessionCountList, func(i, j int) bool {
-		return podSessionCountList[i].session < podSessionCountList[j].session
+		return podSessionCountList[i].node.Session < podSessionCountList[j].node.Session
 	})
 
-	if podSessionCountList[0].edition == "Enterprise" && podSessionCountList[0].session > 0 {
-		return nil
+	node := podSessionCountList[0].node
+	if node.Edition == "Enterprise" && node.Session > 0 {
+		if r == nil || current == nil || isEvacuating(instance, node.Node) {
+			return nil, nil
+		}
+
+		podList := &corev1.PodList{}
+		_ = a.Client.List(ctx, podList, client.InNamespace(current.Namespace), client.MatchingLabels(current.Spec.Selector.MatchLabels))
+		migrateTo := getEMQXNodeNamesByPods(instance.Status.ReplicantNodesStatus.Nodes, podList.Items)
+		if len(migrateTo) == 0 {
+			return nil, nil
+		}
+
+		if err := startEvacuationByAPI(ctx, r, instance, migrateTo, node.Node); err != nil {
+			return nil, emperror.Wrapf(err, "failed to evacuate node %s", node.Node)
+		}
+		a.EventRecorder.Event(instance, corev1.EventTypeNormal, "Evacuate", fmt.Sprintf("Evacuate node %s start", node.Node))
+		return nil, nil
 	}
 
-	return podSessionCountList[0].pod
+	pod := podSessionCountList[0].pod
+	a.EventRecorder.Event(instance, corev1.EventTypeNormal, "ScaleDown", fmt.Sprintf("Pod %s of old replicaSet %s is selected for removal, it has %d sessions", pod.Name, old.Name, node.Session))
+	return pod, nil
 }
 
 func generateReplicaSet(instance *appsv2alpha2.EMQX) *appsv1.ReplicaSet {
@@ -212,6 +407,10 @@ func generateReplicaSet(instance *appsv2alpha2.EMQX) *appsv1.ReplicaSet {
 					NodeName:         instance.Spec.ReplicantTemplate.Spec.NodeName,
 					NodeSelector:     instance.Spec.ReplicantTemplate.Spec.NodeSelector,
 					InitContainers:   instance.Spec.ReplicantTemplate.Spec.InitContainers,
+					TopologySpreadConstraints: getTopologySpreadConstraints(instance,
+						instance.Spec.ReplicantTemplate.Spec.TopologySpreadConstraints,
+						instance.Spec.ReplicantTemplate.Labels,
+					),
 					Containers: append([]corev1.Container{
 						{
 							Name:            appsv2alpha2.DefaultContainerName,
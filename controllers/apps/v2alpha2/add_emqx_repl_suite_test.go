@@ -256,8 +256,11 @@ var _ = Describe("Check add repl controller", Ordered, Label("repl"), func() {
 			instance.Status.ReplicantNodesStatus.Nodes = []appsv2alpha2.EMQXNode{
 				{
 					Node:    fmt.Sprintf("emqx@%s", fakeOldPod.Status.PodIP),
+					Role:    "replicant",
 					Edition: "Enterprise",
 					Session: 0,
+					PodName: fakeOldPod.Name,
+					PodUID:  fakeOldPod.UID,
 				},
 			}
 
@@ -0,0 +1,39 @@
+package v2alpha2
+
+import (
+	"testing"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func TestGetCanaryReplicas(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{
+		Spec: appsv2alpha2.EMQXSpec{
+			UpdateStrategy: appsv2alpha2.UpdateStrategy{
+				Canary: &appsv2alpha2.CanaryStrategy{},
+			},
+			ReplicantTemplate: &appsv2alpha2.EMQXReplicantTemplate{
+				Spec: appsv2alpha2.EMQXReplicantTemplateSpec{
+					Replicas: pointer.Int32(5),
+				},
+			},
+		},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		assert.Equal(t, pointer.Int32(1), getCanaryReplicas(instance))
+	})
+
+	t.Run("percentage is rounded up", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.Canary.Replicas = &intstr.IntOrString{Type: intstr.String, StrVal: "30%"}
+		assert.Equal(t, pointer.Int32(2), getCanaryReplicas(instance))
+	})
+
+	t.Run("no more than the desired replicas", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.Canary.Replicas = &intstr.IntOrString{Type: intstr.Int, IntVal: 10}
+		assert.Equal(t, pointer.Int32(5), getCanaryReplicas(instance))
+	})
+}
@@ -2,8 +2,6 @@ package v2alpha2
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"net"
 	"regexp"
 	"strconv"
@@ -11,6 +9,7 @@ import (
 
 	emperror "emperror.dev/errors"
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -38,7 +37,7 @@ func (a *addListener) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX
 	}
 
 	resources := []client.Object{}
-	svc := generateListenerService(instance, a.getServicePorts(instance, r))
+	svc := generateListenerService(instance, a.getServicePorts(ctx, instance, r))
 	if svc == nil {
 		return subResult{}
 	}
@@ -56,23 +55,23 @@ func (a *addListener) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX
 }
 
 func (a *addListener) getPodList(ctx context.Context, instance *appsv2alpha2.EMQX) []corev1.Pod {
-	labels := appsv2alpha2.AddLabel(
-		instance.Spec.CoreTemplate.Labels,
-		appsv2alpha2.PodTemplateHashLabelKey,
-		instance.Status.CoreNodesStatus.CurrentRevision,
-	)
+	// The pod-template-hash label of the current workload is kept by the rolling update, select its pods by the selector
+	var selector *metav1.LabelSelector
 	if isExistReplicant(instance) {
-		labels = appsv2alpha2.AddLabel(
-			instance.Spec.ReplicantTemplate.Labels,
-			appsv2alpha2.PodTemplateHashLabelKey,
-			instance.Status.ReplicantNodesStatus.CurrentRevision,
-		)
+		if currentRs, _ := getReplicaSetList(ctx, a.Client, instance); currentRs != nil {
+			selector = currentRs.Spec.Selector
+		}
+	} else if currentSts, _ := getStateFulSetList(ctx, a.Client, instance); currentSts != nil {
+		selector = currentSts.Spec.Selector
+	}
+	if selector == nil {
+		return []corev1.Pod{}
 	}
 
 	podList := &corev1.PodList{}
 	_ = a.Client.List(ctx, podList,
 		client.InNamespace(instance.Namespace),
-		client.MatchingLabels(labels),
+		client.MatchingLabels(selector.MatchLabels),
 	)
 
 	list := []corev1.Pod{}
@@ -84,8 +83,8 @@ func (a *addListener) getPodList(ctx context.Context, instance *appsv2alpha2.EMQ
 	return list
 }
 
-func (a *addListener) getServicePorts(instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface) []corev1.ServicePort {
-	listenerPorts, err := getAllListenersByAPI(r)
+func (a *addListener) getServicePorts(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface) []corev1.ServicePort {
+	listenerPorts, err := getAllListenersByAPI(ctx, r)
 	if err != nil {
 		a.EventRecorder.Event(instance, corev1.EventTypeWarning, "FailedToGetListenerPorts", err.Error())
 	}
@@ -160,72 +159,33 @@ func generateEndpoints(svc *corev1.Service, pods []corev1.Pod) *corev1.Endpoints
 	}
 }
 
-// Access EMQX API to get all listeners
-type emqxGateway struct {
-	Name   string `json:"name"`
-	Status string `json:"status"`
-}
-
-type emqxListener struct {
-	Enable bool   `json:"enable"`
-	ID     string `json:"id"`
-	Bind   string `json:"bind"`
-	Type   string `json:"type"`
-}
-
-func getAllListenersByAPI(r innerReq.RequesterInterface) ([]corev1.ServicePort, error) {
-	ports, err := getListenerPortsByAPI(r, "api/v5/listeners")
+func getAllListenersByAPI(ctx context.Context, r innerReq.RequesterInterface) ([]corev1.ServicePort, error) {
+	c := emqxapi.NewV5(r)
+	listeners, err := c.Listeners(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	gateways, err := getGatewaysByAPI(r)
+	gateways, err := c.Gateways(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, gateway := range gateways {
 		if strings.ToLower(gateway.Status) == "running" {
-			apiPath := fmt.Sprintf("api/v5/gateways/%s/listeners", gateway.Name)
-			gatewayPorts, err := getListenerPortsByAPI(r, apiPath)
+			gatewayListeners, err := c.GatewayListeners(ctx, gateway.Name)
 			if err != nil {
 				return nil, err
 			}
-			ports = append(ports, gatewayPorts...)
+			listeners = append(listeners, gatewayListeners...)
 		}
 	}
 
-	return ports, nil
+	return getListenerPorts(listeners), nil
 }
 
-func getGatewaysByAPI(r innerReq.RequesterInterface) ([]emqxGateway, error) {
-	resp, body, err := r.Request("GET", "api/v5/gateways", nil)
-	if err != nil {
-		return nil, emperror.Wrap(err, "failed to get API api/v5/gateways")
-	}
-	if resp.StatusCode != 200 {
-		return nil, emperror.Errorf("failed to get API %s, status : %s, body: %s", "api/v5/gateways", resp.Status, body)
-	}
-	gateway := []emqxGateway{}
-	if err := json.Unmarshal(body, &gateway); err != nil {
-		return nil, emperror.Wrap(err, "failed to parse gateways")
-	}
-	return gateway, nil
-}
-
-func getListenerPortsByAPI(r innerReq.RequesterInterface, apiPath string) ([]corev1.ServicePort, error) {
-	resp, body, err := r.Request("GET", apiPath, nil)
-	if err != nil {
-		return nil, emperror.Wrapf(err, "failed to get API %s", apiPath)
-	}
-	if resp.StatusCode != 200 {
-		return nil, emperror.Errorf("failed to get API %s, status : %s, body: %s", apiPath, resp.Status, body)
-	}
+func getListenerPorts(listeners []emqxapi.Listener) []corev1.ServicePort {
 	ports := []corev1.ServicePort{}
-	listeners := []emqxListener{}
-	if err := json.Unmarshal(body, &listeners); err != nil {
-		return nil, emperror.Wrap(err, "failed to parse listeners")
-	}
 	for _, listener := range listeners {
 		if !listener.Enable {
 			continue
@@ -249,5 +209,5 @@ func getListenerPortsByAPI(r innerReq.RequesterInterface, apiPath string) ([]cor
 			TargetPort: intstr.FromInt(intPort),
 		})
 	}
-	return ports, nil
+	return ports
 }
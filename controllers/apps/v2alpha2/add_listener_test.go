@@ -0,0 +1,31 @@
+package v2alpha2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/emqx/emqx-operator/internal/emqxapi/fake"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestGetAllListenersByAPI(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+	s.AddNode(fake.Node{Name: "emqx@10.0.0.1"})
+	s.AddListener(
+		emqxapi.Listener{ID: "tcp:default", Type: "tcp", Bind: "0.0.0.0:1883", Enable: true},
+		emqxapi.Listener{ID: "ssl:default", Type: "ssl", Bind: "0.0.0.0:8883", Enable: false},
+	)
+	s.AddGateway("lwm2m", emqxapi.Listener{ID: "lwm2m:udp:default", Type: "udp", Bind: "0.0.0.0:5783", Enable: true})
+
+	ports, err := getAllListenersByAPI(context.Background(), s.Requester())
+	assert.Nil(t, err)
+	assert.Equal(t, []corev1.ServicePort{
+		{Name: "tcp-default", Protocol: corev1.ProtocolTCP, Port: 1883, TargetPort: intstr.FromInt(1883)},
+		{Name: "lwm2m-udp-default", Protocol: corev1.ProtocolUDP, Port: 5783, TargetPort: intstr.FromInt(5783)},
+	}, ports)
+	assert.Contains(t, s.Requests(), "GET api/v5/gateways/lwm2m/listeners")
+}
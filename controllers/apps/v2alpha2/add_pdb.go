@@ -0,0 +1,128 @@
+package v2alpha2
+
+import (
+	"context"
+
+	emperror "emperror.dev/errors"
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	policyv1 "k8s.io/api/policy/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type addPdb struct {
+	*EMQXReconciler
+}
+
+func (a *addPdb) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _ innerReq.RequesterInterface) subResult {
+	pdbList := []client.Object{}
+	if currentSts, _ := getStateFulSetList(ctx, a.Client, instance); currentSts != nil {
+		pdbList = append(pdbList, generatePodDisruptionBudget(
+			instance,
+			instance.Spec.CoreTemplate.ObjectMeta,
+			currentSts.Labels[appsv2alpha2.PodTemplateHashLabelKey],
+			getCorePodDisruptionBudgetSpec(instance),
+		))
+	}
+	if instance.Spec.ReplicantTemplate != nil && instance.Status.ReplicantNodesStatus != nil {
+		if currentRs, _ := getReplicaSetList(ctx, a.Client, instance); currentRs != nil {
+			pdbList = append(pdbList, generatePodDisruptionBudget(
+				instance,
+				instance.Spec.ReplicantTemplate.ObjectMeta,
+				currentRs.Labels[appsv2alpha2.PodTemplateHashLabelKey],
+				getReplicantPodDisruptionBudgetSpec(instance),
+			))
+		}
+	}
+
+	if err := a.CreateOrUpdateList(instance, a.Scheme, pdbList); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to create or update PDBs")}
+	}
+	if instance.Spec.ReplicantTemplate == nil {
+		if err := a.deleteReplicantPodDisruptionBudget(ctx, instance); err != nil {
+			return subResult{err: err}
+		}
+	}
+	return subResult{}
+}
+
+// deleteReplicantPodDisruptionBudget deletes the PDB of EMQX replicant nodes after the replicant template is removed,
+// the name of the PDB is unknown without the template, so it is found by the labels.
+func (a *addPdb) deleteReplicantPodDisruptionBudget(ctx context.Context, instance *appsv2alpha2.EMQX) error {
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	if err := a.Client.List(ctx, pdbList,
+		client.InNamespace(instance.Namespace),
+		client.MatchingLabels{
+			appsv2alpha2.InstanceNameLabelKey: instance.Name,
+			appsv2alpha2.DBRoleLabelKey:       "replicant",
+		},
+	); err != nil {
+		return emperror.Wrap(err, "failed to list PDBs")
+	}
+	for i := range pdbList.Items {
+		pdb := &pdbList.Items[i]
+		if !metav1.IsControlledBy(pdb, instance) {
+			continue
+		}
+		if err := a.Client.Delete(ctx, pdb); err != nil && !k8sErrors.IsNotFound(err) {
+			return emperror.Wrapf(err, "failed to delete PDB %s", pdb.Name)
+		}
+	}
+	return nil
+}
+
+// getCorePodDisruptionBudgetSpec returns the PDB of EMQX core nodes, by default at most a minority of
+// core nodes can be disrupted at the same time, and at least one.
+func getCorePodDisruptionBudgetSpec(instance *appsv2alpha2.EMQX) *appsv2alpha2.PodDisruptionBudgetSpec {
+	if pdb := instance.Spec.CoreTemplate.Spec.PodDisruptionBudget; pdb != nil {
+		return pdb
+	}
+	maxUnavailable := (*instance.Spec.CoreTemplate.Spec.Replicas - 1) / 2
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+	return &appsv2alpha2.PodDisruptionBudgetSpec{
+		MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: maxUnavailable},
+	}
+}
+
+// getReplicantPodDisruptionBudgetSpec returns the PDB of EMQX replicant nodes, by default at most 25% of
+// replicant nodes can be disrupted at the same time, and at least one.
+func getReplicantPodDisruptionBudgetSpec(instance *appsv2alpha2.EMQX) *appsv2alpha2.PodDisruptionBudgetSpec {
+	if pdb := instance.Spec.ReplicantTemplate.Spec.PodDisruptionBudget; pdb != nil {
+		return pdb
+	}
+	maxUnavailable := *instance.Spec.ReplicantTemplate.Spec.Replicas / 4
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+	return &appsv2alpha2.PodDisruptionBudgetSpec{
+		MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: maxUnavailable},
+	}
+}
+
+// generatePodDisruptionBudget generates the PDB selecting the pods of the current workload by its pod-template-hash label,
+// the pods of the old revisions are going to be deleted by the blue-green update anyway.
+func generatePodDisruptionBudget(instance *appsv2alpha2.EMQX, template metav1.ObjectMeta, podTemplateHash string, spec *appsv2alpha2.PodDisruptionBudgetSpec) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1",
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: instance.Namespace,
+			Name:      template.Name,
+			Labels:    template.Labels,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: appsv2alpha2.CloneAndAddLabel(template.Labels, appsv2alpha2.PodTemplateHashLabelKey, podTemplateHash),
+			},
+			MinAvailable:   spec.MinAvailable,
+			MaxUnavailable: spec.MaxUnavailable,
+		},
+	}
+}
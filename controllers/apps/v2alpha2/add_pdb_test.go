@@ -0,0 +1,110 @@
+package v2alpha2
+
+import (
+	"context"
+	"testing"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/handler"
+	"github.com/stretchr/testify/assert"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetPodDisruptionBudgetSpec(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{}
+	instance.Spec.ReplicantTemplate = &appsv2alpha2.EMQXReplicantTemplate{}
+
+	t.Run("derived from replicas", func(t *testing.T) {
+		for replicas, maxUnavailable := range map[int32]int32{2: 1, 3: 1, 4: 1, 5: 2} {
+			instance.Spec.CoreTemplate.Spec.Replicas = pointer.Int32(replicas)
+			assert.Equal(t, &intstr.IntOrString{Type: intstr.Int, IntVal: maxUnavailable}, getCorePodDisruptionBudgetSpec(instance).MaxUnavailable)
+		}
+		for replicas, maxUnavailable := range map[int32]int32{0: 1, 3: 1, 8: 2, 10: 2} {
+			instance.Spec.ReplicantTemplate.Spec.Replicas = pointer.Int32(replicas)
+			assert.Equal(t, &intstr.IntOrString{Type: intstr.Int, IntVal: maxUnavailable}, getReplicantPodDisruptionBudgetSpec(instance).MaxUnavailable)
+		}
+	})
+
+	t.Run("override", func(t *testing.T) {
+		pdb := &appsv2alpha2.PodDisruptionBudgetSpec{
+			MinAvailable: &intstr.IntOrString{Type: intstr.String, StrVal: "50%"},
+		}
+		instance.Spec.CoreTemplate.Spec.PodDisruptionBudget = pdb
+		instance.Spec.ReplicantTemplate.Spec.PodDisruptionBudget = pdb
+		assert.Equal(t, pdb, getCorePodDisruptionBudgetSpec(instance))
+		assert.Equal(t, pdb, getReplicantPodDisruptionBudgetSpec(instance))
+	})
+}
+
+func TestGeneratePodDisruptionBudget(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{
+		ObjectMeta: metav1.ObjectMeta{Name: "emqx", Namespace: "emqx"},
+	}
+	template := metav1.ObjectMeta{
+		Name:   "emqx-core",
+		Labels: map[string]string{appsv2alpha2.DBRoleLabelKey: "core"},
+	}
+	spec := &appsv2alpha2.PodDisruptionBudgetSpec{
+		MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+	}
+
+	got := generatePodDisruptionBudget(instance, template, "fake", spec)
+	assert.Equal(t, "emqx", got.Namespace)
+	assert.Equal(t, "emqx-core", got.Name)
+	assert.Equal(t, map[string]string{
+		appsv2alpha2.DBRoleLabelKey:          "core",
+		appsv2alpha2.PodTemplateHashLabelKey: "fake",
+	}, got.Spec.Selector.MatchLabels)
+	assert.Equal(t, spec.MaxUnavailable, got.Spec.MaxUnavailable)
+	assert.Nil(t, got.Spec.MinAvailable)
+	// template labels should not be changed
+	assert.Equal(t, map[string]string{appsv2alpha2.DBRoleLabelKey: "core"}, template.Labels)
+}
+
+func TestDeleteReplicantPodDisruptionBudget(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = appsv2alpha2.AddToScheme(scheme)
+
+	instance := &appsv2alpha2.EMQX{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv2alpha2.GroupVersion.String(), Kind: "EMQX"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "emqx", UID: "fake"},
+	}
+	newPdb := func(name, role string, owner *appsv2alpha2.EMQX) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels: map[string]string{
+					appsv2alpha2.InstanceNameLabelKey: "emqx",
+					appsv2alpha2.DBRoleLabelKey:       role,
+				},
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv2alpha2.GroupVersion.WithKind("EMQX"))},
+			},
+		}
+	}
+	other := instance.DeepCopy()
+	other.UID = "other"
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newPdb("emqx-core", "core", instance),
+		newPdb("emqx-replicant", "replicant", instance),
+		newPdb("emqx-replicant-other", "replicant", other),
+	).Build()
+	a := &addPdb{&EMQXReconciler{Handler: &handler.Handler{Client: k8sClient}}}
+
+	assert.Nil(t, a.deleteReplicantPodDisruptionBudget(context.Background(), instance))
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	assert.Nil(t, k8sClient.List(context.Background(), pdbList, client.InNamespace("default")))
+	var names []string
+	for _, pdb := range pdbList.Items {
+		names = append(names, pdb.Name)
+	}
+	assert.ElementsMatch(t, []string{"emqx-core", "emqx-replicant-other"}, names)
+}
@@ -0,0 +1,146 @@
+package v2alpha2
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	emperror "emperror.dev/errors"
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type checkProgress struct {
+	*EMQXReconciler
+}
+
+func (c *checkProgress) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, _ innerReq.RequesterInterface) subResult {
+	condition := instance.Status.GetLastTrueCondition()
+	if !isProgressDeadlineExceeded(instance, condition) {
+		if !hasFailedRevision(instance) {
+			instance.Status.RemoveCondition(appsv2alpha2.Progressing)
+		}
+		return subResult{}
+	}
+
+	var message string
+	switch condition.Type {
+	case appsv2alpha2.CoreNodesProgressing:
+		message = fmt.Sprintf("EMQX core nodes revision %s has timed out progressing", instance.Status.CoreNodesStatus.CurrentRevision)
+	case appsv2alpha2.ReplicantNodesProgressing:
+		message = fmt.Sprintf("EMQX replicant nodes revision %s has timed out progressing", instance.Status.ReplicantNodesStatus.CurrentRevision)
+	}
+
+	if instance.Spec.UpdateStrategy.AutoRollback && !isRollingUpdate(instance) {
+		var revision string
+		var err error
+		switch condition.Type {
+		case appsv2alpha2.CoreNodesProgressing:
+			revision, err = c.rollbackCore(ctx, instance)
+		case appsv2alpha2.ReplicantNodesProgressing:
+			revision, err = c.rollbackRepl(ctx, instance)
+		}
+		if err != nil {
+			return subResult{err: emperror.Wrap(err, "failed to roll back")}
+		}
+		if revision != "" {
+			message = fmt.Sprintf("%s, rolled back to revision %s", message, revision)
+		}
+	}
+
+	_, progressing := instance.Status.GetCondition(appsv2alpha2.Progressing)
+	if progressing != nil && progressing.Reason == appsv2alpha2.ProgressDeadlineExceededReason && progressing.Message == message {
+		return subResult{}
+	}
+
+	instance.Status.SetCondition(metav1.Condition{
+		Type:               appsv2alpha2.Progressing,
+		Status:             metav1.ConditionFalse,
+		Reason:             appsv2alpha2.ProgressDeadlineExceededReason,
+		Message:            message,
+		ObservedGeneration: instance.Generation,
+	})
+	c.EventRecorder.Event(instance, corev1.EventTypeWarning, appsv2alpha2.ProgressDeadlineExceededReason, message)
+	if err := c.Client.Status().Update(ctx, instance); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to update status")}
+	}
+	return subResult{}
+}
+
+// rollbackCore deletes the statefulSet of the failed revision and uses the last ready statefulSet as the current one,
+// returns the revision rolled back to, or empty string if there is no ready statefulSet to roll back to.
+func (c *checkProgress) rollbackCore(ctx context.Context, instance *appsv2alpha2.EMQX) (string, error) {
+	currentSts, oldStsList := getStateFulSetList(ctx, c.Client, instance)
+	if len(oldStsList) == 0 {
+		return "", nil
+	}
+
+	if currentSts != nil {
+		if err := c.Client.Delete(ctx, currentSts); err != nil && !k8sErrors.IsNotFound(err) {
+			return "", emperror.Wrapf(err, "failed to delete statefulSet %s", currentSts.Name)
+		}
+	}
+
+	revision := getRevision(oldStsList[len(oldStsList)-1].Spec.Template.ObjectMeta)
+	instance.Status.CoreNodesStatus.FailedRevision = instance.Status.CoreNodesStatus.CurrentRevision
+	instance.Status.CoreNodesStatus.CurrentRevision = revision
+	instance.Status.SetCondition(metav1.Condition{
+		Type:               appsv2alpha2.CoreNodesProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             "RollbackStatefulSet",
+		Message:            "Roll back to statefulSet " + oldStsList[len(oldStsList)-1].Name,
+		ObservedGeneration: instance.Generation,
+	})
+	return revision, nil
+}
+
+// rollbackRepl deletes the replicaSet of the failed revision and uses the last ready replicaSet as the current one,
+// returns the revision rolled back to, or empty string if there is no ready replicaSet to roll back to.
+func (c *checkProgress) rollbackRepl(ctx context.Context, instance *appsv2alpha2.EMQX) (string, error) {
+	currentRs, oldRsList := getReplicaSetList(ctx, c.Client, instance)
+	if len(oldRsList) == 0 {
+		return "", nil
+	}
+
+	if currentRs != nil {
+		if err := c.Client.Delete(ctx, currentRs); err != nil && !k8sErrors.IsNotFound(err) {
+			return "", emperror.Wrapf(err, "failed to delete replicaSet %s", currentRs.Name)
+		}
+	}
+
+	revision := getRevision(oldRsList[len(oldRsList)-1].Spec.Template.ObjectMeta)
+	instance.Status.ReplicantNodesStatus.FailedRevision = instance.Status.ReplicantNodesStatus.CurrentRevision
+	instance.Status.ReplicantNodesStatus.CurrentRevision = revision
+	instance.Status.SetCondition(metav1.Condition{
+		Type:               appsv2alpha2.ReplicantNodesProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             "RollbackReplicaSet",
+		Message:            "Roll back to replicaSet " + oldRsList[len(oldRsList)-1].Name,
+		ObservedGeneration: instance.Generation,
+	})
+	return revision, nil
+}
+
+func isProgressDeadlineExceeded(instance *appsv2alpha2.EMQX, condition *metav1.Condition) bool {
+	deadline := instance.Spec.UpdateStrategy.ProgressDeadlineSeconds
+	if deadline == nil || condition == nil {
+		return false
+	}
+	if condition.Type != appsv2alpha2.CoreNodesProgressing && condition.Type != appsv2alpha2.ReplicantNodesProgressing {
+		return false
+	}
+	if condition.Type == appsv2alpha2.ReplicantNodesProgressing && instance.Status.ReplicantNodesStatus == nil {
+		return false
+	}
+	return time.Since(condition.LastTransitionTime.Time) > time.Duration(*deadline)*time.Second
+}
+
+func hasFailedRevision(instance *appsv2alpha2.EMQX) bool {
+	if instance.Status.CoreNodesStatus.FailedRevision != "" {
+		return true
+	}
+	return instance.Status.ReplicantNodesStatus != nil && instance.Status.ReplicantNodesStatus.FailedRevision != ""
+}
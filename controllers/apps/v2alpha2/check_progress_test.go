@@ -0,0 +1,68 @@
+package v2alpha2
+
+import (
+	"testing"
+	"time"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestIsProgressDeadlineExceeded(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{}
+	condition := &metav1.Condition{
+		Type:               appsv2alpha2.CoreNodesProgressing,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+	}
+
+	t.Run("no deadline", func(t *testing.T) {
+		assert.False(t, isProgressDeadlineExceeded(instance, condition))
+	})
+
+	t.Run("no condition", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.ProgressDeadlineSeconds = pointer.Int32(30)
+		assert.False(t, isProgressDeadlineExceeded(instance, nil))
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.ProgressDeadlineSeconds = pointer.Int32(30)
+		assert.True(t, isProgressDeadlineExceeded(instance, condition))
+	})
+
+	t.Run("deadline not exceeded", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.ProgressDeadlineSeconds = pointer.Int32(600)
+		assert.False(t, isProgressDeadlineExceeded(instance, condition))
+	})
+
+	t.Run("not progressing", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.ProgressDeadlineSeconds = pointer.Int32(30)
+		ready := condition.DeepCopy()
+		ready.Type = appsv2alpha2.Ready
+		assert.False(t, isProgressDeadlineExceeded(instance, ready))
+	})
+
+	t.Run("replicant nodes status is nil", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.ProgressDeadlineSeconds = pointer.Int32(30)
+		replicant := condition.DeepCopy()
+		replicant.Type = appsv2alpha2.ReplicantNodesProgressing
+		assert.False(t, isProgressDeadlineExceeded(instance, replicant))
+
+		instance.Status.ReplicantNodesStatus = &appsv2alpha2.EMQXNodesStatus{}
+		assert.True(t, isProgressDeadlineExceeded(instance, replicant))
+	})
+}
+
+func TestHasFailedRevision(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{}
+	assert.False(t, hasFailedRevision(instance))
+
+	instance.Status.ReplicantNodesStatus = &appsv2alpha2.EMQXNodesStatus{FailedRevision: "fake"}
+	assert.True(t, hasFailedRevision(instance))
+
+	instance.Status.ReplicantNodesStatus = nil
+	instance.Status.CoreNodesStatus.FailedRevision = "fake"
+	assert.True(t, hasFailedRevision(instance))
+}
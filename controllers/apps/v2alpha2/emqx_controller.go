@@ -19,24 +19,33 @@ package v2alpha2
 import (
 	"context"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	emperror "emperror.dev/errors"
 	innerErr "github.com/emqx/emqx-operator/internal/errors"
+	"github.com/emqx/emqx-operator/internal/metrics"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrlHandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
 	"github.com/emqx/emqx-operator/internal/handler"
@@ -60,15 +69,29 @@ type EMQXReconciler struct {
 	Config        *rest.Config
 	Scheme        *runtime.Scheme
 	EventRecorder record.EventRecorder
+	// The interval to reconcile the EMQX custom resource periodically, the changes of the owned resources and pods
+	// are watched, the periodic reconciliation is needed for the EMQX cluster status from the management API.
+	ResyncInterval time.Duration
+
+	statsCollector   *statsCollector
+	scaleDownWaiting scaleDownWaiting
 }
 
+// DefaultResyncInterval is the default interval to reconcile the EMQX custom resource periodically
+const DefaultResyncInterval = 20 * time.Second
+
+// emqxKind is the kind of the EMQX custom resource
+const emqxKind = "EMQX"
+
 func NewEMQXReconciler(mgr manager.Manager) *EMQXReconciler {
 	return &EMQXReconciler{
-		Handler:       handler.NewHandler(mgr),
-		Clientset:     kubernetes.NewForConfigOrDie(mgr.GetConfig()),
-		Config:        mgr.GetConfig(),
-		Scheme:        mgr.GetScheme(),
-		EventRecorder: mgr.GetEventRecorderFor("emqx-controller"),
+		Handler:        handler.NewHandler(mgr),
+		Clientset:      kubernetes.NewForConfigOrDie(mgr.GetConfig()),
+		Config:         mgr.GetConfig(),
+		Scheme:         mgr.GetScheme(),
+		EventRecorder:  mgr.GetEventRecorderFor("emqx-controller"),
+		ResyncInterval: DefaultResyncInterval,
+		statsCollector: newStatsCollector(),
 	}
 }
 
@@ -91,6 +114,10 @@ func (r *EMQXReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 	instance := &appsv2alpha2.EMQX{}
 	if err := r.Client.Get(ctx, req.NamespacedName, instance); err != nil {
 		if k8sErrors.IsNotFound(err) {
+			metrics.DeleteInstance(emqxKind, req.Namespace, req.Name)
+			innerReq.ForgetCluster(emqxKind, req.Namespace, req.Name)
+			r.statsCollector.forget(req.NamespacedName)
+			r.scaleDownWaiting.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
@@ -100,27 +127,42 @@ func (r *EMQXReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, nil
 	}
 
+	if instance.Spec.Paused {
+		return r.paused(ctx, instance)
+	}
+	if _, condition := instance.Status.GetCondition(appsv2alpha2.ReconcilePaused); condition != nil {
+		instance.Status.RemoveCondition(appsv2alpha2.ReconcilePaused)
+		if err := r.Client.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, emperror.Wrap(err, "failed to update status")
+		}
+	}
+
 	requester, err := newRequester(r.Client, instance)
 	if err != nil {
 		if k8sErrors.IsNotFound(emperror.Cause(err)) {
 			_ = (&addBootstrap{r}).reconcile(ctx, instance, nil)
 			return ctrl.Result{RequeueAfter: time.Second}, nil
 		}
-		return ctrl.Result{}, emperror.Wrap(err, "failed to get bootstrap user")
+		return ctrl.Result{}, emperror.Wrap(err, "failed to create requester")
 	}
 
 	for _, subReconciler := range []subReconciler{
 		&addBootstrap{r},
 		&updateStatus{r},
 		&updatePodConditions{r},
+		&checkProgress{r},
 		&addSvc{r},
 		&addCore{r},
 		&addRepl{r},
+		&addPdb{r},
 		&addListener{r},
+		&rotateAPIKey{r},
 		&updateStatus{r},
 		&updatePodConditions{r},
 	} {
+		start := time.Now()
 		subResult := subReconciler.reconcile(ctx, instance, requester)
+		metrics.ObserveSubReconciler(emqxKind, instance.Namespace, instance.Name, subReconciler, time.Since(start))
 		if !subResult.result.IsZero() {
 			return subResult.result, nil
 		}
@@ -131,19 +173,53 @@ func (r *EMQXReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 			return ctrl.Result{}, subResult.err
 		}
 	}
-	return ctrl.Result{RequeueAfter: time.Duration(20) * time.Second}, nil
+	return ctrl.Result{RequeueAfter: r.ResyncInterval}, nil
+}
+
+// paused only updates the status of the EMQX custom resource, the resources of the EMQX cluster are left untouched
+func (r *EMQXReconciler) paused(ctx context.Context, instance *appsv2alpha2.EMQX) (ctrl.Result, error) {
+	if !instance.Status.IsConditionTrue(appsv2alpha2.ReconcilePaused) {
+		instance.Status.SetCondition(metav1.Condition{
+			Type:               appsv2alpha2.ReconcilePaused,
+			Status:             metav1.ConditionTrue,
+			Reason:             appsv2alpha2.ReconcilePaused,
+			Message:            "Reconciliation is paused",
+			ObservedGeneration: instance.Generation,
+		})
+	}
+
+	requester, _ := newRequester(r.Client, instance)
+	if subResult := (&updateStatus{r}).reconcile(ctx, instance, requester); subResult.err != nil {
+		return ctrl.Result{}, subResult.err
+	}
+	return ctrl.Result{RequeueAfter: r.ResyncInterval}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *EMQXReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&appsv2alpha2.EMQX{}).
-		WithEventFilter(predicate.Funcs{
-			UpdateFunc: func(e event.UpdateEvent) bool {
-				// Ignore updates to CR status in which case metadata.Generation does not change
-				return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration()
-			},
-		}).
+		For(&appsv2alpha2.EMQX{}, builder.WithPredicates(predicate.Or(
+			// Ignore updates to CR status in which case metadata.Generation does not change
+			predicate.GenerationChangedPredicate{},
+			// The annotations like apps.emqx.io/resume-update are not a part of the spec
+			predicate.AnnotationChangedPredicate{},
+		))).
+		Owns(&appsv1.StatefulSet{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Owns(&appsv1.ReplicaSet{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Owns(&corev1.Service{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Owns(&corev1.Secret{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Owns(&corev1.ConfigMap{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Owns(&policyv1.PodDisruptionBudget{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Watches(
+			&source.Kind{Type: &corev1.Endpoints{}},
+			ctrlHandler.EnqueueRequestsFromMapFunc(r.mapEndpointsToEMQX),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Pod{}},
+			ctrlHandler.EnqueueRequestsFromMapFunc(mapPodToEMQX),
+			builder.WithPredicates(podChangedPredicate),
+		).
 		Complete(r)
 }
 
@@ -153,41 +229,146 @@ func newRequester(k8sClient client.Client, instance *appsv2alpha2.EMQX) (innerRe
 		return nil, err
 	}
 
-	var port string
-	dashboardPort, err := appsv2alpha2.GetDashboardServicePort(instance)
-	if err != nil || dashboardPort == nil {
-		port = "18083"
+	scheme := appsv2alpha2.GetManagementAPIScheme(instance)
+	port := getManagementAPIPort(instance, scheme)
+	var tlsConfig *innerReq.TLSConfig
+	if scheme == "https" {
+		if tlsConfig, err = getManagementAPITLSConfig(context.Background(), k8sClient, instance); err != nil {
+			return nil, err
+		}
 	}
 
-	if dashboardPort != nil {
-		port = dashboardPort.TargetPort.String()
-	}
+	return &innerReq.Pool{
+		Requester: innerReq.Requester{
+			Scheme:    scheme,
+			Username:  username,
+			Password:  password,
+			TLS:       tlsConfig,
+			Kind:      emqxKind,
+			Namespace: instance.Namespace,
+			Instance:  instance.Name,
+		},
+		Endpoints:       getManagementAPIEndpoints(instance, port, listPods(k8sClient, instance)),
+		HealthCheckPath: "status",
+	}, nil
+}
 
+func listPods(k8sClient client.Client, instance *appsv2alpha2.EMQX) []corev1.Pod {
 	podList := &corev1.PodList{}
-	labels := instance.Spec.CoreTemplate.Labels
-	if instance.Status.CoreNodesStatus.CurrentRevision != "" {
-		labels = appsv2alpha2.CloneAndAddLabel(
-			labels,
-			appsv2alpha2.PodTemplateHashLabelKey,
-			instance.Status.CoreNodesStatus.CurrentRevision,
-		)
-	}
-
 	_ = k8sClient.List(context.Background(), podList,
 		client.InNamespace(instance.Namespace),
-		client.MatchingLabels(labels),
+		client.MatchingLabels(instance.Spec.CoreTemplate.Labels),
 	)
-	for _, pod := range podList.Items {
-		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" {
-			return &innerReq.Requester{
-				Host:     fmt.Sprintf("%s:%s", pod.Status.PodIP, port),
-				Username: username,
-				Password: password,
-			}, nil
+	pods := podList.Items
+	if instance.Spec.ReplicantTemplate != nil {
+		replicantList := &corev1.PodList{}
+		_ = k8sClient.List(context.Background(), replicantList,
+			client.InNamespace(instance.Namespace),
+			client.MatchingLabels(instance.Spec.ReplicantTemplate.Labels),
+		)
+		pods = append(pods, replicantList.Items...)
+	}
+	return pods
+}
+
+// maxReplicantEndpoints caps the replicant pods to request the EMQX management API,
+// since all of them are checked in parallel before the first request.
+const maxReplicantEndpoints = 3
+
+// getManagementAPIEndpoints returns the candidate endpoints to request the EMQX management API, in order of:
+// the running core pods, the running replicant pods, and the dashboard service at last. The pods of the current revision
+// are used if any of them is running, so that the pods being replaced are not requested.
+func getManagementAPIEndpoints(instance *appsv2alpha2.EMQX, port string, pods []corev1.Pod) []innerReq.Endpoint {
+	var cores, replicants []corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Labels[appsv2alpha2.DBRoleLabelKey] == "core" {
+			cores = append(cores, pod)
+		} else {
+			replicants = append(replicants, pod)
+		}
+	}
+	cores = filterCurrentRevisionPods(cores, instance.Status.CoreNodesStatus.CurrentRevision)
+	if instance.Status.ReplicantNodesStatus != nil {
+		replicants = filterCurrentRevisionPods(replicants, instance.Status.ReplicantNodesStatus.CurrentRevision)
+	}
+	if len(replicants) > maxReplicantEndpoints {
+		replicants = replicants[:maxReplicantEndpoints]
+	}
+
+	var endpoints []innerReq.Endpoint
+	for _, pod := range append(cores, replicants...) {
+		endpoints = append(endpoints, innerReq.Endpoint{
+			Name: pod.Name,
+			Host: net.JoinHostPort(pod.Status.PodIP, port),
+		})
+	}
+	// The dashboard service is the last resort, it is requested even if none of the pods is known to be running
+	for _, svcPort := range instance.Spec.DashboardServiceTemplate.Spec.Ports {
+		if svcPort.TargetPort.String() == port {
+			svcName := instance.Spec.DashboardServiceTemplate.Name
+			endpoints = append(endpoints, innerReq.Endpoint{
+				Name: svcName,
+				Host: net.JoinHostPort(fmt.Sprintf("%s.%s.svc", svcName, instance.Namespace), strconv.Itoa(int(svcPort.Port))),
+			})
+			break
 		}
 	}
+	return endpoints
+}
 
-	return nil, nil
+// filterCurrentRevisionPods returns the pods of the revision sorted by name, or all the pods if none of them is of the revision
+func filterCurrentRevisionPods(pods []corev1.Pod, revision string) []corev1.Pod {
+	var current []corev1.Pod
+	for _, pod := range pods {
+		if getRevision(pod.ObjectMeta) == revision {
+			current = append(current, pod)
+		}
+	}
+	if len(current) == 0 {
+		current = pods
+	}
+	sort.SliceStable(current, func(i, j int) bool {
+		return current[i].Name < current[j].Name
+	})
+	return current
+}
+
+// getManagementAPIPort returns the port of the dashboard listener to request the EMQX management API by the scheme
+func getManagementAPIPort(instance *appsv2alpha2.EMQX, scheme string) string {
+	getPort, port := appsv2alpha2.GetDashboardServicePort, "18083"
+	if scheme == "https" {
+		getPort, port = appsv2alpha2.GetDashboardHTTPSServicePort, "18084"
+	}
+	if dashboardPort, err := getPort(instance); err == nil && dashboardPort != nil {
+		port = dashboardPort.TargetPort.String()
+	}
+	return port
+}
+
+// getManagementAPITLSConfig returns the TLS config to request the EMQX management API over HTTPS,
+// the certificates are read from the secret in spec.managementAPI.tls.
+func getManagementAPITLSConfig(ctx context.Context, k8sClient client.Client, instance *appsv2alpha2.EMQX) (*innerReq.TLSConfig, error) {
+	tlsConfig := &innerReq.TLSConfig{}
+	if instance.Spec.ManagementAPI == nil || instance.Spec.ManagementAPI.TLS == nil {
+		return tlsConfig, nil
+	}
+	tlsConfig.ServerName = instance.Spec.ManagementAPI.TLS.ServerName
+	tlsConfig.InsecureSkipVerify = instance.Spec.ManagementAPI.TLS.InsecureSkipVerify
+
+	if secretName := instance.Spec.ManagementAPI.TLS.SecretName; secretName != "" {
+		secret := &corev1.Secret{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: instance.Namespace, Name: secretName}, secret); err != nil {
+			// Do not wrap the error, the not found error of the bootstrap user secret is handled by the caller
+			return nil, emperror.Errorf("failed to get TLS secret %s: %s", secretName, err)
+		}
+		tlsConfig.CACert = secret.Data["ca.crt"]
+		tlsConfig.ClientCert = secret.Data[corev1.TLSCertKey]
+		tlsConfig.ClientKey = secret.Data[corev1.TLSPrivateKeyKey]
+	}
+	return tlsConfig, nil
 }
 
 func getBootstrapUser(ctx context.Context, client client.Client, instance *appsv2alpha2.EMQX) (username, password string, err error) {
@@ -200,11 +381,13 @@ func getBootstrapUser(ctx context.Context, client client.Client, instance *appsv
 		return
 	}
 
+	// The API key is changed by the rotation
+	key := getOperatorAPIKey(bootstrapUser)
 	if data, ok := bootstrapUser.Data["bootstrap_user"]; ok {
 		users := strings.Split(string(data), "\n")
 		for _, user := range users {
 			index := strings.Index(user, ":")
-			if index > 0 && user[:index] == appsv2alpha2.DefaultBootstrapAPIKey {
+			if index > 0 && user[:index] == key {
 				username = user[:index]
 				password = user[index+1:]
 				return
@@ -0,0 +1,183 @@
+package v2alpha2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cisco-open/k8s-objectmatcher/patch"
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/handler"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetManagementAPIEndpoints(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "emqx"}}
+	instance.Status.CoreNodesStatus.CurrentRevision = "core-new"
+	instance.Status.ReplicantNodesStatus = &appsv2alpha2.EMQXNodesStatus{CurrentRevision: "repl-new"}
+	instance.Spec.DashboardServiceTemplate.Name = "emqx-dashboard"
+	instance.Spec.DashboardServiceTemplate.Spec.Ports = []corev1.ServicePort{
+		{Name: "dashboard-listeners-http-bind", Port: 80, TargetPort: intstr.FromInt(18083)},
+	}
+
+	newPod := func(name, role, revision, ip string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					appsv2alpha2.DBRoleLabelKey:          role,
+					appsv2alpha2.PodTemplateHashLabelKey: revision,
+				},
+			},
+			Status: corev1.PodStatus{Phase: phase, PodIP: ip},
+		}
+	}
+
+	t.Run("no running pods", func(t *testing.T) {
+		pods := []corev1.Pod{newPod("emqx-core-new-0", "core", "core-new", "", corev1.PodPending)}
+		assert.Equal(t, []innerReq.Endpoint{
+			{Name: "emqx-dashboard", Host: "emqx-dashboard.default.svc:80"},
+		}, getManagementAPIEndpoints(instance, "18083", pods))
+	})
+
+	t.Run("order of endpoints", func(t *testing.T) {
+		pods := []corev1.Pod{
+			newPod("emqx-replicant-old-a", "replicant", "repl-old", "10.0.0.6", corev1.PodRunning),
+			newPod("emqx-replicant-new-a", "replicant", "repl-new", "10.0.0.5", corev1.PodRunning),
+			newPod("emqx-core-old-0", "core", "core-old", "10.0.0.4", corev1.PodRunning),
+			newPod("emqx-core-new-1", "core", "core-new", "10.0.0.2", corev1.PodRunning),
+			newPod("emqx-core-new-0", "core", "core-new", "10.0.0.1", corev1.PodRunning),
+			newPod("emqx-core-new-2", "core", "core-new", "10.0.0.3", corev1.PodPending),
+		}
+		assert.Equal(t, []innerReq.Endpoint{
+			{Name: "emqx-core-new-0", Host: "10.0.0.1:18083"},
+			{Name: "emqx-core-new-1", Host: "10.0.0.2:18083"},
+			{Name: "emqx-replicant-new-a", Host: "10.0.0.5:18083"},
+			{Name: "emqx-dashboard", Host: "emqx-dashboard.default.svc:80"},
+		}, getManagementAPIEndpoints(instance, "18083", pods))
+	})
+
+	t.Run("no running core pods of the current revision", func(t *testing.T) {
+		pods := []corev1.Pod{
+			newPod("emqx-replicant-new-a", "replicant", "repl-new", "10.0.0.6", corev1.PodRunning),
+			newPod("emqx-core-old-1", "core", "core-old", "10.0.0.5", corev1.PodRunning),
+			newPod("emqx-core-old-0", "core", "core-old", "10.0.0.4", corev1.PodRunning),
+			newPod("emqx-core-new-0", "core", "core-new", "10.0.0.1", corev1.PodPending),
+		}
+		assert.Equal(t, []innerReq.Endpoint{
+			{Name: "emqx-core-old-0", Host: "10.0.0.4:18083"},
+			{Name: "emqx-core-old-1", Host: "10.0.0.5:18083"},
+			{Name: "emqx-replicant-new-a", Host: "10.0.0.6:18083"},
+			{Name: "emqx-dashboard", Host: "emqx-dashboard.default.svc:80"},
+		}, getManagementAPIEndpoints(instance, "18083", pods))
+	})
+
+	t.Run("all core pods are down", func(t *testing.T) {
+		pods := []corev1.Pod{
+			newPod("emqx-core-new-0", "core", "core-new", "10.0.0.1", corev1.PodFailed),
+			newPod("emqx-core-new-1", "core", "core-new", "", corev1.PodPending),
+			newPod("emqx-replicant-old-a", "replicant", "repl-old", "10.0.0.6", corev1.PodRunning),
+			newPod("emqx-replicant-new-a", "replicant", "repl-new", "10.0.0.5", corev1.PodRunning),
+		}
+		assert.Equal(t, []innerReq.Endpoint{
+			{Name: "emqx-replicant-new-a", Host: "10.0.0.5:18083"},
+			{Name: "emqx-dashboard", Host: "emqx-dashboard.default.svc:80"},
+		}, getManagementAPIEndpoints(instance, "18083", pods))
+	})
+
+	t.Run("limit of the replicant pods", func(t *testing.T) {
+		var pods []corev1.Pod
+		for _, name := range []string{"e", "d", "c", "b", "a"} {
+			pods = append(pods, newPod("emqx-replicant-new-"+name, "replicant", "repl-new", "10.0.0.5", corev1.PodRunning))
+		}
+		endpoints := getManagementAPIEndpoints(instance, "18083", pods)
+		assert.Len(t, endpoints, maxReplicantEndpoints+1)
+		assert.Equal(t, "emqx-replicant-new-a", endpoints[0].Name)
+		assert.Equal(t, "emqx-dashboard", endpoints[maxReplicantEndpoints].Name)
+	})
+
+	t.Run("no service port of the management API", func(t *testing.T) {
+		pods := []corev1.Pod{newPod("emqx-core-new-0", "core", "core-new", "10.0.0.1", corev1.PodRunning)}
+		assert.Equal(t, []innerReq.Endpoint{
+			{Name: "emqx-core-new-0", Host: "10.0.0.1:18084"},
+		}, getManagementAPIEndpoints(instance, "18084", pods))
+	})
+}
+
+func TestReconcilePaused(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = appsv2alpha2.AddToScheme(scheme)
+
+	instance := &appsv2alpha2.EMQX{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "emqx"},
+		Spec:       appsv2alpha2.EMQXSpec{Image: "emqx:5", Paused: true},
+	}
+	instance.Spec.CoreTemplate.Spec.Replicas = pointer.Int32(2)
+	instance.Default()
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance).Build()
+	r := &EMQXReconciler{
+		Handler: &handler.Handler{
+			Client:  k8sClient,
+			Patcher: &handler.Patcher{Annotator: patch.NewAnnotator(handler.LastAppliedAnnotation)},
+		},
+		Scheme:         scheme,
+		EventRecorder:  record.NewFakeRecorder(10),
+		ResyncInterval: DefaultResyncInterval,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(instance)}
+
+	t.Run("paused", func(t *testing.T) {
+		result, err := r.Reconcile(context.Background(), req)
+		assert.Nil(t, err)
+		assert.Equal(t, ctrl.Result{RequeueAfter: DefaultResyncInterval}, result)
+
+		got := &appsv2alpha2.EMQX{}
+		assert.Nil(t, k8sClient.Get(context.Background(), req.NamespacedName, got))
+		assert.True(t, got.Status.IsConditionTrue(appsv2alpha2.ReconcilePaused))
+		// The status is still updated
+		assert.Equal(t, *instance.Spec.CoreTemplate.Spec.Replicas, got.Status.CoreNodesStatus.Replicas)
+
+		// The resources of the EMQX cluster are left untouched
+		secrets := &corev1.SecretList{}
+		assert.Nil(t, k8sClient.List(context.Background(), secrets))
+		assert.Empty(t, secrets.Items)
+		configMaps := &corev1.ConfigMapList{}
+		assert.Nil(t, k8sClient.List(context.Background(), configMaps))
+		assert.Empty(t, configMaps.Items)
+		services := &corev1.ServiceList{}
+		assert.Nil(t, k8sClient.List(context.Background(), services))
+		assert.Empty(t, services.Items)
+		statefulSets := &appsv1.StatefulSetList{}
+		assert.Nil(t, k8sClient.List(context.Background(), statefulSets))
+		assert.Empty(t, statefulSets.Items)
+	})
+
+	t.Run("resumed", func(t *testing.T) {
+		got := &appsv2alpha2.EMQX{}
+		assert.Nil(t, k8sClient.Get(context.Background(), req.NamespacedName, got))
+		got.Spec.Paused = false
+		assert.Nil(t, k8sClient.Update(context.Background(), got))
+
+		_, err := r.Reconcile(context.Background(), req)
+		assert.Nil(t, err)
+
+		assert.Nil(t, k8sClient.Get(context.Background(), req.NamespacedName, got))
+		_, condition := got.Status.GetCondition(appsv2alpha2.ReconcilePaused)
+		assert.Nil(t, condition)
+
+		secret := &corev1.Secret{}
+		assert.Nil(t, k8sClient.Get(context.Background(), instance.BootstrapUserNamespacedName(), secret))
+	})
+}
@@ -0,0 +1,398 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2alpha2
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	emperror "emperror.dev/errors"
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const autoscalerSyncPeriod = time.Duration(20) * time.Second
+
+// recommendation is the desired replicas computed by the autoscaler at a point in time
+type recommendation struct {
+	replicas  int32
+	timestamp time.Time
+}
+
+// EMQXAutoscalerReconciler reconciles a EMQXAutoscaler object
+type EMQXAutoscalerReconciler struct {
+	Client        client.Client
+	EventRecorder record.EventRecorder
+
+	// recommendations are kept in memory like the HorizontalPodAutoscaler controller,
+	// they are used by the stabilization windows.
+	mu              sync.Mutex
+	recommendations map[types.NamespacedName][]recommendation
+}
+
+func NewEMQXAutoscalerReconciler(mgr manager.Manager) *EMQXAutoscalerReconciler {
+	return &EMQXAutoscalerReconciler{
+		Client:          mgr.GetClient(),
+		EventRecorder:   mgr.GetEventRecorderFor("emqxautoscaler-controller"),
+		recommendations: make(map[types.NamespacedName][]recommendation),
+	}
+}
+
+//+kubebuilder:rbac:groups=apps.emqx.io,resources=emqxautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps.emqx.io,resources=emqxautoscalers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=apps.emqx.io,resources=emqxautoscalers/finalizers,verbs=update
+
+func (r *EMQXAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	autoscaler := &appsv2alpha2.EMQXAutoscaler{}
+	if err := r.Client.Get(ctx, req.NamespacedName, autoscaler); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			r.mu.Lock()
+			delete(r.recommendations, req.NamespacedName)
+			r.mu.Unlock()
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if autoscaler.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	instance := &appsv2alpha2.EMQX{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: autoscaler.Namespace, Name: autoscaler.Spec.InstanceName}, instance); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return r.setInactive(ctx, autoscaler, "TargetNotFound", fmt.Sprintf("EMQX %s not found", autoscaler.Spec.InstanceName))
+		}
+		return ctrl.Result{}, emperror.Wrap(err, "failed to get EMQX")
+	}
+	if instance.Spec.ReplicantTemplate == nil || instance.Status.ReplicantNodesStatus == nil {
+		return r.setInactive(ctx, autoscaler, "NoReplicantNodes", fmt.Sprintf("EMQX %s has no replicant nodes", instance.Name))
+	}
+	if minReplicas := getMinReplicas(autoscaler); minReplicas > autoscaler.Spec.MaxReplicas {
+		return r.setInactive(ctx, autoscaler, "InvalidSpec", fmt.Sprintf("minReplicas %d is greater than maxReplicas %d", minReplicas, autoscaler.Spec.MaxReplicas))
+	}
+	if !instance.Status.IsConditionTrue(appsv2alpha2.Ready) {
+		// Do not scale while the EMQX cluster is being created or updated
+		return r.setInactive(ctx, autoscaler, "TargetNotReady", fmt.Sprintf("EMQX %s is not ready", instance.Name))
+	}
+
+	average, ok := getAverageMetricValue(autoscaler, instance.Status.ReplicantNodesStatus.Nodes)
+	if !ok {
+		return r.setInactive(ctx, autoscaler, "FailedGetMetric", "no running replicant nodes found")
+	}
+
+	now := time.Now()
+	currentReplicas := *instance.Spec.ReplicantTemplate.Spec.Replicas
+	desiredReplicas, limited := getDesiredReplicas(autoscaler, average, int32(len(getRunningReplicantNodes(instance.Status.ReplicantNodesStatus.Nodes))))
+	desiredReplicas = r.stabilize(req.NamespacedName, autoscaler, currentReplicas, desiredReplicas, now)
+	if desiredReplicas != currentReplicas && isInCooldown(autoscaler, now) {
+		desiredReplicas = currentReplicas
+	}
+
+	autoscaler.Status.CurrentReplicas = currentReplicas
+	autoscaler.Status.DesiredReplicas = desiredReplicas
+	autoscaler.Status.CurrentAverageValue = average
+	meta.SetStatusCondition(&autoscaler.Status.Conditions, metav1.Condition{
+		Type:    appsv2alpha2.ScalingActive,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ValidMetricFound",
+		Message: fmt.Sprintf("the autoscaler was able to compute the replicas from %s", autoscaler.Spec.Metric),
+	})
+	if limited {
+		meta.SetStatusCondition(&autoscaler.Status.Conditions, metav1.Condition{
+			Type:    appsv2alpha2.ScalingLimited,
+			Status:  metav1.ConditionTrue,
+			Reason:  "TooFewOrTooManyReplicas",
+			Message: "the desired replicas is limited by minReplicas or maxReplicas",
+		})
+	} else {
+		meta.RemoveStatusCondition(&autoscaler.Status.Conditions, appsv2alpha2.ScalingLimited)
+	}
+
+	if desiredReplicas != currentReplicas {
+		var marked []markedPod
+		if desiredReplicas < currentReplicas {
+			var err error
+			if marked, err = r.markPodsToDelete(ctx, autoscaler, instance, currentReplicas-desiredReplicas); err != nil {
+				return ctrl.Result{}, emperror.Wrap(err, "failed to mark pods to delete")
+			}
+		}
+
+		logger.V(1).Info("scale EMQX replicant nodes", "current", currentReplicas, "desired", desiredReplicas)
+		patch := client.MergeFrom(instance.DeepCopy())
+		instance.Spec.ReplicantTemplate.Spec.Replicas = &desiredReplicas
+		if err := r.Client.Patch(ctx, instance, patch); err != nil {
+			// The marked pods would be deleted first by any later scale down
+			r.unmarkPodsToDelete(ctx, marked)
+			return ctrl.Result{}, emperror.Wrap(err, "failed to scale EMQX replicant nodes")
+		}
+		autoscaler.Status.LastScaleTime = &metav1.Time{Time: now}
+		r.EventRecorder.Event(autoscaler, corev1.EventTypeNormal, "SuccessfulRescale",
+			fmt.Sprintf("New size: %d; reason: %s average %d, target %d", desiredReplicas, autoscaler.Spec.Metric, average, autoscaler.Spec.TargetAverageValue),
+		)
+	}
+
+	if err := r.Client.Status().Update(ctx, autoscaler); err != nil {
+		return ctrl.Result{}, emperror.Wrap(err, "failed to update status")
+	}
+	return ctrl.Result{RequeueAfter: autoscalerSyncPeriod}, nil
+}
+
+func (r *EMQXAutoscalerReconciler) setInactive(ctx context.Context, autoscaler *appsv2alpha2.EMQXAutoscaler, reason, message string) (ctrl.Result, error) {
+	meta.SetStatusCondition(&autoscaler.Status.Conditions, metav1.Condition{
+		Type:    appsv2alpha2.ScalingActive,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	if err := r.Client.Status().Update(ctx, autoscaler); err != nil {
+		return ctrl.Result{}, emperror.Wrap(err, "failed to update status")
+	}
+	return ctrl.Result{RequeueAfter: autoscalerSyncPeriod}, nil
+}
+
+// stabilize records the recommendation and returns the stabilized replicas
+func (r *EMQXAutoscalerReconciler) stabilize(key types.NamespacedName, autoscaler *appsv2alpha2.EMQXAutoscaler, currentReplicas, desiredReplicas int32, now time.Time) int32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var upWindow, downWindow time.Duration
+	if autoscaler.Spec.ScaleUpStabilizationWindowSeconds != nil {
+		upWindow = time.Duration(*autoscaler.Spec.ScaleUpStabilizationWindowSeconds) * time.Second
+	}
+	if autoscaler.Spec.ScaleDownStabilizationWindowSeconds != nil {
+		downWindow = time.Duration(*autoscaler.Spec.ScaleDownStabilizationWindowSeconds) * time.Second
+	}
+	maxWindow := upWindow
+	if downWindow > maxWindow {
+		maxWindow = downWindow
+	}
+
+	list := []recommendation{{replicas: desiredReplicas, timestamp: now}}
+	for _, rec := range r.recommendations[key] {
+		if now.Sub(rec.timestamp) <= maxWindow {
+			list = append(list, rec)
+		}
+	}
+	r.recommendations[key] = list
+
+	return stabilizeRecommendation(list, currentReplicas, upWindow, downWindow, now)
+}
+
+// podDeletionCostAnnotation is the annotation of the cost of deleting a pod compared to the other pods of the replicaSet,
+// https://kubernetes.io/docs/concepts/workloads/controllers/replicaset/#pod-deletion-cost
+const podDeletionCostAnnotation = "controller.kubernetes.io/pod-deletion-cost"
+
+// markedPod is the pod marked to delete and its pod deletion cost before it is marked
+type markedPod struct {
+	pod     *corev1.Pod
+	oldCost *string
+}
+
+// markPodsToDelete sets the lowest pod deletion cost to the least-loaded pods of the current replicaSet,
+// so the replicaSet controller deletes them first when the replicas are decreased.
+func (r *EMQXAutoscalerReconciler) markPodsToDelete(ctx context.Context, autoscaler *appsv2alpha2.EMQXAutoscaler, instance *appsv2alpha2.EMQX, count int32) ([]markedPod, error) {
+	currentRs, _ := getReplicaSetList(ctx, r.Client, instance)
+	if currentRs == nil {
+		return nil, nil
+	}
+	podList := &corev1.PodList{}
+	if err := r.Client.List(ctx, podList,
+		client.InNamespace(instance.Namespace),
+		client.MatchingLabels(currentRs.Spec.Selector.MatchLabels),
+	); err != nil {
+		return nil, emperror.Wrap(err, "failed to list pods")
+	}
+
+	var marked []markedPod
+	for _, pod := range selectLeastLoadedPods(autoscaler, instance.Status.ReplicantNodesStatus.Nodes, podList.Items, count) {
+		m := markedPod{pod: pod}
+		if cost, ok := pod.Annotations[podDeletionCostAnnotation]; ok {
+			m.oldCost = &cost
+		}
+		patch := client.MergeFrom(pod.DeepCopy())
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		pod.Annotations[podDeletionCostAnnotation] = "-99999"
+		if err := r.Client.Patch(ctx, pod, patch); err != nil {
+			r.unmarkPodsToDelete(ctx, marked)
+			return nil, emperror.Wrapf(err, "failed patch pod %s deletion cost", pod.Name)
+		}
+		marked = append(marked, m)
+	}
+	return marked, nil
+}
+
+// unmarkPodsToDelete restores the pod deletion cost of the marked pods when the replicas are not decreased,
+// it is best effort, the failures are logged.
+func (r *EMQXAutoscalerReconciler) unmarkPodsToDelete(ctx context.Context, marked []markedPod) {
+	logger := log.FromContext(ctx)
+	for _, m := range marked {
+		patch := client.MergeFrom(m.pod.DeepCopy())
+		if m.oldCost != nil {
+			m.pod.Annotations[podDeletionCostAnnotation] = *m.oldCost
+		} else {
+			delete(m.pod.Annotations, podDeletionCostAnnotation)
+		}
+		if err := r.Client.Patch(ctx, m.pod, patch); err != nil {
+			logger.Error(err, "failed to restore pod deletion cost", "pod", m.pod.Name)
+		}
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *EMQXAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&appsv2alpha2.EMQXAutoscaler{}).
+		WithEventFilter(predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				// Ignore updates to CR status in which case metadata.Generation does not change
+				return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration()
+			},
+		}).
+		Complete(r)
+}
+
+func getMinReplicas(autoscaler *appsv2alpha2.EMQXAutoscaler) int32 {
+	if autoscaler.Spec.MinReplicas == nil {
+		return 1
+	}
+	return *autoscaler.Spec.MinReplicas
+}
+
+func getRunningReplicantNodes(nodes []appsv2alpha2.EMQXNode) []appsv2alpha2.EMQXNode {
+	list := []appsv2alpha2.EMQXNode{}
+	for _, node := range nodes {
+		if node.Role == "replicant" && node.NodeStatus == "running" {
+			list = append(list, node)
+		}
+	}
+	return list
+}
+
+func getMetricValue(autoscaler *appsv2alpha2.EMQXAutoscaler, node appsv2alpha2.EMQXNode) int64 {
+	if autoscaler.Spec.Metric == appsv2alpha2.AutoscalerMetricSessions {
+		return node.Session
+	}
+	return node.Connections
+}
+
+// getAverageMetricValue returns the average of the metric across all running replicant nodes,
+// returns false if there is no running replicant node.
+func getAverageMetricValue(autoscaler *appsv2alpha2.EMQXAutoscaler, nodes []appsv2alpha2.EMQXNode) (int64, bool) {
+	running := getRunningReplicantNodes(nodes)
+	if len(running) == 0 {
+		return 0, false
+	}
+	var total int64
+	for _, node := range running {
+		total += getMetricValue(autoscaler, node)
+	}
+	return total / int64(len(running)), true
+}
+
+// getDesiredReplicas returns the replicas needed to bring the average of the metric to the target,
+// the result is rounded up and limited by minReplicas and maxReplicas, returns true if it is limited.
+func getDesiredReplicas(autoscaler *appsv2alpha2.EMQXAutoscaler, average int64, running int32) (int32, bool) {
+	total := average * int64(running)
+	target := autoscaler.Spec.TargetAverageValue
+	desired := int32((total + target - 1) / target)
+
+	if minReplicas := getMinReplicas(autoscaler); desired < minReplicas {
+		return minReplicas, true
+	}
+	if desired > autoscaler.Spec.MaxReplicas {
+		return autoscaler.Spec.MaxReplicas, true
+	}
+	return desired, false
+}
+
+// stabilizeRecommendation uses the lowest recommendation in the scale up window and the highest recommendation
+// in the scale down window, so the replicas do not flap when the metric fluctuates.
+func stabilizeRecommendation(recommendations []recommendation, currentReplicas int32, upWindow, downWindow time.Duration, now time.Time) int32 {
+	upRecommendation, downRecommendation := int32(-1), int32(-1)
+	for _, rec := range recommendations {
+		if now.Sub(rec.timestamp) <= upWindow && (upRecommendation == -1 || rec.replicas < upRecommendation) {
+			upRecommendation = rec.replicas
+		}
+		if now.Sub(rec.timestamp) <= downWindow && rec.replicas > downRecommendation {
+			downRecommendation = rec.replicas
+		}
+	}
+
+	replicas := currentReplicas
+	if upRecommendation != -1 && replicas < upRecommendation {
+		replicas = upRecommendation
+	}
+	if downRecommendation != -1 && replicas > downRecommendation {
+		replicas = downRecommendation
+	}
+	return replicas
+}
+
+func isInCooldown(autoscaler *appsv2alpha2.EMQXAutoscaler, now time.Time) bool {
+	if autoscaler.Status.LastScaleTime == nil || autoscaler.Spec.CooldownSeconds == nil {
+		return false
+	}
+	return now.Sub(autoscaler.Status.LastScaleTime.Time) < time.Duration(*autoscaler.Spec.CooldownSeconds)*time.Second
+}
+
+// selectLeastLoadedPods returns the count pods with the lowest metric value, the pods that have not joined
+// the EMQX cluster are picked first.
+func selectLeastLoadedPods(autoscaler *appsv2alpha2.EMQXAutoscaler, nodes []appsv2alpha2.EMQXNode, pods []corev1.Pod, count int32) []*corev1.Pod {
+	type podMetricValue struct {
+		pod   *corev1.Pod
+		value int64
+	}
+	var list []podMetricValue
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		var value int64 = -1
+		if node := findEMQXNodeByPod(nodes, &pod); node != nil {
+			value = getMetricValue(autoscaler, *node)
+		}
+		list = append(list, podMetricValue{pod: pod.DeepCopy(), value: value})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].value < list[j].value
+	})
+
+	selected := []*corev1.Pod{}
+	for i := 0; i < len(list) && int32(i) < count; i++ {
+		selected = append(selected, list[i].pod)
+	}
+	return selected
+}
@@ -0,0 +1,225 @@
+package v2alpha2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlFake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetAverageMetricValue(t *testing.T) {
+	autoscaler := &appsv2alpha2.EMQXAutoscaler{}
+	nodes := []appsv2alpha2.EMQXNode{
+		{Node: "emqx@emqx-core-0.emqx-headless.default.svc.cluster.local", Role: "core", NodeStatus: "running", Connections: 1000, Session: 1000},
+		{Node: "emqx@10.0.0.1", Role: "replicant", NodeStatus: "running", Connections: 100, Session: 300},
+		{Node: "emqx@10.0.0.2", Role: "replicant", NodeStatus: "running", Connections: 200, Session: 500},
+		{Node: "emqx@10.0.0.3", Role: "replicant", NodeStatus: "stopped"},
+	}
+
+	t.Run("connections", func(t *testing.T) {
+		autoscaler.Spec.Metric = appsv2alpha2.AutoscalerMetricConnections
+		average, ok := getAverageMetricValue(autoscaler, nodes)
+		assert.True(t, ok)
+		assert.Equal(t, int64(150), average)
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		autoscaler.Spec.Metric = appsv2alpha2.AutoscalerMetricSessions
+		average, ok := getAverageMetricValue(autoscaler, nodes)
+		assert.True(t, ok)
+		assert.Equal(t, int64(400), average)
+	})
+
+	t.Run("no running replicant nodes", func(t *testing.T) {
+		_, ok := getAverageMetricValue(autoscaler, nodes[:1])
+		assert.False(t, ok)
+	})
+}
+
+func TestGetDesiredReplicas(t *testing.T) {
+	autoscaler := &appsv2alpha2.EMQXAutoscaler{
+		Spec: appsv2alpha2.EMQXAutoscalerSpec{
+			MinReplicas:        pointer.Int32(2),
+			MaxReplicas:        5,
+			TargetAverageValue: 100,
+		},
+	}
+
+	t.Run("round up", func(t *testing.T) {
+		replicas, limited := getDesiredReplicas(autoscaler, 110, 3)
+		assert.Equal(t, int32(4), replicas)
+		assert.False(t, limited)
+	})
+
+	t.Run("limited by minReplicas", func(t *testing.T) {
+		replicas, limited := getDesiredReplicas(autoscaler, 10, 3)
+		assert.Equal(t, int32(2), replicas)
+		assert.True(t, limited)
+	})
+
+	t.Run("limited by maxReplicas", func(t *testing.T) {
+		replicas, limited := getDesiredReplicas(autoscaler, 300, 3)
+		assert.Equal(t, int32(5), replicas)
+		assert.True(t, limited)
+	})
+}
+
+func TestStabilizeRecommendation(t *testing.T) {
+	now := time.Now()
+	recommendations := []recommendation{
+		{replicas: 3, timestamp: now},
+		{replicas: 5, timestamp: now.Add(-time.Minute)},
+		{replicas: 2, timestamp: now.Add(-10 * time.Minute)},
+	}
+
+	t.Run("no window", func(t *testing.T) {
+		assert.Equal(t, int32(3), stabilizeRecommendation(recommendations, 4, 0, 0, now))
+		assert.Equal(t, int32(3), stabilizeRecommendation(recommendations, 2, 0, 0, now))
+	})
+
+	t.Run("scale down window", func(t *testing.T) {
+		assert.Equal(t, int32(5), stabilizeRecommendation(recommendations, 6, 0, 5*time.Minute, now))
+		assert.Equal(t, int32(4), stabilizeRecommendation(recommendations, 4, 0, 5*time.Minute, now))
+	})
+
+	t.Run("scale up window", func(t *testing.T) {
+		assert.Equal(t, int32(3), stabilizeRecommendation(recommendations, 2, 5*time.Minute, 0, now))
+		assert.Equal(t, int32(2), stabilizeRecommendation(recommendations, 1, 15*time.Minute, 0, now))
+	})
+}
+
+func TestIsInCooldown(t *testing.T) {
+	now := time.Now()
+	autoscaler := &appsv2alpha2.EMQXAutoscaler{}
+	assert.False(t, isInCooldown(autoscaler, now))
+
+	autoscaler.Spec.CooldownSeconds = pointer.Int32(60)
+	autoscaler.Status.LastScaleTime = &metav1.Time{Time: now.Add(-30 * time.Second)}
+	assert.True(t, isInCooldown(autoscaler, now))
+
+	autoscaler.Status.LastScaleTime = &metav1.Time{Time: now.Add(-90 * time.Second)}
+	assert.False(t, isInCooldown(autoscaler, now))
+}
+
+func TestSelectLeastLoadedPods(t *testing.T) {
+	autoscaler := &appsv2alpha2.EMQXAutoscaler{
+		Spec: appsv2alpha2.EMQXAutoscalerSpec{Metric: appsv2alpha2.AutoscalerMetricConnections},
+	}
+	nodes := []appsv2alpha2.EMQXNode{
+		{Node: "emqx@10.0.0.1", Role: "replicant", PodName: "emqx-replicant-a", Connections: 300},
+		{Node: "emqx@10.0.0.2", Role: "replicant", PodName: "emqx-replicant-b", Connections: 100},
+		{Node: "emqx@10.0.0.3", Role: "replicant", PodName: "emqx-replicant-c", Connections: 200},
+	}
+	newPod := func(name, ip string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.PodStatus{PodIP: ip},
+		}
+	}
+	pods := []corev1.Pod{
+		newPod("emqx-replicant-a", "10.0.0.1"),
+		newPod("emqx-replicant-b", "10.0.0.2"),
+		newPod("emqx-replicant-c", "10.0.0.3"),
+	}
+
+	t.Run("least loaded", func(t *testing.T) {
+		got := selectLeastLoadedPods(autoscaler, nodes, pods, 2)
+		assert.Len(t, got, 2)
+		assert.Equal(t, "emqx-replicant-b", got[0].Name)
+		assert.Equal(t, "emqx-replicant-c", got[1].Name)
+	})
+
+	t.Run("pod not in cluster first", func(t *testing.T) {
+		got := selectLeastLoadedPods(autoscaler, nodes, append(pods, newPod("emqx-replicant-d", "10.0.0.4")), 1)
+		assert.Len(t, got, 1)
+		assert.Equal(t, "emqx-replicant-d", got[0].Name)
+	})
+
+	t.Run("count greater than pods", func(t *testing.T) {
+		assert.Len(t, selectLeastLoadedPods(autoscaler, nodes, pods, 5), 3)
+	})
+}
+
+func TestMarkPodsToDelete(t *testing.T) {
+	autoscaler := &appsv2alpha2.EMQXAutoscaler{
+		Spec: appsv2alpha2.EMQXAutoscalerSpec{Metric: appsv2alpha2.AutoscalerMetricConnections},
+	}
+	labels := map[string]string{appsv2alpha2.DBRoleLabelKey: "replicant", appsv2alpha2.PodTemplateHashLabelKey: "fake"}
+	instance := &appsv2alpha2.EMQX{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "emqx"}}
+	instance.Spec.ReplicantTemplate = &appsv2alpha2.EMQXReplicantTemplate{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{appsv2alpha2.DBRoleLabelKey: "replicant"}},
+	}
+	instance.Status.ReplicantNodesStatus = &appsv2alpha2.EMQXNodesStatus{
+		CurrentRevision: "fake",
+		Nodes: []appsv2alpha2.EMQXNode{
+			{Node: "emqx@10.0.0.1", Role: "replicant", PodName: "emqx-replicant-a", Connections: 300},
+			{Node: "emqx@10.0.0.2", Role: "replicant", PodName: "emqx-replicant-b", Connections: 100},
+			{Node: "emqx@10.0.0.3", Role: "replicant", PodName: "emqx-replicant-c", Connections: 200},
+		},
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "emqx-replicant-fake", Labels: labels},
+		Spec: appsv1.ReplicaSetSpec{
+			Replicas: pointer.Int32(3),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+		},
+	}
+	newPod := func(name, ip string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels, Annotations: annotations},
+			Status:     corev1.PodStatus{PodIP: ip},
+		}
+	}
+	getCost := func(t *testing.T, k8sClient client.Client, name string) (string, bool) {
+		pod := &corev1.Pod{}
+		assert.Nil(t, k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, pod))
+		cost, ok := pod.Annotations[podDeletionCostAnnotation]
+		return cost, ok
+	}
+
+	t.Run("mark and restore", func(t *testing.T) {
+		k8sClient := ctrlFake.NewClientBuilder().WithObjects(
+			rs,
+			newPod("emqx-replicant-a", "10.0.0.1", nil),
+			newPod("emqx-replicant-b", "10.0.0.2", map[string]string{podDeletionCostAnnotation: "5"}),
+			newPod("emqx-replicant-c", "10.0.0.3", nil),
+		).Build()
+		r := &EMQXAutoscalerReconciler{Client: k8sClient}
+
+		marked, err := r.markPodsToDelete(context.Background(), autoscaler, instance, 2)
+		assert.Nil(t, err)
+		assert.Len(t, marked, 2)
+		for _, name := range []string{"emqx-replicant-b", "emqx-replicant-c"} {
+			cost, _ := getCost(t, k8sClient, name)
+			assert.Equal(t, "-99999", cost)
+		}
+		_, ok := getCost(t, k8sClient, "emqx-replicant-a")
+		assert.False(t, ok)
+
+		r.unmarkPodsToDelete(context.Background(), marked)
+		cost, _ := getCost(t, k8sClient, "emqx-replicant-b")
+		assert.Equal(t, "5", cost)
+		_, ok = getCost(t, k8sClient, "emqx-replicant-c")
+		assert.False(t, ok)
+	})
+
+	t.Run("failed to list pods", func(t *testing.T) {
+		scheme := runtime.NewScheme()
+		_ = appsv1.AddToScheme(scheme)
+		r := &EMQXAutoscalerReconciler{Client: ctrlFake.NewClientBuilder().WithScheme(scheme).WithObjects(rs).Build()}
+
+		_, err := r.markPodsToDelete(context.Background(), autoscaler, instance, 1)
+		assert.ErrorContains(t, err, "failed to list pods")
+	})
+}
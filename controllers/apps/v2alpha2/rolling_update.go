@@ -0,0 +1,128 @@
+package v2alpha2
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func isRollingUpdate(instance *appsv2alpha2.EMQX) bool {
+	return instance.Spec.UpdateStrategy.Type == appsv2alpha2.RollingUpdateStrategyType
+}
+
+// getRollingUpdateBudget returns the absolute number of maxUnavailable and maxSurge for the desired replicas,
+// like the Deployment controller, maxSurge is rounded up and maxUnavailable is rounded down.
+func getRollingUpdateBudget(instance *appsv2alpha2.EMQX, replicas int32) (maxUnavailable, maxSurge int32) {
+	unavailable, surge := intstr.FromInt(1), intstr.FromInt(0)
+	if rollingUpdate := instance.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil {
+		if rollingUpdate.MaxUnavailable != nil {
+			unavailable = *rollingUpdate.MaxUnavailable
+		}
+		if rollingUpdate.MaxSurge != nil {
+			surge = *rollingUpdate.MaxSurge
+		}
+	}
+
+	u, _ := intstr.GetScaledValueFromIntOrPercent(&unavailable, int(replicas), false)
+	s, _ := intstr.GetScaledValueFromIntOrPercent(&surge, int(replicas), true)
+	if u == 0 && s == 0 {
+		// Validation should never allow the user to explicitly use zero values for both maxSurge
+		// and maxUnavailable. Due to rounding down maxUnavailable though, it may resolve to zero.
+		// If both fields are zero, use 1 as maxUnavailable.
+		u = 1
+	}
+	return int32(u), int32(s)
+}
+
+// getOutdatedPods returns the pods that were not created from the pod template with the given revision,
+// the pods being deleted are ignored.
+func getOutdatedPods(ctx context.Context, k8sClient client.Client, namespace string, matchLabels map[string]string, revision string) (pods, outdated []*corev1.Pod) {
+	podList := &corev1.PodList{}
+	_ = k8sClient.List(ctx, podList,
+		client.InNamespace(namespace),
+		client.MatchingLabels(matchLabels),
+	)
+	for _, p := range podList.Items {
+		if p.DeletionTimestamp != nil {
+			continue
+		}
+		pod := p.DeepCopy()
+		pods = append(pods, pod)
+		if pod.Annotations[appsv2alpha2.PodTemplateRevisionAnnotationKey] != revision {
+			outdated = append(outdated, pod)
+		}
+	}
+	return
+}
+
+// isPodAvailable checks whether the pod is on serving and has joined the EMQX cluster.
+func isPodAvailable(nodes []appsv2alpha2.EMQXNode, pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	var ready, onServing bool
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+			ready = true
+		}
+		if c.Type == appsv2alpha2.PodOnServing && c.Status == corev1.ConditionTrue {
+			onServing = true
+		}
+	}
+	return ready && onServing && findEMQXNodeByPod(nodes, pod) != nil
+}
+
+// selectPodsToRoll picks the outdated pods that can be deleted in this reconcile without
+// bringing the number of available pods below the desired replicas minus maxUnavailable.
+// The outdated pods must be sorted by priority, unavailable outdated pods are always picked first,
+// because deleting them does not reduce the availability of the cluster.
+func selectPodsToRoll(nodes []appsv2alpha2.EMQXNode, pods, outdated []*corev1.Pod, replicas, maxUnavailable int32) []*corev1.Pod {
+	var available int32
+	for _, pod := range pods {
+		if isPodAvailable(nodes, pod) {
+			available++
+		}
+	}
+
+	sort.SliceStable(outdated, func(i, j int) bool {
+		return !isPodAvailable(nodes, outdated[i]) && isPodAvailable(nodes, outdated[j])
+	})
+
+	budget := available - (replicas - maxUnavailable)
+	list := []*corev1.Pod{}
+	for _, pod := range outdated {
+		if !isPodAvailable(nodes, pod) {
+			list = append(list, pod)
+			continue
+		}
+		if budget <= 0 {
+			break
+		}
+		list = append(list, pod)
+		budget--
+	}
+	return list
+}
+
+// getPodSession returns the session count of the EMQX node running in the pod
+func getPodSession(nodes []appsv2alpha2.EMQXNode, pod *corev1.Pod) int64 {
+	if node := findEMQXNodeByPod(nodes, pod); node != nil {
+		return node.Session
+	}
+	return 0
+}
+
+// getPodOrdinal returns the ordinal of the statefulSet pod, or -1 if the pod name has no ordinal
+func getPodOrdinal(pod *corev1.Pod) int {
+	ordinal, err := strconv.Atoi(pod.Name[strings.LastIndex(pod.Name, "-")+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
@@ -0,0 +1,125 @@
+package v2alpha2
+
+import (
+	"testing"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestGetRollingUpdateBudget(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{}
+	instance.Spec.UpdateStrategy.Type = appsv2alpha2.RollingUpdateStrategyType
+
+	t.Run("default", func(t *testing.T) {
+		maxUnavailable, maxSurge := getRollingUpdateBudget(instance, 3)
+		assert.Equal(t, int32(1), maxUnavailable)
+		assert.Equal(t, int32(0), maxSurge)
+	})
+
+	t.Run("percent", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.RollingUpdate = &appsv2alpha2.RollingUpdateStrategy{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "50%"},
+			MaxSurge:       &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+		}
+		maxUnavailable, maxSurge := getRollingUpdateBudget(instance, 3)
+		assert.Equal(t, int32(1), maxUnavailable)
+		assert.Equal(t, int32(1), maxSurge)
+	})
+
+	t.Run("both resolve to zero", func(t *testing.T) {
+		instance.Spec.UpdateStrategy.RollingUpdate = &appsv2alpha2.RollingUpdateStrategy{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "10%"},
+			MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: 0},
+		}
+		maxUnavailable, maxSurge := getRollingUpdateBudget(instance, 3)
+		assert.Equal(t, int32(1), maxUnavailable)
+		assert.Equal(t, int32(0), maxSurge)
+	})
+}
+
+func TestSelectPodsToRoll(t *testing.T) {
+	newPod := func(name string, available bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if available {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: status},
+					{Type: appsv2alpha2.PodOnServing, Status: status},
+				},
+			},
+		}
+	}
+	nodes := []appsv2alpha2.EMQXNode{
+		{Node: "emqx@emqx-core-0.emqx-headless.default.svc.cluster.local", Role: "core", PodName: "emqx-core-0"},
+		{Node: "emqx@emqx-core-1.emqx-headless.default.svc.cluster.local", Role: "core", PodName: "emqx-core-1"},
+		{Node: "emqx@emqx-core-2.emqx-headless.default.svc.cluster.local", Role: "core", PodName: "emqx-core-2"},
+	}
+
+	t.Run("all available", func(t *testing.T) {
+		pods := []*corev1.Pod{newPod("emqx-core-0", true), newPod("emqx-core-1", true), newPod("emqx-core-2", true)}
+		got := selectPodsToRoll(nodes, pods, []*corev1.Pod{pods[2], pods[1], pods[0]}, 3, 1)
+		assert.Equal(t, []*corev1.Pod{pods[2]}, got)
+	})
+
+	t.Run("unavailable pods first", func(t *testing.T) {
+		pods := []*corev1.Pod{newPod("emqx-core-0", true), newPod("emqx-core-1", false), newPod("emqx-core-2", true)}
+		got := selectPodsToRoll(nodes, pods, []*corev1.Pod{pods[2], pods[1], pods[0]}, 3, 1)
+		assert.Equal(t, []*corev1.Pod{pods[1]}, got)
+	})
+
+	t.Run("pod not in cluster", func(t *testing.T) {
+		pods := []*corev1.Pod{newPod("emqx-core-0", true), newPod("emqx-core-1", true), newPod("emqx-core-2", true)}
+		got := selectPodsToRoll(nodes[:2], pods, []*corev1.Pod{pods[1], pods[2]}, 3, 1)
+		assert.Equal(t, []*corev1.Pod{pods[2]}, got)
+	})
+
+	t.Run("no budget", func(t *testing.T) {
+		pods := []*corev1.Pod{newPod("emqx-core-0", true), newPod("emqx-core-1", true), newPod("emqx-core-2", true)}
+		got := selectPodsToRoll(nodes, pods, []*corev1.Pod{pods[2], pods[1], pods[0]}, 4, 1)
+		assert.Empty(t, got)
+	})
+}
+
+func TestGetPodOrdinal(t *testing.T) {
+	assert.Equal(t, 2, getPodOrdinal(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "emqx-core-2"}}))
+	assert.Equal(t, -1, getPodOrdinal(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "emqx-replicant-fake"}}))
+}
+
+func TestFindEMQXNodeByPod(t *testing.T) {
+	nodes := []appsv2alpha2.EMQXNode{
+		{Node: "emqx@emqx-core-0.emqx-headless.default.svc.cluster.local", Role: "core", PodName: "emqx-core-0", PodUID: "fake-uid"},
+		{Node: "emqx@10.0.0.1", Role: "replicant"},
+	}
+
+	assert.Equal(t, nodes[0].DeepCopy(), findEMQXNodeByPod(nodes, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "emqx-core-0", UID: "fake-uid"},
+	}))
+	assert.Nil(t, findEMQXNodeByPod(nodes, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "emqx-core-0", UID: "recreated-uid"},
+	}))
+	assert.Nil(t, findEMQXNodeByPod(nodes, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "emqx-core-1"},
+	}))
+	assert.Nil(t, findEMQXNodeByPod(nodes, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "emqx-replicant-fake"},
+		Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+	}))
+}
+
+func TestIsEMQXNodeRunningInPod(t *testing.T) {
+	core := appsv2alpha2.EMQXNode{Node: "emqx@emqx-core-0.emqx-headless.default.svc.cluster.local", Role: "core"}
+	replicant := appsv2alpha2.EMQXNode{Node: "emqx@10.0.0.1", Role: "replicant"}
+
+	assert.True(t, isEMQXNodeRunningInPod(core, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "emqx-core-0"}}))
+	assert.False(t, isEMQXNodeRunningInPod(core, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "emqx-core-1"}}))
+	assert.True(t, isEMQXNodeRunningInPod(replicant, &corev1.Pod{Status: corev1.PodStatus{PodIP: "10.0.0.1"}}))
+	assert.False(t, isEMQXNodeRunningInPod(replicant, &corev1.Pod{}))
+}
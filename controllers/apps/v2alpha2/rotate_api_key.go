@@ -0,0 +1,142 @@
+package v2alpha2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	emperror "emperror.dev/errors"
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// operatorAPIKeyKey is the key of the bootstrap user secret holding the API key the operator uses,
+// the secret has no such key before the first rotation, and DefaultBootstrapAPIKey is used.
+const operatorAPIKeyKey = "operator_api_key"
+
+// rotateAPIKey replaces the API key the operator uses to request the EMQX management API when the
+// apps.emqx.io/rotate-api-key annotation changes. The new API key is created by the API and written to the
+// bootstrap user secret, so the EMQX nodes restarted later still have it, then the old API key is revoked.
+type rotateAPIKey struct {
+	*EMQXReconciler
+}
+
+func (a *rotateAPIKey) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface) subResult {
+	trigger, ok := instance.Annotations[appsv2alpha2.RotateAPIKeyAnnotationKey]
+	if !ok || r == nil {
+		return subResult{}
+	}
+	if instance.Status.APIKeyRotation != nil && instance.Status.APIKeyRotation.Trigger == trigger {
+		return subResult{}
+	}
+
+	secret := &corev1.Secret{}
+	if err := a.Client.Get(ctx, instance.BootstrapUserNamespacedName(), secret); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to get bootstrap user secret")}
+	}
+
+	name := fmt.Sprintf("%s-%d", appsv2alpha2.DefaultBootstrapAPIKey, time.Now().Unix())
+	created, err := emqxapi.NewV5(r).CreateAPIKey(ctx, emqxapi.APIKey{
+		Name:   name,
+		Desc:   "Created by EMQX operator to request the management API",
+		Enable: true,
+	})
+	if err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to create API key")}
+	}
+
+	secret.Data["bootstrap_user"] = []byte(replaceBootstrapUser(
+		string(secret.Data["bootstrap_user"]), getOperatorAPIKey(secret), created.APIKey, created.APISecret,
+	))
+	secret.Data[operatorAPIKeyKey] = []byte(created.APIKey)
+	if err := a.Client.Update(ctx, secret); err != nil {
+		// The secret still has the old API key, the new one would never be used
+		_ = emqxapi.NewV5(r).DeleteAPIKey(ctx, name)
+		return subResult{err: emperror.Wrap(err, "failed to update bootstrap user secret")}
+	}
+
+	// From now on the operator uses the new API key, the old one is revoked only if the new one works,
+	// otherwise the rotation is retried by the next reconciliation and the stale API keys are revoked then.
+	newRequester := withAPIKey(r, created.APIKey, created.APISecret)
+	if _, err := emqxapi.NewV5(newRequester).Nodes(ctx); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to request the management API by the new API key")}
+	}
+	if err := revokeStaleAPIKeys(ctx, newRequester, created.APIKey); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to revoke the old API key")}
+	}
+
+	instance.Status.APIKeyRotation = &appsv2alpha2.APIKeyRotationStatus{
+		Name:             name,
+		Trigger:          trigger,
+		LastRotationTime: metav1.Now(),
+	}
+	a.EventRecorder.Event(instance, corev1.EventTypeNormal, "APIKeyRotated", fmt.Sprintf("Rotated the API key of the operator to %s", name))
+	if err := a.Client.Status().Update(ctx, instance); err != nil {
+		return subResult{err: emperror.Wrap(err, "failed to update status")}
+	}
+	// The requester of this reconciliation has the revoked API key
+	return subResult{result: ctrl.Result{Requeue: true}}
+}
+
+// getOperatorAPIKey returns the API key the operator uses in the bootstrap user secret
+func getOperatorAPIKey(secret *corev1.Secret) string {
+	if key, ok := secret.Data[operatorAPIKeyKey]; ok && len(key) > 0 {
+		return string(key)
+	}
+	return appsv2alpha2.DefaultBootstrapAPIKey
+}
+
+// replaceBootstrapUser replaces the line of the old API key in the bootstrap file with the new API key and secret,
+// the new API key is appended if the old one is not found, the API keys of the user are kept as they are.
+func replaceBootstrapUser(bootstrapUser, oldKey, newKey, newSecret string) string {
+	users := []string{}
+	for _, user := range strings.Split(bootstrapUser, "\n") {
+		if user == "" || strings.HasPrefix(user, oldKey+":") {
+			continue
+		}
+		users = append(users, user)
+	}
+	return strings.Join(append(users, newKey+":"+newSecret), "\n")
+}
+
+// revokeStaleAPIKeys deletes the API keys of the operator except the current one, which are the API key from the
+// bootstrap file and the ones created by the previous rotations, including those left by an interrupted rotation.
+func revokeStaleAPIKeys(ctx context.Context, r innerReq.RequesterInterface, current string) error {
+	c := emqxapi.NewV5(r)
+	keys, err := c.APIKeys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.APIKey == current {
+			continue
+		}
+		if key.APIKey != appsv2alpha2.DefaultBootstrapAPIKey && !strings.HasPrefix(key.Name, appsv2alpha2.DefaultBootstrapAPIKey+"-") {
+			continue
+		}
+		if err := c.DeleteAPIKey(ctx, key.Name); err != nil && !emqxapi.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// withAPIKey returns a copy of the requester authorized by the API key
+func withAPIKey(r innerReq.RequesterInterface, key, secret string) innerReq.RequesterInterface {
+	switch r := r.(type) {
+	case *innerReq.Pool:
+		requester := r.Requester
+		requester.Username, requester.Password = key, secret
+		return &innerReq.Pool{Requester: requester, Endpoints: r.Endpoints, HealthCheckPath: r.HealthCheckPath}
+	case *innerReq.Requester:
+		requester := *r
+		requester.Username, requester.Password = key, secret
+		return &requester
+	}
+	return r
+}
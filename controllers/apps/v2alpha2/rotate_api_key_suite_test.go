@@ -0,0 +1,87 @@
+package v2alpha2
+
+import (
+	"context"
+	"strings"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/emqx/emqx-operator/internal/emqxapi/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Check rotate API key controller", Ordered, Label("api-key"), func() {
+	var a *rotateAPIKey
+	var s *fake.Server
+	var instance *appsv2alpha2.EMQX = new(appsv2alpha2.EMQX)
+	var ns *corev1.Namespace = &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "controller-v2alpha2-rotate-api-key-test",
+			Labels: map[string]string{
+				"test": "e2e",
+			},
+		},
+	}
+
+	BeforeAll(func() {
+		a = &rotateAPIKey{emqxReconciler}
+		s = fake.NewServer()
+		s.AddNode(fake.Node{Name: "emqx@emqx-core-0.emqx-headless.fake", Role: "core", Version: "5.1.0"})
+		s.SetBasicAuth(appsv2alpha2.DefaultBootstrapAPIKey, "secret")
+
+		instance = emqx.DeepCopy()
+		instance.Namespace = ns.Name
+		instance.Annotations = map[string]string{appsv2alpha2.RotateAPIKeyAnnotationKey: "1"}
+		Expect(k8sClient.Create(context.TODO(), ns)).Should(Succeed())
+		Expect(k8sClient.Create(ctx, instance)).Should(Succeed())
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      instance.BootstrapUserNamespacedName().Name,
+				Namespace: instance.Namespace,
+			},
+			StringData: map[string]string{
+				"bootstrap_user": "foo:bar\n" + appsv2alpha2.DefaultBootstrapAPIKey + ":secret",
+			},
+		})).Should(Succeed())
+	})
+
+	AfterAll(func() {
+		s.Close()
+		Expect(k8sClient.Delete(ctx, ns)).Should(Succeed())
+	})
+
+	It("should rotate the API key", func() {
+		Eventually(func() subResult {
+			return a.reconcile(ctx, instance, s.Requester())
+		}, timeout, interval).Should(Equal(subResult{result: ctrl.Result{Requeue: true}}))
+
+		Expect(instance.Status.APIKeyRotation).ShouldNot(BeNil())
+		Expect(instance.Status.APIKeyRotation.Trigger).Should(Equal("1"))
+		Expect(s.APIKeys()).Should(Equal([]string{instance.Status.APIKeyRotation.Name}))
+
+		username, password, err := getBootstrapUser(ctx, k8sClient, instance)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(username).ShouldNot(Equal(appsv2alpha2.DefaultBootstrapAPIKey))
+
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, instance.BootstrapUserNamespacedName(), secret)).Should(Succeed())
+		Expect(strings.Split(string(secret.Data["bootstrap_user"]), "\n")).Should(Equal([]string{"foo:bar", username + ":" + password}))
+
+		_, err = emqxapi.NewV5(s.Requester()).Nodes(ctx)
+		Expect(emqxapi.IsCode(err, "BAD_API_KEY_OR_SECRET")).Should(BeTrue())
+		_, err = emqxapi.NewV5(withAPIKey(s.Requester(), username, password)).Nodes(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("should not rotate the API key again with the same annotation", func() {
+		username, password, err := getBootstrapUser(ctx, k8sClient, instance)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(a.reconcile(ctx, instance, withAPIKey(s.Requester(), username, password))).Should(Equal(subResult{}))
+		Expect(s.APIKeys()).Should(HaveLen(1))
+	})
+})
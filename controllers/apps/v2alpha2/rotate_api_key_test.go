@@ -0,0 +1,79 @@
+package v2alpha2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/emqx/emqx-operator/internal/emqxapi/fake"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetOperatorAPIKey(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{"bootstrap_user": []byte("emqx-operator-controller:secret")}}
+	assert.Equal(t, "emqx-operator-controller", getOperatorAPIKey(secret))
+
+	secret.Data["operator_api_key"] = []byte("rotated")
+	assert.Equal(t, "rotated", getOperatorAPIKey(secret))
+}
+
+func TestReplaceBootstrapUser(t *testing.T) {
+	t.Run("replace the operator API key", func(t *testing.T) {
+		got := replaceBootstrapUser("foo:bar\nemqx-operator-controller:secret", "emqx-operator-controller", "new", "new-secret")
+		assert.Equal(t, "foo:bar\nnew:new-secret", got)
+	})
+
+	t.Run("keep the API keys with the same prefix", func(t *testing.T) {
+		got := replaceBootstrapUser("emqx-operator-controller-user:bar\nemqx-operator-controller:secret\n", "emqx-operator-controller", "new", "new-secret")
+		assert.Equal(t, "emqx-operator-controller-user:bar\nnew:new-secret", got)
+	})
+
+	t.Run("old API key not found", func(t *testing.T) {
+		got := replaceBootstrapUser("foo:bar", "emqx-operator-controller", "new", "new-secret")
+		assert.Equal(t, "foo:bar\nnew:new-secret", got)
+	})
+}
+
+func TestRevokeStaleAPIKeys(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+	s.AddNode(fake.Node{Name: "emqx@10.0.0.1"})
+	s.SetBasicAuth("emqx-operator-controller", "secret")
+
+	c := emqxapi.NewV5(s.Requester())
+	for _, name := range []string{"emqx-operator-controller-1", "emqx-operator-controller-2", "user"} {
+		_, err := c.CreateAPIKey(context.Background(), emqxapi.APIKey{Name: name, Enable: true})
+		assert.Nil(t, err)
+	}
+	current, err := c.CreateAPIKey(context.Background(), emqxapi.APIKey{Name: "emqx-operator-controller-3", Enable: true})
+	assert.Nil(t, err)
+
+	r := withAPIKey(s.Requester(), current.APIKey, current.APISecret)
+	assert.Nil(t, revokeStaleAPIKeys(context.Background(), r, current.APIKey))
+	assert.Equal(t, []string{"user", "emqx-operator-controller-3"}, s.APIKeys())
+}
+
+func TestWithAPIKey(t *testing.T) {
+	t.Run("requester", func(t *testing.T) {
+		requester := &innerReq.Requester{Host: "10.0.0.1:18083", Username: "old", Password: "old-secret"}
+		got := withAPIKey(requester, "new", "new-secret")
+		assert.Equal(t, &innerReq.Requester{Host: "10.0.0.1:18083", Username: "new", Password: "new-secret"}, got)
+		assert.Equal(t, "old", requester.Username)
+	})
+
+	t.Run("pool", func(t *testing.T) {
+		pool := &innerReq.Pool{
+			Requester:       innerReq.Requester{Scheme: "https", Username: "old", Password: "old-secret"},
+			Endpoints:       []innerReq.Endpoint{{Name: "emqx-core-0", Host: "10.0.0.1:18084"}},
+			HealthCheckPath: "status",
+		}
+		got := withAPIKey(pool, "new", "new-secret")
+		assert.Equal(t, "https", got.GetScheme())
+		assert.Equal(t, "new", got.GetUsername())
+		assert.Equal(t, "new-secret", got.GetPassword())
+		assert.Equal(t, "10.0.0.1:18084", got.GetHost())
+		assert.Equal(t, "old", pool.GetUsername())
+	})
+}
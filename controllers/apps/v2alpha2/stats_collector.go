@@ -0,0 +1,112 @@
+package v2alpha2
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/emqx/emqx-operator/internal/metrics"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// statsCollectInterval is the minimum interval to collect the statistics of an EMQX cluster,
+// the rates are computed from the counters of two collections, so they must not be too close.
+const statsCollectInterval = 15 * time.Second
+
+type statsSample struct {
+	received  int64
+	sent      int64
+	dropped   int64
+	timestamp time.Time
+}
+
+// statsCollector collects the cluster-wide statistics of the EMQX clusters periodically,
+// it keeps the last message counters of each cluster to compute the message rates.
+type statsCollector struct {
+	mu      sync.Mutex
+	samples map[types.NamespacedName]statsSample
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{
+		samples: map[types.NamespacedName]statsSample{},
+	}
+}
+
+// collect returns the statistics of the EMQX cluster and exports them as metrics,
+// the statistics in the status are returned if they were collected in statsCollectInterval.
+func (c *statsCollector) collect(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface, now time.Time) (*appsv2alpha2.EMQXStats, error) {
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+
+	c.mu.Lock()
+	last, ok := c.samples[key]
+	c.mu.Unlock()
+	if ok && instance.Status.Stats != nil && now.Sub(last.timestamp) < statsCollectInterval {
+		return instance.Status.Stats, nil
+	}
+
+	api := emqxapi.NewV5(r)
+	counters, err := api.Metrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := api.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sample := statsSample{
+		received:  counters["messages.received"],
+		sent:      counters["messages.sent"],
+		dropped:   counters["messages.dropped"],
+		timestamp: now,
+	}
+
+	c.mu.Lock()
+	c.samples[key] = sample
+	c.mu.Unlock()
+
+	clusterStats := metrics.ClusterStats{
+		Subscriptions:    stats["subscriptions.count"],
+		Topics:           stats["topics.count"],
+		RetainedMessages: stats["retained.count"],
+	}
+	// The rates are unknown until the counters are collected twice
+	if ok {
+		elapsed := now.Sub(last.timestamp)
+		clusterStats.MessagesReceivedRate = computeRate(last.received, sample.received, elapsed)
+		clusterStats.MessagesSentRate = computeRate(last.sent, sample.sent, elapsed)
+		clusterStats.MessagesDroppedRate = computeRate(last.dropped, sample.dropped, elapsed)
+	}
+	metrics.SetClusterStatsGauges(emqxKind, instance.Namespace, instance.Name, clusterStats)
+
+	return &appsv2alpha2.EMQXStats{
+		MessagesReceivedRate: int64(math.Round(clusterStats.MessagesReceivedRate)),
+		MessagesSentRate:     int64(math.Round(clusterStats.MessagesSentRate)),
+		MessagesDroppedRate:  int64(math.Round(clusterStats.MessagesDroppedRate)),
+		Subscriptions:        clusterStats.Subscriptions,
+		Topics:               clusterStats.Topics,
+		RetainedMessages:     clusterStats.RetainedMessages,
+		LastUpdateTime:       metav1.NewTime(now),
+	}, nil
+}
+
+// forget drops the last counters of the EMQX cluster
+func (c *statsCollector) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.samples, key)
+}
+
+// computeRate returns the per second rate of the counter, the counter is reset when the EMQX nodes restart,
+// the rate is 0 in that case.
+func computeRate(last, current int64, elapsed time.Duration) float64 {
+	if current < last || elapsed <= 0 {
+		return 0
+	}
+	return float64(current-last) / elapsed.Seconds()
+}
@@ -0,0 +1,78 @@
+package v2alpha2
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type fakeRequester struct {
+	request func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error)
+}
+
+func (f *fakeRequester) GetScheme() string                 { return "http" }
+func (f *fakeRequester) GetHost() string                   { return "" }
+func (f *fakeRequester) GetUsername() string               { return "" }
+func (f *fakeRequester) GetPassword() string               { return "" }
+func (f *fakeRequester) GetTLSConfig() *innerReq.TLSConfig { return nil }
+func (f *fakeRequester) Request(_ context.Context, method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+	return f.request(method, path, body)
+}
+
+func TestComputeRate(t *testing.T) {
+	assert.Equal(t, float64(10), computeRate(100, 300, 20*time.Second))
+	assert.Equal(t, float64(0), computeRate(300, 100, 20*time.Second))
+	assert.Equal(t, float64(0), computeRate(100, 300, 0))
+}
+
+func TestStatsCollector(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "emqx"}}
+	received := 100
+	f := &fakeRequester{
+		request: func(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+			if path == "api/v5/metrics?aggregate=true" {
+				received += 300
+				return &http.Response{StatusCode: http.StatusOK}, []byte(`{"messages.received":` + strconv.Itoa(received) + `}`), nil
+			}
+			return &http.Response{StatusCode: http.StatusOK}, []byte(`{"topics.count":3,"subscriptions.count":5,"retained.count":1}`), nil
+		},
+	}
+	c := newStatsCollector()
+	now := time.Now()
+
+	t.Run("first collection has no rates", func(t *testing.T) {
+		stats, err := c.collect(context.Background(), instance, f, now)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), stats.MessagesReceivedRate)
+		assert.Equal(t, int64(5), stats.Subscriptions)
+		assert.Equal(t, int64(3), stats.Topics)
+		assert.Equal(t, int64(1), stats.RetainedMessages)
+		instance.Status.Stats = stats
+	})
+
+	t.Run("too soon to collect again", func(t *testing.T) {
+		stats, err := c.collect(context.Background(), instance, f, now.Add(time.Second))
+		assert.Nil(t, err)
+		assert.Equal(t, instance.Status.Stats, stats)
+		assert.Equal(t, 400, received)
+	})
+
+	t.Run("second collection has rates", func(t *testing.T) {
+		stats, err := c.collect(context.Background(), instance, f, now.Add(30*time.Second))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(10), stats.MessagesReceivedRate)
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		c.forget(types.NamespacedName{Namespace: "default", Name: "emqx"})
+		assert.Empty(t, c.samples)
+	})
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package v2alpha2
 
 import (
+	"fmt"
+	"strings"
+
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,8 +40,14 @@ type emqxStatusMachine struct {
 	replicantNodesReady       status
 	available                 status
 	ready                     status
+	degraded                  status
 
 	currentStatus status
+
+	// The error of requesting the EMQX management API in this reconciliation, nil if the API is reachable
+	managementAPIErr error
+	// The number of the replicant pods not updated to the revision of the current replicaSet
+	outdatedReplicantPods int
 }
 
 func newEMQXStatusMachine(emqx *appsv2alpha2.EMQX) *emqxStatusMachine {
@@ -53,6 +62,7 @@ func newEMQXStatusMachine(emqx *appsv2alpha2.EMQX) *emqxStatusMachine {
 	replicantNodesReadyStatus := &replicantNodesReadyStatus{emqxStatusMachine: emqxStatusMachine}
 	availableStatus := &availableStatus{emqxStatusMachine: emqxStatusMachine}
 	readyStatus := &readyStatus{emqxStatusMachine: emqxStatusMachine}
+	degradedStatus := &degradedStatus{emqxStatusMachine: emqxStatusMachine}
 
 	emqxStatusMachine.initialized = initializedStatus
 	emqxStatusMachine.coreNodesProgressing = coreNodesProgressingStatus
@@ -61,6 +71,7 @@ func newEMQXStatusMachine(emqx *appsv2alpha2.EMQX) *emqxStatusMachine {
 	emqxStatusMachine.replicantNodesReady = replicantNodesReadyStatus
 	emqxStatusMachine.available = availableStatus
 	emqxStatusMachine.ready = readyStatus
+	emqxStatusMachine.degraded = degradedStatus
 	emqxStatusMachine.setCurrentStatus(emqx)
 
 	return emqxStatusMachine
@@ -91,6 +102,8 @@ func (s *emqxStatusMachine) setCurrentStatus(emqx *appsv2alpha2.EMQX) {
 		s.currentStatus = s.available
 	case appsv2alpha2.Ready:
 		s.currentStatus = s.ready
+	case appsv2alpha2.Degraded:
+		s.currentStatus = s.degraded
 	default:
 		s.currentStatus = s.initialized
 	}
@@ -117,12 +130,14 @@ func (s *initializedStatus) nextStatus(_ *appsv1.StatefulSet, _ *appsv1.ReplicaS
 
 	s.emqxStatusMachine.emqx.Status.RemoveCondition(appsv2alpha2.Available)
 	s.emqxStatusMachine.emqx.Status.RemoveCondition(appsv2alpha2.Ready)
+	s.emqxStatusMachine.emqx.Status.RemoveCondition(appsv2alpha2.Degraded)
 
 	s.emqxStatusMachine.emqx.Status.SetCondition(metav1.Condition{
-		Type:    appsv2alpha2.CoreNodesProgressing,
-		Status:  metav1.ConditionTrue,
-		Reason:  "CoreNodesProgressing",
-		Message: "Core nodes progressing",
+		Type:               appsv2alpha2.CoreNodesProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             "CoreNodesProgressing",
+		Message:            "Core nodes progressing",
+		ObservedGeneration: s.emqxStatusMachine.emqx.Generation,
 	})
 	s.emqxStatusMachine.setCurrentStatus(s.emqxStatusMachine.emqx)
 }
@@ -139,16 +154,22 @@ func (s *coreNodesProgressingStatus) nextStatus(currentSts *appsv1.StatefulSet,
 		return
 	}
 
+	// all pods are updated to the latest pod template when rolling update
+	if isRollingUpdate(s.emqxStatusMachine.emqx) && currentSts.Status.UpdatedReplicas != currentSts.Status.Replicas {
+		return
+	}
+
 	// core nodes is ready
 	if s.emqxStatusMachine.emqx.Status.CoreNodesStatus.ReadyReplicas < s.emqxStatusMachine.emqx.Status.CoreNodesStatus.Replicas {
 		return
 	}
 
 	s.emqxStatusMachine.emqx.Status.SetCondition(metav1.Condition{
-		Type:    appsv2alpha2.CoreNodesReady,
-		Status:  metav1.ConditionTrue,
-		Reason:  "CoreNodesReady",
-		Message: "Core nodes is ready",
+		Type:               appsv2alpha2.CoreNodesReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "CoreNodesReady",
+		Message:            "Core nodes is ready",
+		ObservedGeneration: s.emqxStatusMachine.emqx.Generation,
 	})
 	s.emqxStatusMachine.setCurrentStatus(s.emqxStatusMachine.emqx)
 }
@@ -160,20 +181,22 @@ type codeNodesReadyStatus struct {
 func (s *codeNodesReadyStatus) nextStatus(currentSts *appsv1.StatefulSet, currentRs *appsv1.ReplicaSet) {
 	if isExistReplicant(s.emqxStatusMachine.emqx) {
 		s.emqxStatusMachine.emqx.Status.SetCondition(metav1.Condition{
-			Type:    appsv2alpha2.ReplicantNodesProgressing,
-			Status:  metav1.ConditionTrue,
-			Reason:  appsv2alpha2.ReplicantNodesProgressing,
-			Message: "Replicant nodes progressing",
+			Type:               appsv2alpha2.ReplicantNodesProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             appsv2alpha2.ReplicantNodesProgressing,
+			Message:            "Replicant nodes progressing",
+			ObservedGeneration: s.emqxStatusMachine.emqx.Generation,
 		})
 		s.emqxStatusMachine.setCurrentStatus(s.emqxStatusMachine.emqx)
 		return
 	}
 
 	s.emqxStatusMachine.emqx.Status.SetCondition(metav1.Condition{
-		Type:    appsv2alpha2.Available,
-		Status:  metav1.ConditionTrue,
-		Reason:  appsv2alpha2.Available,
-		Message: "Cluster is available",
+		Type:               appsv2alpha2.Available,
+		Status:             metav1.ConditionTrue,
+		Reason:             appsv2alpha2.Available,
+		Message:            "Cluster is available",
+		ObservedGeneration: s.emqxStatusMachine.emqx.Generation,
 	})
 	s.emqxStatusMachine.setCurrentStatus(s.emqxStatusMachine.emqx)
 }
@@ -193,16 +216,22 @@ func (s *replicantNodesProgressingStatus) nextStatus(currentSts *appsv1.Stateful
 		return
 	}
 
+	// all pods are updated to the latest pod template when rolling update
+	if isRollingUpdate(s.emqxStatusMachine.emqx) && s.emqxStatusMachine.outdatedReplicantPods > 0 {
+		return
+	}
+
 	// replicant nodes is ready
 	if s.emqxStatusMachine.emqx.Status.ReplicantNodesStatus.ReadyReplicas < s.emqxStatusMachine.emqx.Status.ReplicantNodesStatus.Replicas {
 		return
 	}
 
 	s.emqxStatusMachine.emqx.Status.SetCondition(metav1.Condition{
-		Type:    appsv2alpha2.ReplicantNodesReady,
-		Status:  metav1.ConditionTrue,
-		Reason:  appsv2alpha2.ReplicantNodesReady,
-		Message: "Replicant nodes ready",
+		Type:               appsv2alpha2.ReplicantNodesReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             appsv2alpha2.ReplicantNodesReady,
+		Message:            "Replicant nodes ready",
+		ObservedGeneration: s.emqxStatusMachine.emqx.Generation,
 	})
 	s.emqxStatusMachine.setCurrentStatus(s.emqxStatusMachine.emqx)
 }
@@ -218,10 +247,11 @@ func (s *replicantNodesReadyStatus) nextStatus(currentSts *appsv1.StatefulSet, c
 	}
 
 	s.emqxStatusMachine.emqx.Status.SetCondition(metav1.Condition{
-		Type:    appsv2alpha2.Available,
-		Status:  metav1.ConditionTrue,
-		Reason:  appsv2alpha2.Available,
-		Message: "Cluster is available",
+		Type:               appsv2alpha2.Available,
+		Status:             metav1.ConditionTrue,
+		Reason:             appsv2alpha2.Available,
+		Message:            "Cluster is available",
+		ObservedGeneration: s.emqxStatusMachine.emqx.Generation,
 	})
 	s.emqxStatusMachine.setCurrentStatus(s.emqxStatusMachine.emqx)
 }
@@ -231,6 +261,11 @@ type availableStatus struct {
 }
 
 func (s *availableStatus) nextStatus(currentSts *appsv1.StatefulSet, currentRs *appsv1.ReplicaSet) {
+	if reason, message, available := s.emqxStatusMachine.checkHealth(currentSts, currentRs); reason != "" {
+		s.emqxStatusMachine.degrade(reason, message, available)
+		return
+	}
+
 	if s.emqxStatusMachine.emqx.Status.CoreNodesStatus.ReadyReplicas != s.emqxStatusMachine.emqx.Status.CoreNodesStatus.Replicas {
 		return
 	}
@@ -240,11 +275,15 @@ func (s *availableStatus) nextStatus(currentSts *appsv1.StatefulSet, currentRs *
 		}
 	}
 
+	if s.emqxStatusMachine.emqx.Status.IsConditionTrue(appsv2alpha2.Degraded) {
+		s.emqxStatusMachine.recover()
+	}
 	s.emqxStatusMachine.emqx.Status.SetCondition(metav1.Condition{
-		Type:    appsv2alpha2.Ready,
-		Status:  metav1.ConditionTrue,
-		Reason:  appsv2alpha2.Ready,
-		Message: "Cluster is ready",
+		Type:               appsv2alpha2.Ready,
+		Status:             metav1.ConditionTrue,
+		Reason:             appsv2alpha2.Ready,
+		Message:            "Cluster is ready",
+		ObservedGeneration: s.emqxStatusMachine.emqx.Generation,
 	})
 	s.emqxStatusMachine.setCurrentStatus(s.emqxStatusMachine.emqx)
 }
@@ -253,4 +292,147 @@ type readyStatus struct {
 	emqxStatusMachine *emqxStatusMachine
 }
 
-func (s *readyStatus) nextStatus(currentSts *appsv1.StatefulSet, currentRs *appsv1.ReplicaSet) {}
+func (s *readyStatus) nextStatus(currentSts *appsv1.StatefulSet, currentRs *appsv1.ReplicaSet) {
+	if reason, message, available := s.emqxStatusMachine.checkHealth(currentSts, currentRs); reason != "" {
+		s.emqxStatusMachine.degrade(reason, message, available)
+	}
+}
+
+type degradedStatus struct {
+	emqxStatusMachine *emqxStatusMachine
+}
+
+func (s *degradedStatus) nextStatus(currentSts *appsv1.StatefulSet, currentRs *appsv1.ReplicaSet) {
+	if reason, message, available := s.emqxStatusMachine.checkHealth(currentSts, currentRs); reason != "" {
+		s.emqxStatusMachine.degrade(reason, message, available)
+		return
+	}
+
+	s.emqxStatusMachine.recover()
+	if !s.emqxStatusMachine.emqx.Status.IsConditionTrue(appsv2alpha2.Available) {
+		s.emqxStatusMachine.emqx.Status.SetCondition(metav1.Condition{
+			Type:               appsv2alpha2.Available,
+			Status:             metav1.ConditionTrue,
+			Reason:             appsv2alpha2.Available,
+			Message:            "Cluster is available",
+			ObservedGeneration: s.emqxStatusMachine.emqx.Generation,
+		})
+	}
+	s.emqxStatusMachine.setCurrentStatus(s.emqxStatusMachine.emqx)
+	s.emqxStatusMachine.currentStatus.nextStatus(currentSts, currentRs)
+}
+
+// checkHealth checks whether the EMQX cluster that has been available is still healthy, it returns an empty reason if so.
+// available is false when the cluster can not serve anymore, which is only decided by the readiness of the core pods,
+// the unreachable management API says nothing about whether the MQTT clients are served.
+func (s *emqxStatusMachine) checkHealth(currentSts *appsv1.StatefulSet, currentRs *appsv1.ReplicaSet) (reason, message string, available bool) {
+	emqx := s.emqx
+
+	if currentSts.UID == "" {
+		return appsv2alpha2.CoreNodesNotReadyReason, "Core nodes are not ready, the statefulSet is not found", false
+	}
+	if currentSts.Status.ReadyReplicas < *currentSts.Spec.Replicas {
+		return appsv2alpha2.CoreNodesNotReadyReason, fmt.Sprintf("Core nodes are not ready, %d/%d pods are ready", currentSts.Status.ReadyReplicas, *currentSts.Spec.Replicas), false
+	}
+
+	// The nodes in the status are not refreshed if the management API is unreachable
+	if s.managementAPIErr != nil {
+		return appsv2alpha2.ManagementAPIUnreachableReason, fmt.Sprintf("Failed to request the EMQX management API: %s", s.managementAPIErr.Error()), true
+	}
+	if message := getNodesNotInClusterMessage(emqx.Status.CoreNodesStatus, *currentSts.Spec.Replicas); message != "" {
+		return appsv2alpha2.NodeNotInClusterReason, "Core nodes are not in the cluster, " + message, true
+	}
+
+	if isExistReplicant(emqx) {
+		if currentRs.UID == "" {
+			return appsv2alpha2.ReplicantNodesNotReadyReason, "Replicant nodes are not ready, the replicaSet is not found", true
+		}
+		if currentRs.Status.ReadyReplicas < *currentRs.Spec.Replicas {
+			return appsv2alpha2.ReplicantNodesNotReadyReason, fmt.Sprintf("Replicant nodes are not ready, %d/%d pods are ready", currentRs.Status.ReadyReplicas, *currentRs.Spec.Replicas), true
+		}
+		if emqx.Status.ReplicantNodesStatus != nil {
+			if message := getNodesNotInClusterMessage(*emqx.Status.ReplicantNodesStatus, *currentRs.Spec.Replicas); message != "" {
+				return appsv2alpha2.NodeNotInClusterReason, "Replicant nodes are not in the cluster, " + message, true
+			}
+		}
+	}
+
+	return "", "", true
+}
+
+// getNodesNotInClusterMessage describes the EMQX nodes that are expected but not running in the cluster, it returns an empty string if there is none.
+func getNodesNotInClusterMessage(status appsv2alpha2.EMQXNodesStatus, replicas int32) string {
+	var stopped []string
+	for _, node := range status.Nodes {
+		if node.NodeStatus != "running" {
+			stopped = append(stopped, node.Node)
+		}
+	}
+
+	var details []string
+	if running := status.ReadyReplicas - int32(len(stopped)); running < replicas {
+		details = append(details, fmt.Sprintf("%d/%d nodes are running", running, replicas))
+	}
+	if len(stopped) > 0 {
+		details = append(details, fmt.Sprintf("stopped nodes: %s", strings.Join(stopped, ", ")))
+	}
+	if len(status.PodsNotInCluster) > 0 {
+		details = append(details, fmt.Sprintf("pods not in cluster: %s", strings.Join(status.PodsNotInCluster, ", ")))
+	}
+	return strings.Join(details, "; ")
+}
+
+// degrade moves the EMQX cluster to the degraded status, the Ready condition, and the Available condition if the cluster
+// can not serve, are set to False with the same reason.
+func (s *emqxStatusMachine) degrade(reason, message string, available bool) {
+	emqx := s.emqx
+
+	_, degraded := emqx.Status.GetCondition(appsv2alpha2.Degraded)
+	if degraded != nil && degraded.Status == metav1.ConditionTrue &&
+		degraded.Reason == reason && degraded.Message == message && degraded.ObservedGeneration == emqx.Generation &&
+		emqx.Status.IsConditionTrue(appsv2alpha2.Available) == available {
+		return
+	}
+
+	if emqx.Status.IsConditionTrue(appsv2alpha2.Available) != available {
+		condition := metav1.Condition{
+			Type:               appsv2alpha2.Available,
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: emqx.Generation,
+		}
+		if available {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = appsv2alpha2.Available
+			condition.Message = "Cluster is available"
+		}
+		emqx.Status.SetCondition(condition)
+	}
+	emqx.Status.SetCondition(metav1.Condition{
+		Type:               appsv2alpha2.Ready,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: emqx.Generation,
+	})
+	emqx.Status.SetCondition(metav1.Condition{
+		Type:               appsv2alpha2.Degraded,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: emqx.Generation,
+	})
+	s.setCurrentStatus(emqx)
+}
+
+// recover marks the EMQX cluster as not degraded
+func (s *emqxStatusMachine) recover() {
+	s.emqx.Status.SetCondition(metav1.Condition{
+		Type:               appsv2alpha2.Degraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Recovered",
+		Message:            "Cluster is recovered",
+		ObservedGeneration: s.emqx.Generation,
+	})
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v2alpha2
 
 import (
+	"errors"
 	"testing"
 
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
@@ -371,6 +372,30 @@ func TestNextStatusForReplicantNodeProgressing(t *testing.T) {
 		assert.Equal(t, appsv2alpha2.ReplicantNodesProgressing, emqxStatusMachine.GetEMQX().Status.Conditions[0].Type)
 	})
 
+	t.Run("still status when replicant pods are outdated during rolling update", func(t *testing.T) {
+		sts := currentSts.DeepCopy()
+		rs := currentRs.DeepCopy()
+		emqx := instance.DeepCopy()
+		emqx.Spec.UpdateStrategy.Type = appsv2alpha2.RollingUpdateStrategyType
+		emqx.Status.Conditions = []metav1.Condition{
+			{
+				Type:   appsv2alpha2.ReplicantNodesProgressing,
+				Status: metav1.ConditionTrue,
+			},
+		}
+		emqxStatusMachine := newEMQXStatusMachine(emqx)
+
+		emqxStatusMachine.outdatedReplicantPods = 1
+		emqxStatusMachine.NextStatus(sts, rs)
+		assert.Equal(t, emqxStatusMachine.replicantNodesProgressing, emqxStatusMachine.currentStatus)
+		assert.Equal(t, appsv2alpha2.ReplicantNodesProgressing, emqxStatusMachine.GetEMQX().Status.Conditions[0].Type)
+
+		emqxStatusMachine.outdatedReplicantPods = 0
+		emqxStatusMachine.NextStatus(sts, rs)
+		assert.Equal(t, emqxStatusMachine.replicantNodesReady, emqxStatusMachine.currentStatus)
+		assert.Equal(t, appsv2alpha2.ReplicantNodesReady, emqxStatusMachine.GetEMQX().Status.Conditions[0].Type)
+	})
+
 	t.Run("next status", func(t *testing.T) {
 		sts := currentSts.DeepCopy()
 		rs := currentRs.DeepCopy()
@@ -475,3 +500,152 @@ func TestNextStatusForAvailable(t *testing.T) {
 		assert.Equal(t, appsv2alpha2.Ready, emqxStatusMachine.GetEMQX().Status.Conditions[0].Type)
 	})
 }
+
+func TestNextStatusForReady(t *testing.T) {
+	newReadyEMQX := func() *appsv2alpha2.EMQX {
+		emqx := instance.DeepCopy()
+		emqx.Generation = 2
+		emqx.Status.Conditions = []metav1.Condition{
+			{Type: appsv2alpha2.Ready, Status: metav1.ConditionTrue},
+			{Type: appsv2alpha2.Available, Status: metav1.ConditionTrue},
+		}
+		return emqx
+	}
+
+	t.Run("still status when cluster is healthy", func(t *testing.T) {
+		emqxStatusMachine := newEMQXStatusMachine(newReadyEMQX())
+		emqxStatusMachine.NextStatus(currentSts.DeepCopy(), currentRs.DeepCopy())
+		assert.Equal(t, emqxStatusMachine.ready, emqxStatusMachine.currentStatus)
+		assert.False(t, emqxStatusMachine.GetEMQX().Status.IsConditionTrue(appsv2alpha2.Degraded))
+	})
+
+	t.Run("core nodes not ready", func(t *testing.T) {
+		sts := currentSts.DeepCopy()
+		sts.Status.ReadyReplicas = 2
+		emqxStatusMachine := newEMQXStatusMachine(newReadyEMQX())
+		emqxStatusMachine.NextStatus(sts, currentRs.DeepCopy())
+		assert.Equal(t, emqxStatusMachine.degraded, emqxStatusMachine.currentStatus)
+
+		status := emqxStatusMachine.GetEMQX().Status
+		assert.Equal(t, appsv2alpha2.Degraded, status.Conditions[0].Type)
+		for _, conditionType := range []string{appsv2alpha2.Degraded, appsv2alpha2.Ready, appsv2alpha2.Available} {
+			_, condition := status.GetCondition(conditionType)
+			assert.Equal(t, appsv2alpha2.CoreNodesNotReadyReason, condition.Reason)
+			assert.Equal(t, int64(2), condition.ObservedGeneration)
+		}
+		assert.False(t, status.IsConditionTrue(appsv2alpha2.Ready))
+		assert.False(t, status.IsConditionTrue(appsv2alpha2.Available))
+	})
+
+	t.Run("replicant nodes not ready", func(t *testing.T) {
+		rs := currentRs.DeepCopy()
+		rs.Status.ReadyReplicas = 2
+		emqxStatusMachine := newEMQXStatusMachine(newReadyEMQX())
+		emqxStatusMachine.NextStatus(currentSts.DeepCopy(), rs)
+		assert.Equal(t, emqxStatusMachine.degraded, emqxStatusMachine.currentStatus)
+
+		status := emqxStatusMachine.GetEMQX().Status
+		_, condition := status.GetCondition(appsv2alpha2.Degraded)
+		assert.Equal(t, appsv2alpha2.ReplicantNodesNotReadyReason, condition.Reason)
+		assert.False(t, status.IsConditionTrue(appsv2alpha2.Ready))
+		assert.True(t, status.IsConditionTrue(appsv2alpha2.Available))
+	})
+
+	t.Run("node not in cluster", func(t *testing.T) {
+		emqx := newReadyEMQX()
+		emqx.Status.CoreNodesStatus.ReadyReplicas = 2
+		emqxStatusMachine := newEMQXStatusMachine(emqx)
+		emqxStatusMachine.NextStatus(currentSts.DeepCopy(), currentRs.DeepCopy())
+		assert.Equal(t, emqxStatusMachine.degraded, emqxStatusMachine.currentStatus)
+
+		_, condition := emqxStatusMachine.GetEMQX().Status.GetCondition(appsv2alpha2.Degraded)
+		assert.Equal(t, appsv2alpha2.NodeNotInClusterReason, condition.Reason)
+		assert.Equal(t, "Core nodes are not in the cluster, 2/3 nodes are running", condition.Message)
+	})
+
+	t.Run("stopped node and pod not in cluster", func(t *testing.T) {
+		emqx := newReadyEMQX()
+		emqx.Status.ReplicantNodesStatus.Nodes = []appsv2alpha2.EMQXNode{
+			{Node: "emqx@10.0.0.1", NodeStatus: "running"},
+			{Node: "emqx@10.0.0.2", NodeStatus: "running"},
+			{Node: "emqx@10.0.0.3", NodeStatus: "stopped"},
+		}
+		emqx.Status.ReplicantNodesStatus.PodsNotInCluster = []string{"emqx-replicant-a"}
+		emqxStatusMachine := newEMQXStatusMachine(emqx)
+		emqxStatusMachine.NextStatus(currentSts.DeepCopy(), currentRs.DeepCopy())
+
+		_, condition := emqxStatusMachine.GetEMQX().Status.GetCondition(appsv2alpha2.Degraded)
+		assert.Equal(t, appsv2alpha2.NodeNotInClusterReason, condition.Reason)
+		assert.Equal(t, "Replicant nodes are not in the cluster, 2/3 nodes are running; stopped nodes: emqx@10.0.0.3; pods not in cluster: emqx-replicant-a", condition.Message)
+	})
+
+	t.Run("management API unreachable", func(t *testing.T) {
+		emqxStatusMachine := newEMQXStatusMachine(newReadyEMQX())
+		emqxStatusMachine.managementAPIErr = errors.New("connection refused")
+		emqxStatusMachine.NextStatus(currentSts.DeepCopy(), currentRs.DeepCopy())
+		assert.Equal(t, emqxStatusMachine.degraded, emqxStatusMachine.currentStatus)
+
+		status := emqxStatusMachine.GetEMQX().Status
+		_, condition := status.GetCondition(appsv2alpha2.Degraded)
+		assert.Equal(t, appsv2alpha2.ManagementAPIUnreachableReason, condition.Reason)
+		assert.True(t, status.IsConditionTrue(appsv2alpha2.Available))
+	})
+
+	t.Run("management API unreachable and core nodes not ready", func(t *testing.T) {
+		sts := currentSts.DeepCopy()
+		sts.Status.ReadyReplicas = 2
+		emqxStatusMachine := newEMQXStatusMachine(newReadyEMQX())
+		emqxStatusMachine.managementAPIErr = errors.New("connection refused")
+		emqxStatusMachine.NextStatus(sts, currentRs.DeepCopy())
+
+		status := emqxStatusMachine.GetEMQX().Status
+		_, condition := status.GetCondition(appsv2alpha2.Available)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, appsv2alpha2.CoreNodesNotReadyReason, condition.Reason)
+	})
+}
+
+func TestNextStatusForDegraded(t *testing.T) {
+	newDegradedEMQX := func() *appsv2alpha2.EMQX {
+		emqx := instance.DeepCopy()
+		emqx.Status.Conditions = []metav1.Condition{
+			{Type: appsv2alpha2.Degraded, Status: metav1.ConditionTrue, Reason: appsv2alpha2.CoreNodesNotReadyReason},
+			{Type: appsv2alpha2.Ready, Status: metav1.ConditionFalse, Reason: appsv2alpha2.CoreNodesNotReadyReason},
+			{Type: appsv2alpha2.Available, Status: metav1.ConditionFalse, Reason: appsv2alpha2.CoreNodesNotReadyReason},
+			{Type: appsv2alpha2.ReplicantNodesReady, Status: metav1.ConditionTrue},
+		}
+		return emqx
+	}
+
+	t.Run("still status when cluster is not healthy", func(t *testing.T) {
+		sts := currentSts.DeepCopy()
+		sts.Status.ReadyReplicas = 2
+		emqxStatusMachine := newEMQXStatusMachine(newDegradedEMQX())
+		emqxStatusMachine.NextStatus(sts, currentRs.DeepCopy())
+		assert.Equal(t, emqxStatusMachine.degraded, emqxStatusMachine.currentStatus)
+	})
+
+	t.Run("degradation changes", func(t *testing.T) {
+		rs := currentRs.DeepCopy()
+		rs.Status.ReadyReplicas = 2
+		emqxStatusMachine := newEMQXStatusMachine(newDegradedEMQX())
+		emqxStatusMachine.NextStatus(currentSts.DeepCopy(), rs)
+		assert.Equal(t, emqxStatusMachine.degraded, emqxStatusMachine.currentStatus)
+
+		status := emqxStatusMachine.GetEMQX().Status
+		_, condition := status.GetCondition(appsv2alpha2.Degraded)
+		assert.Equal(t, appsv2alpha2.ReplicantNodesNotReadyReason, condition.Reason)
+		assert.True(t, status.IsConditionTrue(appsv2alpha2.Available))
+	})
+
+	t.Run("recovered", func(t *testing.T) {
+		emqxStatusMachine := newEMQXStatusMachine(newDegradedEMQX())
+		emqxStatusMachine.NextStatus(currentSts.DeepCopy(), currentRs.DeepCopy())
+		assert.Equal(t, emqxStatusMachine.ready, emqxStatusMachine.currentStatus)
+
+		status := emqxStatusMachine.GetEMQX().Status
+		assert.Equal(t, appsv2alpha2.Ready, status.Conditions[0].Type)
+		assert.True(t, status.IsConditionTrue(appsv2alpha2.Available))
+		assert.False(t, status.IsConditionTrue(appsv2alpha2.Degraded))
+	})
+}
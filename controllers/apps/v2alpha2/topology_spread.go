@@ -0,0 +1,86 @@
+package v2alpha2
+
+import (
+	"context"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getTopologySpreadConstraints returns the topology spread constraints of the template, in zoneAware mode, the default
+// constraints are appended for the zone and hostname topology keys that are not set by the user.
+// The EMQX core nodes must be spread across zones to keep the Mria core quorum when a zone is down,
+// so the zone constraint of the core nodes is hard, the other constraints are best effort.
+func getTopologySpreadConstraints(instance *appsv2alpha2.EMQX, constraints []corev1.TopologySpreadConstraint, labels map[string]string) []corev1.TopologySpreadConstraint {
+	if !instance.Spec.ZoneAware {
+		return constraints
+	}
+
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			appsv2alpha2.InstanceNameLabelKey: labels[appsv2alpha2.InstanceNameLabelKey],
+			appsv2alpha2.DBRoleLabelKey:       labels[appsv2alpha2.DBRoleLabelKey],
+		},
+	}
+	zoneWhenUnsatisfiable := corev1.ScheduleAnyway
+	if labels[appsv2alpha2.DBRoleLabelKey] == "core" {
+		zoneWhenUnsatisfiable = corev1.DoNotSchedule
+	}
+	defaults := []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelTopologyZone,
+			WhenUnsatisfiable: zoneWhenUnsatisfiable,
+			LabelSelector:     selector,
+		},
+		{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelHostname,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     selector.DeepCopy(),
+		},
+	}
+
+	list := append([]corev1.TopologySpreadConstraint{}, constraints...)
+	for _, d := range defaults {
+		found := false
+		for _, c := range constraints {
+			if c.TopologyKey == d.TopologyKey {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, d)
+		}
+	}
+	return list
+}
+
+// setZoneForEMQXNodes sets the zone of the Kubernetes node where the EMQX node is running
+func setZoneForEMQXNodes(ctx context.Context, k8sClient client.Client, nodes []appsv2alpha2.EMQXNode) {
+	zones := map[string]string{}
+	for _, n := range nodes {
+		if _, ok := zones[n.KubernetesNode]; ok || n.KubernetesNode == "" {
+			continue
+		}
+		node := &corev1.Node{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: n.KubernetesNode}, node); err != nil {
+			continue
+		}
+		zones[n.KubernetesNode] = node.Labels[corev1.LabelTopologyZone]
+	}
+
+	setEMQXNodesZone(nodes, zones)
+}
+
+// setEMQXNodesZone sets the zone of each EMQX node by its Kubernetes node,
+// zones is the map of the Kubernetes node name to the zone.
+func setEMQXNodesZone(nodes []appsv2alpha2.EMQXNode, zones map[string]string) {
+	for i := range nodes {
+		nodes[i].Zone = zones[nodes[i].KubernetesNode]
+	}
+}
@@ -0,0 +1,85 @@
+package v2alpha2
+
+import (
+	"testing"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetTopologySpreadConstraints(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{}
+	labels := map[string]string{
+		appsv2alpha2.ManagerByLabelKey:    "emqx-operator",
+		appsv2alpha2.InstanceNameLabelKey: "emqx",
+		appsv2alpha2.DBRoleLabelKey:       "core",
+	}
+	custom := []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           2,
+			TopologyKey:       corev1.LabelHostname,
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+		},
+	}
+
+	t.Run("not zone aware", func(t *testing.T) {
+		assert.Nil(t, getTopologySpreadConstraints(instance, nil, labels))
+		assert.Equal(t, custom, getTopologySpreadConstraints(instance, custom, labels))
+	})
+
+	t.Run("zone aware", func(t *testing.T) {
+		instance.Spec.ZoneAware = true
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				appsv2alpha2.InstanceNameLabelKey: "emqx",
+				appsv2alpha2.DBRoleLabelKey:       "core",
+			},
+		}
+		assert.Equal(t, []corev1.TopologySpreadConstraint{
+			{
+				MaxSkew:           1,
+				TopologyKey:       corev1.LabelTopologyZone,
+				WhenUnsatisfiable: corev1.DoNotSchedule,
+				LabelSelector:     selector,
+			},
+			{
+				MaxSkew:           1,
+				TopologyKey:       corev1.LabelHostname,
+				WhenUnsatisfiable: corev1.ScheduleAnyway,
+				LabelSelector:     selector,
+			},
+		}, getTopologySpreadConstraints(instance, nil, labels))
+	})
+
+	t.Run("zone aware should not override the custom constraints", func(t *testing.T) {
+		instance.Spec.ZoneAware = true
+		got := getTopologySpreadConstraints(instance, custom, labels)
+		assert.Len(t, got, 2)
+		assert.Equal(t, custom[0], got[0])
+		assert.Equal(t, corev1.LabelTopologyZone, got[1].TopologyKey)
+	})
+
+	t.Run("zone constraint of replicant nodes is best effort", func(t *testing.T) {
+		instance.Spec.ZoneAware = true
+		got := getTopologySpreadConstraints(instance, nil, map[string]string{
+			appsv2alpha2.InstanceNameLabelKey: "emqx",
+			appsv2alpha2.DBRoleLabelKey:       "replicant",
+		})
+		assert.Equal(t, corev1.ScheduleAnyway, got[0].WhenUnsatisfiable)
+	})
+}
+
+func TestSetEMQXNodesZone(t *testing.T) {
+	nodes := []appsv2alpha2.EMQXNode{
+		{Node: "emqx@emqx-core-0.emqx-headless.default.svc.cluster.local", Role: "core", KubernetesNode: "node-a"},
+		{Node: "emqx@10.0.0.1", Role: "replicant", KubernetesNode: "node-b"},
+		{Node: "emqx@10.0.0.2", Role: "replicant"},
+	}
+
+	setEMQXNodesZone(nodes, map[string]string{"node-a": "zone-a", "node-b": "zone-b"})
+	assert.Equal(t, "zone-a", nodes[0].Zone)
+	assert.Equal(t, "zone-b", nodes[1].Zone)
+	assert.Equal(t, "", nodes[2].Zone)
+}
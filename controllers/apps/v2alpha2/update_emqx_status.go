@@ -2,13 +2,20 @@ package v2alpha2
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	emperror "emperror.dev/errors"
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/emqx/emqx-operator/internal/metrics"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -19,51 +26,90 @@ type updateStatus struct {
 func (u *updateStatus) reconcile(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface) subResult {
 	var existedSts *appsv1.StatefulSet = &appsv1.StatefulSet{}
 	var existedRs *appsv1.ReplicaSet = &appsv1.ReplicaSet{}
+	var outdatedReplicantPods int
 
-	stsList := &appsv1.StatefulSetList{}
-	_ = u.Client.List(ctx, stsList,
-		client.InNamespace(instance.Namespace),
-		client.MatchingLabels(appsv2alpha2.CloneAndAddLabel(
-			instance.Spec.CoreTemplate.Labels,
-			appsv2alpha2.PodTemplateHashLabelKey,
-			instance.Status.CoreNodesStatus.CurrentRevision,
-		)),
-	)
-	if len(stsList.Items) > 0 {
-		existedSts = stsList.Items[0].DeepCopy()
+	if currentSts, _ := getStateFulSetList(ctx, u.Client, instance); currentSts != nil {
+		existedSts = currentSts
 	}
 
 	instance.Status.CoreNodesStatus.Replicas = *instance.Spec.CoreTemplate.Spec.Replicas
+	instance.Status.CoreNodesStatus.RetainedRevisions = nil
+	for _, sts := range getHistoryStatefulSetList(ctx, u.Client, instance) {
+		instance.Status.CoreNodesStatus.RetainedRevisions = append(instance.Status.CoreNodesStatus.RetainedRevisions, getRevision(sts.Spec.Template.ObjectMeta))
+	}
 
 	if isExistReplicant(instance) {
 		if instance.Status.ReplicantNodesStatus == nil {
 			instance.Status.ReplicantNodesStatus = &appsv2alpha2.EMQXNodesStatus{}
 		}
 
-		rsList := &appsv1.ReplicaSetList{}
-		_ = u.Client.List(ctx, rsList,
-			client.InNamespace(instance.Namespace),
-			client.MatchingLabels(appsv2alpha2.CloneAndAddLabel(
-				instance.Spec.ReplicantTemplate.Labels,
-				appsv2alpha2.PodTemplateHashLabelKey,
-				instance.Status.ReplicantNodesStatus.CurrentRevision,
-			)),
-		)
-		if len(rsList.Items) > 0 {
-			existedRs = rsList.Items[0].DeepCopy()
+		if currentRs, _ := getReplicaSetList(ctx, u.Client, instance); currentRs != nil {
+			existedRs = currentRs
+			_, outdated := getOutdatedPods(ctx, u.Client, currentRs.Namespace, currentRs.Spec.Selector.MatchLabels, currentRs.Spec.Template.Annotations[appsv2alpha2.PodTemplateRevisionAnnotationKey])
+			outdatedReplicantPods = len(outdated)
 		}
 
 		instance.Status.ReplicantNodesStatus.Replicas = *instance.Spec.ReplicantTemplate.Spec.Replicas
+		instance.Status.ReplicantNodesStatus.RetainedRevisions = nil
+		for _, rs := range getHistoryReplicaSetList(ctx, u.Client, instance) {
+			instance.Status.ReplicantNodesStatus.RetainedRevisions = append(instance.Status.ReplicantNodesStatus.RetainedRevisions, getRevision(rs.Spec.Template.ObjectMeta))
+		}
 	}
 
-	if r != nil {
-		if emqxNodes, err := getNodeStatuesByAPI(r); err != nil {
+	var managementAPIErr error
+	if r == nil {
+		managementAPIErr = emperror.New("no running core pod to request")
+	} else {
+		c := emqxapi.NewV5(r)
+		if emqxNodes, err := c.Nodes(ctx); err != nil {
+			managementAPIErr = err
 			u.EventRecorder.Event(instance, corev1.EventTypeWarning, "FailedToGetNodeStatuses", err.Error())
 		} else {
+			podList := &corev1.PodList{}
+			_ = u.Client.List(ctx, podList,
+				client.InNamespace(instance.Namespace),
+				client.MatchingLabels{appsv2alpha2.InstanceNameLabelKey: instance.Name},
+			)
+			mapEMQXNodesToPods(emqxNodes, podList.Items)
+			setZoneForEMQXNodes(ctx, u.Client, emqxNodes)
 			instance.Status.SetNodes(emqxNodes)
+
+			instance.Status.CoreNodesStatus.PodsNotInCluster = getPodsNotInCluster(emqxNodes, podList.Items, "core")
+			if instance.Status.ReplicantNodesStatus != nil {
+				instance.Status.ReplicantNodesStatus.PodsNotInCluster = getPodsNotInCluster(emqxNodes, podList.Items, "replicant")
+			}
+			setNodesGauges(instance)
+		}
+
+		if alarms, err := c.Alarms(ctx); err != nil {
+			u.EventRecorder.Event(instance, corev1.EventTypeWarning, "FailedToGetAlarms", err.Error())
+		} else {
+			updateAlarms(u.EventRecorder, instance, alarms)
+		}
+
+		if stats, err := u.statsCollector.collect(ctx, instance, r, time.Now()); err != nil {
+			u.EventRecorder.Event(instance, corev1.EventTypeWarning, "FailedToGetStats", err.Error())
+		} else {
+			instance.Status.Stats = stats
+		}
+
+		if isEnterprise(instance) {
+			if evacuationsStatus, err := c.EvacuationStatus(ctx); err != nil {
+				u.EventRecorder.Event(instance, corev1.EventTypeWarning, "FailedToGetEvacuationStatus", err.Error())
+			} else {
+				instance.Status.NodeEvacuationsStatus = evacuationsStatus
+			}
+		} else {
+			instance.Status.NodeEvacuationsStatus = nil
 		}
 	}
-	newEMQXStatusMachine(instance).NextStatus(existedSts, existedRs)
+	setSummary(instance)
+	updateManagementAPICondition(instance, innerReq.GetCircuitState(emqxKind, instance.Namespace, instance.Name))
+
+	emqxStatusMachine := newEMQXStatusMachine(instance)
+	emqxStatusMachine.managementAPIErr = managementAPIErr
+	emqxStatusMachine.outdatedReplicantPods = outdatedReplicantPods
+	emqxStatusMachine.NextStatus(existedSts, existedRs)
 
 	if err := u.Client.Status().Update(ctx, instance); err != nil {
 		return subResult{err: emperror.Wrap(err, "failed to update status")}
@@ -71,18 +117,166 @@ func (u *updateStatus) reconcile(ctx context.Context, instance *appsv2alpha2.EMQ
 	return subResult{}
 }
 
-func getNodeStatuesByAPI(r innerReq.RequesterInterface) ([]appsv2alpha2.EMQXNode, error) {
-	resp, body, err := r.Request("GET", "api/v5/nodes", nil)
-	if err != nil {
-		return nil, emperror.Wrap(err, "failed to get API api/v5/nodes")
+// setNodesGauges exports the ready replicas, connections and sessions of each role from the status
+func setNodesGauges(instance *appsv2alpha2.EMQX) {
+	sum := func(status *appsv2alpha2.EMQXNodesStatus) (conns, sess int64) {
+		for _, node := range status.Nodes {
+			conns += node.Connections
+			sess += node.Session
+		}
+		return
+	}
+
+	conns, sess := sum(&instance.Status.CoreNodesStatus)
+	metrics.SetNodesGauges(emqxKind, instance.Namespace, instance.Name, "core", instance.Status.CoreNodesStatus.ReadyReplicas, conns, sess)
+	if instance.Status.ReplicantNodesStatus == nil {
+		metrics.DeleteNodesGauges(emqxKind, instance.Namespace, instance.Name, "replicant")
+		return
+	}
+	conns, sess = sum(instance.Status.ReplicantNodesStatus)
+	metrics.SetNodesGauges(emqxKind, instance.Namespace, instance.Name, "replicant", instance.Status.ReplicantNodesStatus.ReadyReplicas, conns, sess)
+}
+
+// setSummary sets the status fields shown by kubectl get, like the EMQX versions, ready replicas and connections
+func setSummary(instance *appsv2alpha2.EMQX) {
+	nodes := instance.Status.CoreNodesStatus.Nodes
+	if instance.Status.ReplicantNodesStatus != nil {
+		nodes = append(nodes[:len(nodes):len(nodes)], instance.Status.ReplicantNodesStatus.Nodes...)
+	}
+
+	var versions []string
+	var connections int64
+	seen := map[string]bool{}
+	for _, node := range nodes {
+		if node.Version != "" && !seen[node.Version] {
+			seen[node.Version] = true
+			versions = append(versions, node.Version)
+		}
+		connections += node.Connections
+	}
+	sort.Strings(versions)
+	instance.Status.CurrentVersion = strings.Join(versions, ",")
+	instance.Status.Connections = connections
+
+	instance.Status.CoreNodesStatus.Ready = fmt.Sprintf("%d/%d", instance.Status.CoreNodesStatus.ReadyReplicas, instance.Status.CoreNodesStatus.Replicas)
+	if instance.Status.ReplicantNodesStatus != nil {
+		instance.Status.ReplicantNodesStatus.Ready = fmt.Sprintf("%d/%d", instance.Status.ReplicantNodesStatus.ReadyReplicas, instance.Status.ReplicantNodesStatus.Replicas)
+	}
+}
+
+// updateAlarms sets the active alarms and the AlarmsActive condition, the alarms activated or deactivated
+// since the last reconciliation are recorded as events.
+func updateAlarms(recorder record.EventRecorder, instance *appsv2alpha2.EMQX, alarms []appsv2alpha2.EMQXAlarm) {
+	sort.Slice(alarms, func(i, j int) bool {
+		if alarms[i].Node != alarms[j].Node {
+			return alarms[i].Node < alarms[j].Node
+		}
+		return alarms[i].Name < alarms[j].Name
+	})
+
+	activated, deactivated := diffAlarms(instance.Status.Alarms, alarms)
+	for _, alarm := range activated {
+		recorder.Eventf(instance, corev1.EventTypeWarning, "AlarmActivated", "Alarm %s is activated on node %s: %s", alarm.Name, alarm.Node, alarm.Message)
+	}
+	for _, alarm := range deactivated {
+		recorder.Eventf(instance, corev1.EventTypeWarning, "AlarmDeactivated", "Alarm %s is deactivated on node %s", alarm.Name, alarm.Node)
+	}
+	instance.Status.Alarms = alarms
+
+	condition := metav1.Condition{
+		Type:               appsv2alpha2.AlarmsActive,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NoActiveAlarms",
+		Message:            "No active alarms",
+		ObservedGeneration: instance.Generation,
+	}
+	if len(alarms) > 0 {
+		var names []string
+		for _, alarm := range alarms {
+			names = append(names, fmt.Sprintf("%s on %s", alarm.Name, alarm.Node))
+		}
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = appsv2alpha2.AlarmsActive
+		condition.Message = "Active alarms: " + strings.Join(names, ", ")
 	}
-	if resp.StatusCode != 200 {
-		return nil, emperror.Errorf("failed to get API %s, status : %s, body: %s", "api/v5/nodes", resp.Status, body)
+	_, old := instance.Status.GetCondition(appsv2alpha2.AlarmsActive)
+	if old == nil || old.Status != condition.Status || old.Message != condition.Message || old.ObservedGeneration != condition.ObservedGeneration {
+		instance.Status.SetCondition(condition)
 	}
+}
 
-	nodeStatuses := []appsv2alpha2.EMQXNode{}
-	if err := json.Unmarshal(body, &nodeStatuses); err != nil {
-		return nil, emperror.Wrap(err, "failed to unmarshal node statuses")
+// updateManagementAPICondition sets the ManagementAPIUnavailable condition by the circuit breaker of the cluster,
+// the requests to the cluster by the endpoints of the management API fail fast without being sent while the circuit is open.
+func updateManagementAPICondition(instance *appsv2alpha2.EMQX, state innerReq.CircuitState) {
+	condition := metav1.Condition{
+		Type:               appsv2alpha2.ManagementAPIUnavailable,
+		Status:             metav1.ConditionFalse,
+		Reason:             "CircuitBreakerClosed",
+		Message:            "The management API is requested",
+		ObservedGeneration: instance.Generation,
+	}
+	if state.Open {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "CircuitBreakerOpen"
+		condition.Message = fmt.Sprintf("Stopped requesting the management API since %s after repeated failures, last error: %s",
+			state.Since.UTC().Format(time.RFC3339), state.LastError)
+	}
+	_, old := instance.Status.GetCondition(appsv2alpha2.ManagementAPIUnavailable)
+	if old == nil || old.Status != condition.Status || old.Message != condition.Message || old.ObservedGeneration != condition.ObservedGeneration {
+		instance.Status.SetCondition(condition)
+	}
+}
+
+// diffAlarms returns the alarms in new but not in old, and the alarms in old but not in new
+func diffAlarms(old, new []appsv2alpha2.EMQXAlarm) (activated, deactivated []appsv2alpha2.EMQXAlarm) {
+	contains := func(list []appsv2alpha2.EMQXAlarm, alarm appsv2alpha2.EMQXAlarm) bool {
+		for _, a := range list {
+			if a.Node == alarm.Node && a.Name == alarm.Name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, alarm := range new {
+		if !contains(old, alarm) {
+			activated = append(activated, alarm)
+		}
+	}
+	for _, alarm := range old {
+		if !contains(new, alarm) {
+			deactivated = append(deactivated, alarm)
+		}
+	}
+	return
+}
+
+// mapEMQXNodesToPods sets the pod of each EMQX node, the EMQX nodes not running in any pod are left unchanged
+func mapEMQXNodesToPods(nodes []appsv2alpha2.EMQXNode, pods []corev1.Pod) {
+	for i := range nodes {
+		for _, pod := range pods {
+			if pod.DeletionTimestamp != nil || !isEMQXNodeRunningInPod(nodes[i], &pod) {
+				continue
+			}
+			nodes[i].PodName = pod.Name
+			nodes[i].PodUID = pod.UID
+			nodes[i].PodIP = pod.Status.PodIP
+			nodes[i].KubernetesNode = pod.Spec.NodeName
+			nodes[i].Revision = getRevision(pod.ObjectMeta)
+			break
+		}
+	}
+}
+
+// getPodsNotInCluster returns the names of the running pods with the db role that have not joined the EMQX cluster
+func getPodsNotInCluster(nodes []appsv2alpha2.EMQXNode, pods []corev1.Pod, role string) []string {
+	var list []string
+	for _, pod := range pods {
+		if pod.Labels[appsv2alpha2.DBRoleLabelKey] != role || pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if findEMQXNodeByPod(nodes, &pod) == nil {
+			list = append(list, pod.Name)
+		}
 	}
-	return nodeStatuses, nil
+	return list
 }
@@ -0,0 +1,85 @@
+package v2alpha2
+
+import (
+	"context"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/emqx/emqx-operator/internal/emqxapi/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Check update status controller", Ordered, Label("status"), func() {
+	var u *updateStatus
+	var s *fake.Server
+	var instance *appsv2alpha2.EMQX = new(appsv2alpha2.EMQX)
+	var ns *corev1.Namespace = &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "controller-v2alpha2-update-status-test",
+			Labels: map[string]string{
+				"test": "e2e",
+			},
+		},
+	}
+
+	BeforeAll(func() {
+		u = &updateStatus{emqxReconciler}
+		s = fake.NewServer()
+		s.AddNode(
+			fake.Node{Name: "emqx@emqx-core-0.emqx-headless.fake", Role: "core", Version: "5.1.0", Edition: "Enterprise", Connections: 10, Sessions: 10},
+			fake.Node{Name: "emqx@emqx-core-1.emqx-headless.fake", Role: "core", Version: "5.1.0", Edition: "Enterprise"},
+			fake.Node{Name: "emqx@emqx-core-2.emqx-headless.fake", Role: "core", Version: "5.1.0", Edition: "Enterprise"},
+		)
+
+		instance = emqx.DeepCopy()
+		instance.Namespace = ns.Name
+		Expect(k8sClient.Create(context.TODO(), ns)).Should(Succeed())
+		Expect(k8sClient.Create(ctx, instance)).Should(Succeed())
+	})
+
+	AfterAll(func() {
+		s.Close()
+		Expect(k8sClient.Delete(ctx, ns)).Should(Succeed())
+	})
+
+	It("should update the nodes", func() {
+		Expect(u.reconcile(ctx, instance, s.Requester())).Should(Equal(subResult{}))
+		Expect(instance.Status.CoreNodesStatus.Nodes).Should(HaveLen(3))
+		Expect(instance.Status.CoreNodesStatus.ReadyReplicas).Should(Equal(int32(3)))
+		Expect(instance.Status.NodeEvacuationsStatus).Should(BeEmpty())
+	})
+
+	It("should update the evacuations", func() {
+		Expect(emqxapi.NewV5(s.Requester()).StartEvacuation(ctx, "emqx@emqx-core-0.emqx-headless.fake", emqxapi.EvacuationRequest{
+			ConnEvictRate: 5,
+			SessEvictRate: 10,
+			MigrateTo:     []string{"emqx@emqx-core-1.emqx-headless.fake", "emqx@emqx-core-2.emqx-headless.fake"},
+		})).Should(Succeed())
+
+		var states []string
+		for i := 0; i < 4; i++ {
+			Expect(u.reconcile(ctx, instance, s.Requester())).Should(Equal(subResult{}))
+			Expect(instance.Status.NodeEvacuationsStatus).Should(HaveLen(1))
+			states = append(states, instance.Status.NodeEvacuationsStatus[0].State)
+			s.Step()
+		}
+		Expect(states).Should(Equal([]string{"evicting_conns", "evicting_conns", "evicting_sessions", "prohibiting"}))
+		Expect(instance.Status.CoreNodesStatus.Nodes).Should(ContainElement(And(
+			HaveField("Node", "emqx@emqx-core-0.emqx-headless.fake"),
+			HaveField("Session", int64(0)),
+		)))
+	})
+
+	It("should update the nodes after the node leaves", func() {
+		s.SetLocalNode("emqx@emqx-core-1.emqx-headless.fake")
+		Expect(emqxapi.NewV5(s.Requester()).ForceLeave(ctx, "emqx@emqx-core-0.emqx-headless.fake")).Should(Succeed())
+
+		Expect(u.reconcile(ctx, instance, s.Requester())).Should(Equal(subResult{}))
+		Expect(instance.Status.CoreNodesStatus.Nodes).Should(HaveLen(2))
+		Expect(instance.Status.NodeEvacuationsStatus).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,181 @@
+package v2alpha2
+
+import (
+	"testing"
+	"time"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMapEMQXNodesToPods(t *testing.T) {
+	nodes := []appsv2alpha2.EMQXNode{
+		{Node: "emqx@emqx-core-0.emqx-headless.default.svc.cluster.local", Role: "core"},
+		{Node: "emqx@10.0.0.1", Role: "replicant"},
+		{Node: "emqx@10.0.0.2", Role: "replicant"},
+	}
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "emqx-core-0",
+				UID:    "core-uid",
+				Labels: map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "core-hash"},
+				Annotations: map[string]string{
+					appsv2alpha2.PodTemplateRevisionAnnotationKey: "core-revision",
+				},
+			},
+			Spec:   corev1.PodSpec{NodeName: "node-a"},
+			Status: corev1.PodStatus{PodIP: "10.0.1.1"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "emqx-replicant-fake",
+				UID:    "replicant-uid",
+				Labels: map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "replicant-hash"},
+			},
+			Spec:   corev1.PodSpec{NodeName: "node-b"},
+			Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+		},
+	}
+
+	mapEMQXNodesToPods(nodes, pods)
+	assert.Equal(t, appsv2alpha2.EMQXNode{
+		Node:           "emqx@emqx-core-0.emqx-headless.default.svc.cluster.local",
+		Role:           "core",
+		PodName:        "emqx-core-0",
+		PodUID:         "core-uid",
+		PodIP:          "10.0.1.1",
+		KubernetesNode: "node-a",
+		Revision:       "core-revision",
+	}, nodes[0])
+	assert.Equal(t, appsv2alpha2.EMQXNode{
+		Node:           "emqx@10.0.0.1",
+		Role:           "replicant",
+		PodName:        "emqx-replicant-fake",
+		PodUID:         "replicant-uid",
+		PodIP:          "10.0.0.1",
+		KubernetesNode: "node-b",
+		Revision:       "replicant-hash",
+	}, nodes[1])
+	assert.Equal(t, appsv2alpha2.EMQXNode{Node: "emqx@10.0.0.2", Role: "replicant"}, nodes[2])
+}
+
+func TestGetPodsNotInCluster(t *testing.T) {
+	nodes := []appsv2alpha2.EMQXNode{
+		{Node: "emqx@emqx-core-0.emqx-headless.default.svc.cluster.local", Role: "core", PodName: "emqx-core-0"},
+	}
+	newPod := func(name, role string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{appsv2alpha2.DBRoleLabelKey: role},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	pods := []corev1.Pod{
+		newPod("emqx-core-0", "core", corev1.PodRunning),
+		newPod("emqx-core-1", "core", corev1.PodRunning),
+		newPod("emqx-core-2", "core", corev1.PodPending),
+		newPod("emqx-replicant-fake", "replicant", corev1.PodRunning),
+	}
+
+	assert.Equal(t, []string{"emqx-core-1"}, getPodsNotInCluster(nodes, pods, "core"))
+	assert.Equal(t, []string{"emqx-replicant-fake"}, getPodsNotInCluster(nodes, pods, "replicant"))
+}
+
+func TestSetSummary(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{
+		Status: appsv2alpha2.EMQXStatus{
+			CoreNodesStatus: appsv2alpha2.EMQXNodesStatus{
+				Replicas:      3,
+				ReadyReplicas: 2,
+				Nodes: []appsv2alpha2.EMQXNode{
+					{Node: "emqx@10.0.0.1", Version: "5.0.9", Connections: 1},
+					{Node: "emqx@10.0.0.2", Version: "5.0.8", Connections: 2},
+				},
+			},
+			ReplicantNodesStatus: &appsv2alpha2.EMQXNodesStatus{
+				Replicas:      2,
+				ReadyReplicas: 1,
+				Nodes: []appsv2alpha2.EMQXNode{
+					{Node: "emqx@10.0.0.3", Version: "5.0.9", Connections: 4},
+				},
+			},
+		},
+	}
+
+	setSummary(instance)
+	assert.Equal(t, "5.0.8,5.0.9", instance.Status.CurrentVersion)
+	assert.Equal(t, int64(7), instance.Status.Connections)
+	assert.Equal(t, "2/3", instance.Status.CoreNodesStatus.Ready)
+	assert.Equal(t, "1/2", instance.Status.ReplicantNodesStatus.Ready)
+	assert.Len(t, instance.Status.CoreNodesStatus.Nodes, 2)
+}
+
+func TestUpdateAlarms(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{}
+	recorder := record.NewFakeRecorder(10)
+
+	t.Run("alarms activated", func(t *testing.T) {
+		updateAlarms(recorder, instance, []appsv2alpha2.EMQXAlarm{
+			{Node: "emqx@10.0.0.2", Name: "high_system_memory_usage", Message: "System memory usage is higher than 70%"},
+			{Node: "emqx@10.0.0.1", Name: "too_many_processes", Message: "Process usage is higher than 80%"},
+		})
+		assert.Equal(t, "emqx@10.0.0.1", instance.Status.Alarms[0].Node)
+		assert.Equal(t, "Warning AlarmActivated Alarm too_many_processes is activated on node emqx@10.0.0.1: Process usage is higher than 80%", <-recorder.Events)
+		assert.Equal(t, "Warning AlarmActivated Alarm high_system_memory_usage is activated on node emqx@10.0.0.2: System memory usage is higher than 70%", <-recorder.Events)
+
+		_, condition := instance.Status.GetCondition(appsv2alpha2.AlarmsActive)
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, "Active alarms: too_many_processes on emqx@10.0.0.1, high_system_memory_usage on emqx@10.0.0.2", condition.Message)
+	})
+
+	t.Run("alarm deactivated", func(t *testing.T) {
+		updateAlarms(recorder, instance, []appsv2alpha2.EMQXAlarm{
+			{Node: "emqx@10.0.0.1", Name: "too_many_processes", Message: "Process usage is higher than 80%"},
+		})
+		assert.Len(t, instance.Status.Alarms, 1)
+		assert.Equal(t, "Warning AlarmDeactivated Alarm high_system_memory_usage is deactivated on node emqx@10.0.0.2", <-recorder.Events)
+		assert.Len(t, recorder.Events, 0)
+	})
+
+	t.Run("no active alarms", func(t *testing.T) {
+		updateAlarms(recorder, instance, []appsv2alpha2.EMQXAlarm{})
+		assert.Len(t, recorder.Events, 1)
+		<-recorder.Events
+
+		_, condition := instance.Status.GetCondition(appsv2alpha2.AlarmsActive)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, "NoActiveAlarms", condition.Reason)
+	})
+}
+
+func TestUpdateManagementAPICondition(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{}
+
+	t.Run("circuit closed", func(t *testing.T) {
+		updateManagementAPICondition(instance, innerReq.CircuitState{})
+		_, condition := instance.Status.GetCondition(appsv2alpha2.ManagementAPIUnavailable)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, "CircuitBreakerClosed", condition.Reason)
+	})
+
+	t.Run("circuit open", func(t *testing.T) {
+		updateManagementAPICondition(instance, innerReq.CircuitState{
+			Open:      true,
+			Since:     time.Date(2023, 7, 1, 8, 0, 0, 0, time.UTC),
+			LastError: "502 Bad Gateway",
+		})
+		_, condition := instance.Status.GetCondition(appsv2alpha2.ManagementAPIUnavailable)
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, "CircuitBreakerOpen", condition.Reason)
+		assert.Equal(t, "Stopped requesting the management API since 2023-07-01T08:00:00Z after repeated failures, last error: 502 Bad Gateway", condition.Message)
+		// It does not describe the cluster status
+		assert.Nil(t, instance.Status.GetLastTrueCondition())
+	})
+}
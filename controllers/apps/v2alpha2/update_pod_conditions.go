@@ -4,10 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 
 	semver "github.com/Masterminds/semver/v3"
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
 	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -39,7 +39,7 @@ func (u *updatePodConditions) reconcile(ctx context.Context, instance *appsv2alp
 
 		onServingCondition := corev1.PodCondition{
 			Type:               appsv2alpha2.PodOnServing,
-			Status:             u.checkInCluster(instance, r, pod.DeepCopy()),
+			Status:             u.checkInCluster(ctx, instance, r, pod.DeepCopy()),
 			LastProbeTime:      metav1.Now(),
 			LastTransitionTime: metav1.Now(),
 		}
@@ -57,51 +57,43 @@ func (u *updatePodConditions) reconcile(ctx context.Context, instance *appsv2alp
 	return subResult{}
 }
 
-func (u *updatePodConditions) checkInCluster(instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface, pod *corev1.Pod) corev1.ConditionStatus {
+func (u *updatePodConditions) checkInCluster(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface, pod *corev1.Pod) corev1.ConditionStatus {
 	nodes := instance.Status.CoreNodesStatus.Nodes
 	if isExistReplicant(instance) {
 		nodes = append(nodes, instance.Status.ReplicantNodesStatus.Nodes...)
 	}
-	for _, node := range nodes {
-		l := strings.Split(node.Node, "@")
-		host := l[len(l)-1]
-
-		if (node.Role == "core" && strings.HasPrefix(host, pod.Name)) ||
-			(node.Role == "replicant" && host == pod.Status.PodIP) {
-			if node.Edition == "enterprise" {
-				v, _ := semver.NewVersion(node.Version)
-				if v.Compare(semver.MustParse("5.0.3")) >= 0 {
-					return u.checkRebalanceStatus(instance, r, pod)
-				}
-			}
-			return corev1.ConditionTrue
+	node := findEMQXNodeByPod(nodes, pod)
+	if node == nil {
+		return corev1.ConditionFalse
+	}
+	if node.Edition == "enterprise" {
+		v, _ := semver.NewVersion(node.Version)
+		if v.Compare(semver.MustParse("5.0.3")) >= 0 {
+			return u.checkRebalanceStatus(ctx, instance, r, pod)
 		}
 	}
-	return corev1.ConditionFalse
+	return corev1.ConditionTrue
 }
 
-func (u *updatePodConditions) checkRebalanceStatus(instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface, pod *corev1.Pod) corev1.ConditionStatus {
-	var port string
-	dashboardPort, err := appsv2alpha2.GetDashboardServicePort(instance)
-	if err != nil || dashboardPort == nil {
-		port = "18083"
-	}
-
-	if dashboardPort != nil {
-		port = dashboardPort.TargetPort.String()
-	}
-
+func (u *updatePodConditions) checkRebalanceStatus(ctx context.Context, instance *appsv2alpha2.EMQX, r innerReq.RequesterInterface, pod *corev1.Pod) corev1.ConditionStatus {
 	requester := &innerReq.Requester{
-		Username: r.GetUsername(),
-		Password: r.GetPassword(),
-		Host:     fmt.Sprintf("%s:%s", pod.Status.PodIP, port),
+		Scheme:    r.GetScheme(),
+		Username:  r.GetUsername(),
+		Password:  r.GetPassword(),
+		Host:      fmt.Sprintf("%s:%s", pod.Status.PodIP, getManagementAPIPort(instance, r.GetScheme())),
+		TLS:       r.GetTLSConfig(),
+		Kind:      emqxKind,
+		Namespace: instance.Namespace,
+		Instance:  instance.Name,
+		// The unavailable node responds 503, which is the answer of the check, not a transient failure to retry
+		Retries: -1,
 	}
 
-	resp, _, err := requester.Request("GET", "api/v5/load_rebalance/availability_check", nil)
+	available, err := emqxapi.NewV5(requester).AvailabilityCheck(ctx)
 	if err != nil {
 		return corev1.ConditionUnknown
 	}
-	if resp.StatusCode != 200 {
+	if !available {
 		return corev1.ConditionFalse
 	}
 	return corev1.ConditionTrue
@@ -0,0 +1,37 @@
+package v2alpha2
+
+import (
+	"context"
+	"testing"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/emqx/emqx-operator/internal/emqxapi/fake"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckRebalanceStatus(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+	s.AddNode(
+		fake.Node{Name: "emqx@127.0.0.1", Edition: "Enterprise", Connections: 10, Sessions: 10},
+		fake.Node{Name: "emqx@10.0.0.2", Edition: "Enterprise"},
+	)
+
+	// The availability is checked by the pod IP and the dashboard port
+	instance := &appsv2alpha2.EMQX{
+		Spec: appsv2alpha2.EMQXSpec{BootstrapConfig: "dashboard.listeners.http.bind = " + s.Port()},
+	}
+	pod := &corev1.Pod{Status: corev1.PodStatus{PodIP: "127.0.0.1"}}
+	u := &updatePodConditions{}
+
+	assert.Equal(t, corev1.ConditionTrue, u.checkRebalanceStatus(context.Background(), instance, s.Requester(), pod))
+
+	assert.Nil(t, emqxapi.NewV5(s.Requester()).StartEvacuation(context.Background(), "emqx@127.0.0.1", emqxapi.EvacuationRequest{
+		ConnEvictRate: 10,
+		SessEvictRate: 10,
+		MigrateTo:     []string{"emqx@10.0.0.2"},
+	}))
+	assert.Equal(t, corev1.ConditionFalse, u.checkRebalanceStatus(context.Background(), instance, s.Requester(), pod))
+}
@@ -8,16 +8,21 @@ import (
 	"hash"
 	"hash/fnv"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	emperror "emperror.dev/errors"
 	"github.com/cisco-open/k8s-objectmatcher/patch"
 	"github.com/davecgh/go-spew/spew"
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	"github.com/tidwall/gjson"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -54,30 +59,183 @@ func isExistReplicant(instance *appsv2alpha2.EMQX) bool {
 // 	return rsMap
 // }
 
-func canBeScaledDown(instance *appsv2alpha2.EMQX, conditionType string, eList []*corev1.Event) bool {
-	var initialDelaySecondsReady bool
-	var waitTakeover bool
+// scaleDownWaiting remembers the reason the scale down of each old statefulSet or replicaSet is waiting for,
+// so the waiting events are emitted when the reason changes rather than by every reconciliation.
+type scaleDownWaiting struct {
+	mu      sync.Mutex
+	reasons map[types.NamespacedName]map[string]string
+}
 
-	_, condition := instance.Status.GetCondition(conditionType)
-	if condition != nil && condition.Status == metav1.ConditionTrue {
-		delay := time.Since(condition.LastTransitionTime.Time).Seconds()
-		if int32(delay) > instance.Spec.UpdateStrategy.InitialDelaySeconds {
-			initialDelaySecondsReady = true
+// update records the waiting reason of the old statefulSet or replicaSet of the instance, returns true if the reason is changed,
+// the empty reason means it is not waiting any more.
+func (w *scaleDownWaiting) update(instance, obj client.Object, reason string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := client.ObjectKeyFromObject(instance)
+	if w.reasons[key][obj.GetName()] == reason {
+		return false
+	}
+	if reason == "" {
+		delete(w.reasons[key], obj.GetName())
+		if len(w.reasons[key]) == 0 {
+			delete(w.reasons, key)
 		}
+		return true
+	}
+	if w.reasons == nil {
+		w.reasons = map[types.NamespacedName]map[string]string{}
 	}
+	if w.reasons[key] == nil {
+		w.reasons[key] = map[string]string{}
+	}
+	w.reasons[key][obj.GetName()] = reason
+	return true
+}
 
-	if len(eList) == 0 {
-		waitTakeover = true
-		return initialDelaySecondsReady && waitTakeover
+// forget drops the waiting reasons of a deleted instance.
+func (w *scaleDownWaiting) forget(key types.NamespacedName) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.reasons, key)
+}
+
+// getScaleDownWaiting returns the reason and the message of the update step that the scale down of the old revision is waiting for,
+// the reason is empty when the old revision can be scaled down.
+func getScaleDownWaiting(instance *appsv2alpha2.EMQX, conditionType string, eList []*corev1.Event) (reason, message string) {
+	_, condition := instance.Status.GetCondition(conditionType)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		return "WaitingForReady", fmt.Sprintf("Waiting for the %s condition before scaling down the old revision", conditionType)
 	}
 
+	initialDelaySeconds := instance.Spec.UpdateStrategy.InitialDelaySeconds
+	if int32(time.Since(condition.LastTransitionTime.Time).Seconds()) <= initialDelaySeconds {
+		return "WaitingForInitialDelay", fmt.Sprintf("Waiting %d initialDelaySeconds before scaling down the old revision", initialDelaySeconds)
+	}
+
+	if len(eList) == 0 {
+		return "", ""
+	}
+	waitTakeover := instance.Spec.UpdateStrategy.EvacuationStrategy.WaitTakeover
 	lastEvent := eList[len(eList)-1]
-	delay := time.Since(lastEvent.LastTimestamp.Time).Seconds()
-	if int32(delay) > instance.Spec.UpdateStrategy.EvacuationStrategy.WaitTakeover {
-		waitTakeover = true
+	if int32(time.Since(lastEvent.LastTimestamp.Time).Seconds()) <= waitTakeover {
+		return "WaitingForTakeover", fmt.Sprintf("Waiting %d seconds for the clients to be taken over before scaling down the old revision again", waitTakeover)
+	}
+	return "", ""
+}
+
+// summarizePatch returns the changed fields of the pod template in the patch, like "spec.containers[emqx].image",
+// at most 5 fields are listed.
+func summarizePatch(data []byte) string {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return string(data)
+	}
+	if spec, ok := obj["spec"].(map[string]interface{}); ok {
+		if template, ok := spec["template"].(map[string]interface{}); ok {
+			obj = template
+		}
+	}
+
+	fields := collectPatchFields("", obj)
+	sort.Strings(fields)
+	if len(fields) > 5 {
+		return fmt.Sprintf("%s and %d more", strings.Join(fields[:5], ", "), len(fields)-5)
+	}
+	return strings.Join(fields, ", ")
+}
+
+func collectPatchFields(prefix string, value interface{}) []string {
+	var fields []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			// Skip the directives of the strategic merge patch, like $setElementOrder and $retainKeys
+			if strings.HasPrefix(key, "$") {
+				continue
+			}
+			// The merge key of the list element is part of the path
+			if key == "name" && strings.HasSuffix(prefix, "]") {
+				continue
+			}
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			fields = append(fields, collectPatchFields(path, item)...)
+		}
+	case []interface{}:
+		// Only the elements of the lists merged by name are listed, otherwise the whole list is replaced
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				if name, ok := m["name"].(string); ok {
+					fields = append(fields, collectPatchFields(fmt.Sprintf("%s[%s]", prefix, name), item)...)
+				}
+			}
+		}
+	}
+	if len(fields) == 0 && prefix != "" {
+		return []string{prefix}
+	}
+	return fields
+}
+
+func isEnterprise(instance *appsv2alpha2.EMQX) bool {
+	for _, node := range instance.Status.CoreNodesStatus.Nodes {
+		if node.Edition == "Enterprise" {
+			return true
+		}
+	}
+	return false
+}
+
+func isEvacuating(instance *appsv2alpha2.EMQX, nodeName string) bool {
+	for _, e := range instance.Status.NodeEvacuationsStatus {
+		if e.Node == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// getEMQXNodeNamesByPods returns the names of the EMQX nodes running in the pods, the pods not in the EMQX cluster are ignored
+func getEMQXNodeNamesByPods(nodes []appsv2alpha2.EMQXNode, pods []corev1.Pod) []string {
+	names := []string{}
+	for i := range pods {
+		if node := findEMQXNodeByPod(nodes, &pods[i]); node != nil {
+			names = append(names, node.Node)
+		}
+	}
+	return names
+}
+
+func startEvacuationByAPI(ctx context.Context, r innerReq.RequesterInterface, instance *appsv2alpha2.EMQX, migrateTo []string, nodeName string) error {
+	return emqxapi.NewV5(r).StartEvacuation(ctx, nodeName, emqxapi.EvacuationRequest{
+		ConnEvictRate: instance.Spec.UpdateStrategy.EvacuationStrategy.ConnEvictRate,
+		SessEvictRate: instance.Spec.UpdateStrategy.EvacuationStrategy.SessEvictRate,
+		WaitTakeover:  instance.Spec.UpdateStrategy.EvacuationStrategy.WaitTakeover,
+		MigrateTo:     migrateTo,
+	})
+}
+
+// findEMQXNodeByPod returns the EMQX node running in the pod, or nil if the pod has not joined the EMQX cluster,
+// the EMQX nodes are mapped to the pods by mapEMQXNodesToPods when the status is updated.
+func findEMQXNodeByPod(nodes []appsv2alpha2.EMQXNode, pod *corev1.Pod) *appsv2alpha2.EMQXNode {
+	for _, node := range nodes {
+		if node.PodName != "" && node.PodName == pod.Name && node.PodUID == pod.UID {
+			return node.DeepCopy()
+		}
 	}
+	return nil
+}
+
+// isEMQXNodeRunningInPod checks whether the EMQX node is running in the pod by the node name,
+// the host of core nodes is the pod DNS name, the host of replicant nodes is the pod IP.
+func isEMQXNodeRunningInPod(node appsv2alpha2.EMQXNode, pod *corev1.Pod) bool {
+	l := strings.Split(node.Node, "@")
+	host := strings.Split(l[len(l)-1], ":")[0]
 
-	return initialDelaySecondsReady && waitTakeover
+	return (node.Role == "core" && (host == pod.Name || strings.HasPrefix(host, pod.Name+"."))) ||
+		(node.Role == "replicant" && pod.Status.PodIP != "" && host == pod.Status.PodIP)
 }
 
 func getStateFulSetList(ctx context.Context, k8sClient client.Client, instance *appsv2alpha2.EMQX) (currentSts *appsv1.StatefulSet, oldStsList []*appsv1.StatefulSet) {
@@ -87,8 +245,11 @@ func getStateFulSetList(ctx context.Context, k8sClient client.Client, instance *
 		client.MatchingLabels(instance.Spec.CoreTemplate.Labels),
 	)
 	for _, sts := range list.Items {
-		if hash, ok := sts.Labels[appsv2alpha2.PodTemplateHashLabelKey]; ok && hash == instance.Status.CoreNodesStatus.CurrentRevision {
-			currentSts = sts.DeepCopy()
+		if isCurrentRevision(sts.Labels, sts.Spec.Template.ObjectMeta, instance.Status.CoreNodesStatus.CurrentRevision) {
+			// The statefulSet of an old revision may have the same pod template as the current one after rolling update
+			if currentSts == nil || *currentSts.Spec.Replicas == 0 {
+				currentSts = sts.DeepCopy()
+			}
 		} else {
 			if *sts.Spec.Replicas != 0 && sts.Status.ReadyReplicas == sts.Status.Replicas {
 				oldStsList = append(oldStsList, sts.DeepCopy())
@@ -107,8 +268,11 @@ func getReplicaSetList(ctx context.Context, k8sClient client.Client, instance *a
 		client.MatchingLabels(instance.Spec.ReplicantTemplate.Labels),
 	)
 	for _, rs := range list.Items {
-		if hash, ok := rs.Labels[appsv2alpha2.PodTemplateHashLabelKey]; ok && hash == instance.Status.ReplicantNodesStatus.CurrentRevision {
-			currentRs = rs.DeepCopy()
+		if isCurrentRevision(rs.Labels, rs.Spec.Template.ObjectMeta, instance.Status.ReplicantNodesStatus.CurrentRevision) {
+			// The replicaSet of an old revision may have the same pod template as the current one after rolling update
+			if currentRs == nil || *currentRs.Spec.Replicas == 0 {
+				currentRs = rs.DeepCopy()
+			}
 		} else {
 			if *rs.Spec.Replicas != 0 && rs.Status.ReadyReplicas == rs.Status.Replicas {
 				oldRsList = append(oldRsList, rs.DeepCopy())
@@ -119,6 +283,51 @@ func getReplicaSetList(ctx context.Context, k8sClient client.Client, instance *a
 	return
 }
 
+// getRevision returns the revision of the pod template, the rolling update advances the pod-template-revision
+// annotation in place while the pod-template-hash label is kept for the immutable selector,
+// the label is the revision of the pod templates created without the annotation.
+func getRevision(template metav1.ObjectMeta) string {
+	if revision, ok := template.Annotations[appsv2alpha2.PodTemplateRevisionAnnotationKey]; ok {
+		return revision
+	}
+	return template.Labels[appsv2alpha2.PodTemplateHashLabelKey]
+}
+
+// isCurrentRevision checks whether the statefulSet or replicaSet is of the current revision, by the revision of its pod
+// template, or by its pod-template-hash label if the status is not updated yet after rolling update.
+func isCurrentRevision(labels map[string]string, template metav1.ObjectMeta, currentRevision string) bool {
+	hash, ok := labels[appsv2alpha2.PodTemplateHashLabelKey]
+	if !ok || currentRevision == "" {
+		return false
+	}
+	return hash == currentRevision || getRevision(template) == currentRevision
+}
+
+func getRevisionHistoryLimit(instance *appsv2alpha2.EMQX) int {
+	if instance.Spec.RevisionHistoryLimit == nil {
+		return 3
+	}
+	return int(*instance.Spec.RevisionHistoryLimit)
+}
+
+func getHistoryStatefulSetList(ctx context.Context, k8sClient client.Client, instance *appsv2alpha2.EMQX) []*appsv1.StatefulSet {
+	list := &appsv1.StatefulSetList{}
+	_ = k8sClient.List(ctx, list,
+		client.InNamespace(instance.Namespace),
+		client.MatchingLabels(instance.Spec.CoreTemplate.Labels),
+	)
+	return handlerHistoryStatefulSetList(list, instance.Status.CoreNodesStatus.CurrentRevision)
+}
+
+func getHistoryReplicaSetList(ctx context.Context, k8sClient client.Client, instance *appsv2alpha2.EMQX) []*appsv1.ReplicaSet {
+	list := &appsv1.ReplicaSetList{}
+	_ = k8sClient.List(ctx, list,
+		client.InNamespace(instance.Namespace),
+		client.MatchingLabels(instance.Spec.ReplicantTemplate.Labels),
+	)
+	return handlerHistoryReplicaSetList(list, instance.Status.ReplicantNodesStatus.CurrentRevision)
+}
+
 func getEventList(ctx context.Context, clientSet *kubernetes.Clientset, obj client.Object) []*corev1.Event {
 	// https://github.com/kubernetes-sigs/kubebuilder/issues/547#issuecomment-450772300
 	eventList, _ := clientSet.CoreV1().Events(obj.GetNamespace()).List(context.Background(), metav1.ListOptions{
@@ -127,6 +336,34 @@ func getEventList(ctx context.Context, clientSet *kubernetes.Clientset, obj clie
 	return handlerEventList(eventList)
 }
 
+// handlerHistoryStatefulSetList returns the statefulSets of the old revisions, sorted by creation timestamp from oldest to newest
+func handlerHistoryStatefulSetList(list *appsv1.StatefulSetList, currentRevision string) []*appsv1.StatefulSet {
+	stsList := []*appsv1.StatefulSet{}
+
+	for _, sts := range list.Items {
+		if _, ok := sts.Labels[appsv2alpha2.PodTemplateHashLabelKey]; ok && !isCurrentRevision(sts.Labels, sts.Spec.Template.ObjectMeta, currentRevision) {
+			stsList = append(stsList, sts.DeepCopy())
+		}
+	}
+
+	sort.Sort(StatefulSetsByCreationTimestamp(stsList))
+	return stsList
+}
+
+// handlerHistoryReplicaSetList returns the replicaSets of the old revisions, sorted by creation timestamp from oldest to newest
+func handlerHistoryReplicaSetList(list *appsv1.ReplicaSetList, currentRevision string) []*appsv1.ReplicaSet {
+	rsList := []*appsv1.ReplicaSet{}
+
+	for _, rs := range list.Items {
+		if _, ok := rs.Labels[appsv2alpha2.PodTemplateHashLabelKey]; ok && !isCurrentRevision(rs.Labels, rs.Spec.Template.ObjectMeta, currentRevision) {
+			rsList = append(rsList, rs.DeepCopy())
+		}
+	}
+
+	sort.Sort(ReplicaSetsByCreationTimestamp(rsList))
+	return rsList
+}
+
 func handlerStatefulSetList(list *appsv1.StatefulSetList) []*appsv1.StatefulSet {
 	stsList := []*appsv1.StatefulSet{}
 
@@ -173,6 +410,10 @@ func justCheckPodTemplate() patch.CalculateOption {
 		if _, ok := podTemplateSpec.Labels[appsv2alpha2.PodTemplateHashLabelKey]; ok {
 			podTemplateSpec.Labels = appsv2alpha2.CloneAndRemoveLabel(podTemplateSpec.Labels, appsv2alpha2.PodTemplateHashLabelKey)
 		}
+		// Remove the podTemplateRevisionAnnotationKey from the podTemplateSpec
+		if _, ok := podTemplateSpec.Annotations[appsv2alpha2.PodTemplateRevisionAnnotationKey]; ok {
+			podTemplateSpec.Annotations = appsv2alpha2.CloneAndRemoveLabel(podTemplateSpec.Annotations, appsv2alpha2.PodTemplateRevisionAnnotationKey)
+		}
 
 		emptyRs := &appsv1.ReplicaSet{}
 		emptyRs.Spec.Template = *podTemplateSpec
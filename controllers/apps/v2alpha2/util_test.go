@@ -1,20 +1,28 @@
 package v2alpha2
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func TestCanBeScaledDown(t *testing.T) {
+func TestGetScaleDownWaiting(t *testing.T) {
 	t.Run("event list is empty, current replicaSet is not available, can not scale down", func(t *testing.T) {
-		assert.False(t, canBeScaledDown(&appsv2alpha2.EMQX{}, appsv2alpha2.CoreNodesReady, []*corev1.Event{}))
+		reason, _ := getScaleDownWaiting(&appsv2alpha2.EMQX{}, appsv2alpha2.CoreNodesReady, []*corev1.Event{})
+		assert.Equal(t, "WaitingForReady", reason)
 	})
 
 	t.Run("event list is empty, initialDelaySeconds not ready, can not scale down", func(t *testing.T) {
@@ -30,7 +38,8 @@ func TestCanBeScaledDown(t *testing.T) {
 				},
 			},
 		}
-		assert.False(t, canBeScaledDown(emqx, appsv2alpha2.CoreNodesReady, []*corev1.Event{}))
+		reason, _ := getScaleDownWaiting(emqx, appsv2alpha2.CoreNodesReady, []*corev1.Event{})
+		assert.Equal(t, "WaitingForInitialDelay", reason)
 	})
 
 	t.Run("event list is empty, initialDelaySeconds is ready, can scale down", func(t *testing.T) {
@@ -51,15 +60,17 @@ func TestCanBeScaledDown(t *testing.T) {
 			},
 		}
 
-		assert.True(t, canBeScaledDown(emqx, appsv2alpha2.CoreNodesReady, []*corev1.Event{}))
+		reason, _ := getScaleDownWaiting(emqx, appsv2alpha2.CoreNodesReady, []*corev1.Event{})
+		assert.Equal(t, "", reason)
 	})
 
 	t.Run("event list not empty, current replicaSet is not available, can not scale down", func(t *testing.T) {
-		assert.False(t, canBeScaledDown(&appsv2alpha2.EMQX{}, appsv2alpha2.CoreNodesReady, []*corev1.Event{
+		reason, _ := getScaleDownWaiting(&appsv2alpha2.EMQX{}, appsv2alpha2.CoreNodesReady, []*corev1.Event{
 			{
 				LastTimestamp: metav1.Time{Time: time.Now().AddDate(0, 0, 1)},
 			},
-		}))
+		})
+		assert.Equal(t, "WaitingForReady", reason)
 	})
 
 	t.Run("event list is not empty, initialDelaySeconds is ready, waitTakeover not ready, can not scale down", func(t *testing.T) {
@@ -89,7 +100,8 @@ func TestCanBeScaledDown(t *testing.T) {
 			},
 		}
 
-		assert.False(t, canBeScaledDown(emqx, appsv2alpha2.CoreNodesReady, eventList))
+		reason, _ := getScaleDownWaiting(emqx, appsv2alpha2.CoreNodesReady, eventList)
+		assert.Equal(t, "WaitingForTakeover", reason)
 	})
 
 	t.Run("event list is not empty,initialDelaySeconds is ready, waitTakeover is ready, can scale down", func(t *testing.T) {
@@ -119,7 +131,38 @@ func TestCanBeScaledDown(t *testing.T) {
 			},
 		}
 
-		assert.True(t, canBeScaledDown(emqx, appsv2alpha2.CoreNodesReady, eventList))
+		reason, _ := getScaleDownWaiting(emqx, appsv2alpha2.CoreNodesReady, eventList)
+		assert.Equal(t, "", reason)
+	})
+}
+
+func TestScaleDownWaiting(t *testing.T) {
+	w := &scaleDownWaiting{}
+	instance := &appsv2alpha2.EMQX{ObjectMeta: metav1.ObjectMeta{Name: "emqx", Namespace: "default"}}
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "emqx-core-old", Namespace: "default"}}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "emqx-replicant-old", Namespace: "default"}}
+
+	t.Run("not waiting", func(t *testing.T) {
+		assert.False(t, w.update(instance, sts, ""))
+	})
+
+	t.Run("reason changed", func(t *testing.T) {
+		assert.True(t, w.update(instance, sts, "WaitingForInitialDelay"))
+		assert.False(t, w.update(instance, sts, "WaitingForInitialDelay"))
+		assert.True(t, w.update(instance, rs, "WaitingForInitialDelay"))
+		assert.True(t, w.update(instance, sts, "WaitingForTakeover"))
+		assert.False(t, w.update(instance, sts, "WaitingForTakeover"))
+	})
+
+	t.Run("not waiting any more", func(t *testing.T) {
+		assert.True(t, w.update(instance, sts, ""))
+		assert.True(t, w.update(instance, sts, "WaitingForTakeover"))
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		w.forget(client.ObjectKeyFromObject(instance))
+		assert.Empty(t, w.reasons)
+		assert.True(t, w.update(instance, rs, "WaitingForInitialDelay"))
 	})
 }
 
@@ -273,6 +316,111 @@ func TestHandlerReplicaSetList(t *testing.T) {
 	})
 }
 
+func TestHandlerHistoryStatefulSetList(t *testing.T) {
+	list := &appsv1.StatefulSetList{
+		Items: []appsv1.StatefulSet{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "emqx-core-current",
+					Labels:            map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "current"},
+					CreationTimestamp: metav1.Time{Time: time.Now()},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "emqx-core-new",
+					Labels:            map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "new"},
+					CreationTimestamp: metav1.Time{Time: time.Now().AddDate(0, 0, -1)},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "emqx-core-old",
+					Labels:            map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "old"},
+					CreationTimestamp: metav1.Time{Time: time.Now().AddDate(0, 0, -2)},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "emqx-core-fake",
+				},
+			},
+		},
+	}
+
+	var l []string
+	for _, d := range handlerHistoryStatefulSetList(list, "current") {
+		l = append(l, d.Name)
+	}
+	assert.Equal(t, []string{"emqx-core-old", "emqx-core-new"}, l)
+}
+
+func TestHandlerHistoryReplicaSetList(t *testing.T) {
+	list := &appsv1.ReplicaSetList{
+		Items: []appsv1.ReplicaSet{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "emqx-replicant-current",
+					Labels:            map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "current"},
+					CreationTimestamp: metav1.Time{Time: time.Now()},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "emqx-replicant-new",
+					Labels:            map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "new"},
+					CreationTimestamp: metav1.Time{Time: time.Now().AddDate(0, 0, -1)},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "emqx-replicant-old",
+					Labels:            map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "old"},
+					CreationTimestamp: metav1.Time{Time: time.Now().AddDate(0, 0, -2)},
+				},
+			},
+		},
+	}
+
+	var l []string
+	for _, d := range handlerHistoryReplicaSetList(list, "current") {
+		l = append(l, d.Name)
+	}
+	assert.Equal(t, []string{"emqx-replicant-old", "emqx-replicant-new"}, l)
+}
+
+func TestGetRevision(t *testing.T) {
+	template := metav1.ObjectMeta{
+		Labels: map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "hash"},
+	}
+	assert.Equal(t, "hash", getRevision(template))
+
+	template.Annotations = map[string]string{appsv2alpha2.PodTemplateRevisionAnnotationKey: "rolled"}
+	assert.Equal(t, "rolled", getRevision(template))
+}
+
+func TestIsCurrentRevision(t *testing.T) {
+	labels := map[string]string{appsv2alpha2.PodTemplateHashLabelKey: "hash"}
+	template := metav1.ObjectMeta{
+		Labels:      labels,
+		Annotations: map[string]string{appsv2alpha2.PodTemplateRevisionAnnotationKey: "rolled"},
+	}
+
+	t.Run("rolled in place", func(t *testing.T) {
+		assert.True(t, isCurrentRevision(labels, template, "rolled"))
+	})
+
+	t.Run("status not updated after rolling update", func(t *testing.T) {
+		assert.True(t, isCurrentRevision(labels, template, "hash"))
+	})
+
+	t.Run("other revision", func(t *testing.T) {
+		assert.False(t, isCurrentRevision(labels, template, "other"))
+		assert.False(t, isCurrentRevision(labels, template, ""))
+		assert.False(t, isCurrentRevision(nil, template, "rolled"))
+	})
+}
+
 func TestHandlerEventList(t *testing.T) {
 	t.Run("filter event", func(t *testing.T) {
 		list := &corev1.EventList{
@@ -315,3 +463,84 @@ func TestHandlerEventList(t *testing.T) {
 		assert.ElementsMatch(t, []string{"emqx-0", "emqx-1"}, l)
 	})
 }
+
+func TestStartEvacuationByAPI(t *testing.T) {
+	instance := &appsv2alpha2.EMQX{
+		Spec: appsv2alpha2.EMQXSpec{
+			UpdateStrategy: appsv2alpha2.UpdateStrategy{
+				EvacuationStrategy: appsv2alpha2.EvacuationStrategy{
+					ConnEvictRate: 100,
+					SessEvictRate: 50,
+					WaitTakeover:  10,
+				},
+			},
+		},
+	}
+
+	t.Run("should start evacuation", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, "/api/v5/load_rebalance/emqx@10.0.0.1/evacuation/start", r.URL.Path)
+
+			body, _ := io.ReadAll(r.Body)
+			m := map[string]interface{}{}
+			_ = json.Unmarshal(body, &m)
+			assert.Equal(t, map[string]interface{}{
+				"conn_evict_rate": float64(100),
+				"sess_evict_rate": float64(50),
+				"wait_takeover":   float64(10),
+				"migrate_to":      []interface{}{"emqx@10.0.0.2"},
+			}, m)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		r := &innerReq.Requester{Host: server.Listener.Addr().String()}
+		assert.Nil(t, startEvacuationByAPI(context.Background(), r, instance, []string{"emqx@10.0.0.2"}, "emqx@10.0.0.1"))
+	})
+
+	t.Run("should return error when status code is not 200", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"BAD_REQUEST","message":"already_started"}`))
+		}))
+		defer server.Close()
+
+		r := &innerReq.Requester{Host: server.Listener.Addr().String()}
+		assert.ErrorContains(t, startEvacuationByAPI(context.Background(), r, instance, []string{"emqx@10.0.0.2"}, "emqx@10.0.0.1"), "already_started")
+	})
+}
+
+func TestGetEMQXNodeNamesByPods(t *testing.T) {
+	nodes := []appsv2alpha2.EMQXNode{
+		{Node: "emqx@10.0.0.1", Role: "replicant", PodName: "emqx-replicant-a"},
+		{Node: "emqx@10.0.0.2", Role: "replicant", PodName: "emqx-replicant-b"},
+	}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "emqx-replicant-a"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "emqx-replicant-c"}},
+	}
+	assert.Equal(t, []string{"emqx@10.0.0.1"}, getEMQXNodeNamesByPods(nodes, pods))
+}
+
+func TestSummarizePatch(t *testing.T) {
+	t.Run("changed container image", func(t *testing.T) {
+		patch := `{"spec":{"template":{"spec":{"$setElementOrder/containers":[{"name":"emqx"}],"containers":[{"image":"emqx:5.0.9","name":"emqx"}]}}}}`
+		assert.Equal(t, "spec.containers[emqx].image", summarizePatch([]byte(patch)))
+	})
+
+	t.Run("changed labels and list", func(t *testing.T) {
+		patch := `{"spec":{"template":{"metadata":{"labels":{"foo":"bar","removed":null}},"spec":{"tolerations":[{"key":"foo","operator":"Exists"}]}}}}`
+		assert.Equal(t, "metadata.labels.foo, metadata.labels.removed, spec.tolerations", summarizePatch([]byte(patch)))
+	})
+
+	t.Run("deleted container", func(t *testing.T) {
+		patch := `{"spec":{"template":{"spec":{"containers":[{"$patch":"delete","name":"sidecar"}]}}}}`
+		assert.Equal(t, "spec.containers[sidecar]", summarizePatch([]byte(patch)))
+	})
+
+	t.Run("too many fields", func(t *testing.T) {
+		patch := `{"spec":{"template":{"metadata":{"labels":{"a":"1","b":"2","c":"3","d":"4","e":"5","f":"6","g":"7"}}}}}`
+		assert.Equal(t, "metadata.labels.a, metadata.labels.b, metadata.labels.c, metadata.labels.d, metadata.labels.e and 2 more", summarizePatch([]byte(patch)))
+	})
+}
@@ -0,0 +1,80 @@
+package v2alpha2
+
+import (
+	"context"
+	"reflect"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// mapEndpointsToEMQX enqueues the EMQX custom resource that owns the service of the endpoints,
+// the endpoints are created by Kubernetes without owner reference, but have the same name as the service.
+func (r *EMQXReconciler) mapEndpointsToEMQX(obj client.Object) []reconcile.Request {
+	svc := &corev1.Service{}
+	if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(obj), svc); err != nil {
+		return nil
+	}
+	return getEMQXRequestByOwner(svc)
+}
+
+// mapPodToEMQX enqueues the EMQX custom resource by the instance label of the pod
+func mapPodToEMQX(obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	// The pods of EMQX 4 custom resources have the instance label too, but not the db role label
+	if _, ok := labels[appsv2alpha2.DBRoleLabelKey]; !ok || labels[appsv2alpha2.InstanceNameLabelKey] == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: labels[appsv2alpha2.InstanceNameLabelKey]}},
+	}
+}
+
+func getEMQXRequestByOwner(obj client.Object) []reconcile.Request {
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil || owner.Kind != emqxKind {
+		return nil
+	}
+	if gv, err := schema.ParseGroupVersion(owner.APIVersion); err != nil || gv.Group != appsv2alpha2.GroupVersion.Group {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: owner.Name}},
+	}
+}
+
+// podChangedPredicate only passes the pod updates that may change the EMQX cluster,
+// the pods are updated frequently, e.g. by the kubelet, and most of the updates do not matter.
+var podChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		newPod, ok := e.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		return oldPod.Status.Phase != newPod.Status.Phase ||
+			oldPod.Status.PodIP != newPod.Status.PodIP ||
+			oldPod.Spec.NodeName != newPod.Spec.NodeName ||
+			(oldPod.DeletionTimestamp == nil) != (newPod.DeletionTimestamp == nil) ||
+			!reflect.DeepEqual(oldPod.Labels, newPod.Labels) ||
+			!reflect.DeepEqual(getPodConditionStatuses(oldPod), getPodConditionStatuses(newPod))
+	},
+}
+
+func getPodConditionStatuses(pod *corev1.Pod) map[corev1.PodConditionType]corev1.ConditionStatus {
+	statuses := map[corev1.PodConditionType]corev1.ConditionStatus{}
+	for _, c := range pod.Status.Conditions {
+		statuses[c.Type] = c.Status
+	}
+	return statuses
+}
@@ -0,0 +1,101 @@
+package v2alpha2
+
+import (
+	"testing"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestMapPodToEMQX(t *testing.T) {
+	t.Run("EMQX 5 pod", func(t *testing.T) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "emqx-core-0",
+				Labels: map[string]string{
+					appsv2alpha2.InstanceNameLabelKey: "emqx",
+					appsv2alpha2.DBRoleLabelKey:       "core",
+				},
+			},
+		}
+		assert.Equal(t, []reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: "default", Name: "emqx"}},
+		}, mapPodToEMQX(pod))
+	})
+
+	t.Run("EMQX 4 pod", func(t *testing.T) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "emqx-ee-0",
+				Labels: map[string]string{
+					appsv2alpha2.InstanceNameLabelKey: "emqx-ee",
+				},
+			},
+		}
+		assert.Nil(t, mapPodToEMQX(pod))
+	})
+}
+
+func TestGetEMQXRequestByOwner(t *testing.T) {
+	newSvc := func(apiVersion, kind string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "emqx-dashboard",
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: apiVersion, Kind: kind, Name: "emqx", Controller: pointer.Bool(true)},
+				},
+			},
+		}
+	}
+
+	assert.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: "emqx"}},
+	}, getEMQXRequestByOwner(newSvc("apps.emqx.io/v2alpha2", "EMQX")))
+	assert.Nil(t, getEMQXRequestByOwner(newSvc("apps.emqx.io/v1beta4", "EmqxBroker")))
+	assert.Nil(t, getEMQXRequestByOwner(newSvc("example.com/v1", "EMQX")))
+	assert.Nil(t, getEMQXRequestByOwner(&corev1.Service{}))
+}
+
+func TestPodChangedPredicate(t *testing.T) {
+	oldPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "emqx-core-0",
+			ResourceVersion: "1",
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: "10.0.0.1",
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+
+	t.Run("irrelevant change", func(t *testing.T) {
+		newPod := oldPod.DeepCopy()
+		newPod.ResourceVersion = "2"
+		newPod.Status.Conditions[0].LastProbeTime = metav1.Now()
+		assert.False(t, podChangedPredicate.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}))
+	})
+
+	t.Run("pod is ready", func(t *testing.T) {
+		newPod := oldPod.DeepCopy()
+		newPod.Status.Conditions[0].Status = corev1.ConditionTrue
+		assert.True(t, podChangedPredicate.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}))
+	})
+
+	t.Run("pod is deleting", func(t *testing.T) {
+		newPod := oldPod.DeepCopy()
+		newPod.DeletionTimestamp = &metav1.Time{}
+		assert.True(t, podChangedPredicate.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}))
+	})
+}
@@ -0,0 +1,70 @@
+package emqxapi
+
+import (
+	"context"
+	"encoding/json"
+
+	emperror "emperror.dev/errors"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	"github.com/tidwall/gjson"
+)
+
+type client struct {
+	requester innerReq.RequesterInterface
+}
+
+// request sends the request with the JSON body, and returns the response body if the status code is 2xx,
+// or an *Error with the EMQX error code otherwise.
+func (c *client) request(ctx context.Context, method, path string, reqBody any) ([]byte, error) {
+	var b []byte
+	if reqBody != nil {
+		var err error
+		if b, err = json.Marshal(reqBody); err != nil {
+			return nil, emperror.Wrapf(err, "failed to marshal request of API %s %s", method, path)
+		}
+	}
+	resp, body, err := c.requester.Request(ctx, method, path, b)
+	if err != nil {
+		return nil, emperror.Wrapf(err, "failed to request API %s %s", method, path)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newError(method, path, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// newError returns the error with the code and message in the response body, like {"code": "BAD_REQUEST", "message": "..."},
+// the body is used as the message if it is not a JSON error.
+func newError(method, path string, statusCode int, body []byte) *Error {
+	e := &Error{Method: method, Path: path, StatusCode: statusCode}
+	if result := gjson.ParseBytes(body); gjson.ValidBytes(body) && result.IsObject() {
+		e.Code = result.Get("code").String()
+		e.Message = result.Get("message").String()
+	}
+	if e.Code == "" && e.Message == "" {
+		e.Message = string(body)
+		if len(e.Message) > maxErrorBodyLength {
+			e.Message = e.Message[:maxErrorBodyLength] + "..."
+		}
+	}
+	return e
+}
+
+// decode unmarshals the value of the key in the response body into v, the whole body is unmarshalled if the key is empty
+func decode(path string, body []byte, key string, v any) error {
+	raw := body
+	if key != "" {
+		result := gjson.GetBytes(body, key)
+		if !result.Exists() {
+			return emperror.Errorf("failed to unmarshal %s of API %s: %s not found", key, path, key)
+		}
+		raw = []byte(result.Raw)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		if key == "" {
+			key = "response"
+		}
+		return emperror.Wrapf(err, "failed to unmarshal %s of API %s", key, path)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package emqxapi
+
+import (
+	"fmt"
+	"net/http"
+
+	emperror "emperror.dev/errors"
+)
+
+// maxErrorBodyLength is the max length of the response body kept in the error if it is not a JSON error
+const maxErrorBodyLength = 256
+
+// Error is returned when the EMQX management API responds with an error
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Code is the error code of EMQX, like BAD_REQUEST in EMQX 5, or 400 in EMQX 4
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("failed to request API %s %s, status: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		msg += ", code: " + e.Code
+	}
+	if e.Message != "" {
+		msg += ", message: " + e.Message
+	}
+	return msg
+}
+
+// IsCode returns true if the error is returned by the EMQX management API with the code
+func IsCode(err error, code string) bool {
+	var apiErr *Error
+	return emperror.As(err, &apiErr) && apiErr.Code == code
+}
+
+// IsNotFound returns true if the resource requested by the EMQX management API is not found
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return emperror.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.Code == "NOT_FOUND")
+}
+
+// IsAPIError returns true if the error is returned by the EMQX management API,
+// false if the request failed without response, like the EMQX node is unreachable.
+func IsAPIError(err error) bool {
+	var apiErr *Error
+	return emperror.As(err, &apiErr)
+}
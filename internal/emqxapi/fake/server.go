@@ -0,0 +1,550 @@
+// Package fake provides an in-process fake of the EMQX 4 and 5 management API for the controller tests.
+// The cluster state is kept in memory, it is changed by the API requests and by the methods of Server,
+// like the nodes joining and leaving the cluster, and the evacuations and rebalances moving forward by Step.
+package fake
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+)
+
+// Node is an EMQX node in the fake cluster
+type Node struct {
+	// Name is the EMQX node name, like emqx@10.0.0.1
+	Name string
+	// Role is core or replicant, it is ignored by the EMQX 4 API
+	Role    string
+	Version string
+	// Edition is Opensource or Enterprise
+	Edition     string
+	Connections int64
+	Sessions    int64
+}
+
+type Plugin struct {
+	Name        string
+	Version     string
+	Description string
+}
+
+type Alarm struct {
+	Node    string
+	Name    string
+	Message string
+}
+
+type gateway struct {
+	name      string
+	listeners []emqxapi.Listener
+}
+
+type plugin struct {
+	Plugin
+	// running is the nodes the plugin is running on
+	running map[string]bool
+}
+
+type evacuation struct {
+	node               string
+	req                emqxapi.EvacuationRequest
+	state              string
+	initialConnections int64
+	initialSessions    int64
+}
+
+type rebalance struct {
+	coordinator string
+	req         emqxapi.RebalanceRequest
+	state       string
+	donors      []string
+	recipients  []string
+}
+
+// Server is the fake EMQX management API server, it serves the EMQX 4 API under api/v4 and the EMQX 5 API under api/v5,
+// the requests are handled as if they are received by the local node, which is the first node by default.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	username    string
+	password    string
+	apiKeys     []*emqxapi.APIKey
+	localNode   string
+	nodes       []*Node
+	listeners   []emqxapi.Listener
+	gateways    []*gateway
+	plugins     []*plugin
+	alarms      []Alarm
+	metrics     map[string]int64
+	evacuations []*evacuation
+	rebalances  []*rebalance
+	requests    []string
+}
+
+// NewServer starts the fake EMQX management API server, the caller should call Close when finished
+func NewServer() *Server {
+	s := &Server{metrics: map[string]int64{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Requester returns the requester of the server with the basic auth credentials, the failed requests are not retried
+func (s *Server) Requester() *innerReq.Requester {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &innerReq.Requester{
+		Host:     s.Listener.Addr().String(),
+		Username: s.username,
+		Password: s.password,
+		Retries:  -1,
+	}
+}
+
+// Port returns the port the server listens on, it is used as the dashboard port of the EMQX custom resource
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	return port
+}
+
+// SetBasicAuth requires the requests to the API, except the status, to be authorized by the API keys,
+// the username and password is added as the API key from the bootstrap file, it can be deleted by the API like others.
+func (s *Server) SetBasicAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username, s.password = username, password
+	s.apiKeys = append(s.apiKeys, &emqxapi.APIKey{
+		Name:      "from_bootstrap_file_" + username,
+		APIKey:    username,
+		APISecret: password,
+		Enable:    true,
+	})
+}
+
+// APIKeys returns the names of the API keys
+func (s *Server) APIKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := []string{}
+	for _, key := range s.apiKeys {
+		names = append(names, key.Name)
+	}
+	return names
+}
+
+// SetLocalNode sets the node receiving the requests, the node can not force leave itself,
+// and it is unavailable when it is being evacuated or rebalanced.
+func (s *Server) SetLocalNode(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.localNode = name
+}
+
+// AddNode joins the nodes to the cluster, the running plugins are started on the nodes
+func (s *Server) AddNode(nodes ...Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range nodes {
+		node := nodes[i]
+		if node.Edition == "" {
+			node.Edition = "Opensource"
+		}
+		s.nodes = append(s.nodes, &node)
+		for _, p := range s.plugins {
+			if len(p.running) > 0 {
+				p.running[node.Name] = true
+			}
+		}
+	}
+	if s.localNode == "" && len(s.nodes) > 0 {
+		s.localNode = s.nodes[0].Name
+	}
+}
+
+// RemoveNode makes the node leave the cluster, it returns false if the node is not in the cluster
+func (s *Server) RemoveNode(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.removeNode(name)
+}
+
+// UpdateNode changes the node by the function, like the connections and sessions of the node
+func (s *Server) UpdateNode(name string, update func(node *Node)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node := s.findNode(name)
+	if node == nil {
+		return false
+	}
+	update(node)
+	return true
+}
+
+// Nodes returns a copy of the nodes in the cluster
+func (s *Server) Nodes() []Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nodes := make([]Node, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		nodes = append(nodes, *node)
+	}
+	return nodes
+}
+
+// AddListener adds the listener to all the nodes
+func (s *Server) AddListener(listeners ...emqxapi.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listeners...)
+}
+
+func (s *Server) AddGateway(name string, listeners ...emqxapi.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gateways = append(s.gateways, &gateway{name: name, listeners: listeners})
+}
+
+// AddPlugin installs the plugin, it is running on all the nodes if running is true
+func (s *Server) AddPlugin(p Plugin, running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	installed := &plugin{Plugin: p, running: map[string]bool{}}
+	if running {
+		for _, node := range s.nodes {
+			installed.running[node.Name] = true
+		}
+	}
+	s.plugins = append(s.plugins, installed)
+}
+
+// IsPluginRunning returns true if the plugin is running on the node
+func (s *Server) IsPluginRunning(name, node string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.plugins {
+		if p.Name == name {
+			return p.running[node]
+		}
+	}
+	return false
+}
+
+func (s *Server) ActivateAlarm(alarm Alarm) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alarms = append(s.alarms, alarm)
+}
+
+func (s *Server) DeactivateAlarm(node, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alarms := []Alarm{}
+	for _, alarm := range s.alarms {
+		if alarm.Node != node || alarm.Name != name {
+			alarms = append(alarms, alarm)
+		}
+	}
+	s.alarms = alarms
+}
+
+// SetMetric sets the value of the metric or the stat of the cluster, like messages.received or topics.count
+func (s *Server) SetMetric(key string, value int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics[key] = value
+}
+
+// Requests returns the requests received by the server, like "GET api/v5/nodes"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// Step moves the evacuations and rebalances forward for one second: the connections and sessions are moved from
+// the evacuated nodes and the donors to the other nodes by the eviction rates, the rebalances are completed
+// when the connections are balanced, and the evacuated nodes are kept prohibiting until they leave the cluster.
+func (s *Server) Step() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.evacuations {
+		node := s.findNode(e.node)
+		recipients := s.findNodes(e.req.MigrateTo)
+		switch {
+		case node.Connections > 0:
+			node.Connections -= move(node.Connections, e.req.ConnEvictRate, recipients, func(n *Node, c int64) { n.Connections += c })
+		case node.Sessions > 0:
+			node.Sessions -= move(node.Sessions, e.req.SessEvictRate, recipients, func(n *Node, c int64) { n.Sessions += c })
+		}
+		e.state = evacuationState(node)
+	}
+
+	rebalances := []*rebalance{}
+	for _, r := range s.rebalances {
+		if r.state == "wait_health_check" {
+			r.state = "evicting_conns"
+			rebalances = append(rebalances, r)
+			continue
+		}
+		average := s.averageConnections(r.req.Nodes)
+		balanced := true
+		for _, donor := range s.findNodes(r.donors) {
+			if excess := donor.Connections - average; excess > int64(r.req.AbsConnThreshold) {
+				donor.Connections -= move(excess, r.req.ConnEvictRate, s.findNodes(r.recipients), func(n *Node, c int64) { n.Connections += c })
+				balanced = balanced && donor.Connections-average <= int64(r.req.AbsConnThreshold)
+			}
+		}
+		if !balanced {
+			rebalances = append(rebalances, r)
+		}
+	}
+	s.rebalances = rebalances
+}
+
+// move moves the count limited by the rate to the recipients evenly, it returns the count moved
+func move(count int64, rate int32, recipients []*Node, add func(*Node, int64)) int64 {
+	if len(recipients) == 0 {
+		return 0
+	}
+	if rate > 0 && count > int64(rate) {
+		count = int64(rate)
+	}
+	for i, n := range recipients {
+		share := count / int64(len(recipients))
+		if int64(i) < count%int64(len(recipients)) {
+			share++
+		}
+		add(n, share)
+	}
+	return count
+}
+
+func evacuationState(node *Node) string {
+	switch {
+	case node.Connections > 0:
+		return "evicting_conns"
+	case node.Sessions > 0:
+		return "evicting_sessions"
+	default:
+		return "prohibiting"
+	}
+}
+
+func (s *Server) averageConnections(names []string) int64 {
+	nodes := s.findNodes(names)
+	if len(nodes) == 0 {
+		return 0
+	}
+	var total int64
+	for _, n := range nodes {
+		total += n.Connections
+	}
+	return total / int64(len(nodes))
+}
+
+func (s *Server) findNode(name string) *Node {
+	for _, node := range s.nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+func (s *Server) findNodes(names []string) []*Node {
+	nodes := []*Node{}
+	for _, name := range names {
+		if node := s.findNode(name); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (s *Server) findEvacuation(node string) *evacuation {
+	for _, e := range s.evacuations {
+		if e.node == node {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *Server) findPlugin(name string) *plugin {
+	for _, p := range s.plugins {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// removeNode removes the node with its evacuation and alarms
+func (s *Server) removeNode(name string) bool {
+	if s.findNode(name) == nil {
+		return false
+	}
+	nodes := []*Node{}
+	for _, node := range s.nodes {
+		if node.Name != name {
+			nodes = append(nodes, node)
+		}
+	}
+	s.nodes = nodes
+	evacuations := []*evacuation{}
+	for _, e := range s.evacuations {
+		if e.node != name {
+			evacuations = append(evacuations, e)
+		}
+	}
+	s.evacuations = evacuations
+	alarms := []Alarm{}
+	for _, alarm := range s.alarms {
+		if alarm.Node != name {
+			alarms = append(alarms, alarm)
+		}
+	}
+	s.alarms = alarms
+	if s.localNode == name {
+		s.localNode = ""
+		if len(s.nodes) > 0 {
+			s.localNode = s.nodes[0].Name
+		}
+	}
+	return true
+}
+
+// startEvacuation starts to evacuate the node, it returns the error message if the request is invalid
+func (s *Server) startEvacuation(name string, req emqxapi.EvacuationRequest) string {
+	node := s.findNode(name)
+	if node == nil {
+		return "node_not_found"
+	}
+	if s.findEvacuation(name) != nil {
+		return "already_started"
+	}
+	if len(req.MigrateTo) == 0 || len(s.findNodes(req.MigrateTo)) != len(req.MigrateTo) {
+		return "invalid_migrate_to"
+	}
+	s.evacuations = append(s.evacuations, &evacuation{
+		node:               name,
+		req:                req,
+		state:              evacuationState(node),
+		initialConnections: node.Connections,
+		initialSessions:    node.Sessions,
+	})
+	return ""
+}
+
+// isAvailable returns false if the local node is being evacuated or it is a donor of a rebalance
+func (s *Server) isAvailable() bool {
+	if s.findEvacuation(s.localNode) != nil {
+		return false
+	}
+	for _, r := range s.rebalances {
+		for _, donor := range r.donors {
+			if donor == s.localNode {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isAuthorized returns true if no API key is required or the request has the key and secret of an enabled API key
+func (s *Server) isAuthorized(r *http.Request) bool {
+	if len(s.apiKeys) == 0 {
+		return true
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	for _, key := range s.apiKeys {
+		if key.Enable && key.APIKey == username && key.APISecret == password {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	s.requests = append(s.requests, r.Method+" "+path)
+
+	if path == "status" {
+		if s.localNode == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("Node " + s.localNode + " is started\nemqx is running"))
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	if len(segments) < 3 || segments[0] != "api" {
+		http.NotFound(w, r)
+		return
+	}
+	routes := s.v5Routes()
+	if segments[1] == "v4" {
+		routes = s.v4Routes()
+	}
+
+	if !s.isAuthorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"code": "BAD_API_KEY_OR_SECRET", "message": "Check api_key/api_secret"})
+		return
+	}
+
+	for _, route := range routes {
+		if params, ok := match(route.pattern, segments[2:]); ok && route.method == r.Method {
+			route.handle(w, r, params)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+type route struct {
+	method string
+	// pattern is the path after the API version, the segments starting with a colon match any value
+	pattern string
+	handle  func(w http.ResponseWriter, r *http.Request, params []string)
+}
+
+// match returns the values of the parameters in the pattern if the segments match the pattern
+func match(pattern string, segments []string) ([]string, bool) {
+	parts := strings.Split(pattern, "/")
+	if len(parts) != len(segments) {
+		return nil, false
+	}
+	params := []string{}
+	for i, part := range parts {
+		switch {
+		case strings.HasPrefix(part, ":"):
+			params = append(params, segments[i])
+		case part != segments[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func readJSON(r *http.Request, v any) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
@@ -0,0 +1,298 @@
+package fake
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodes(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := emqxapi.NewV5(s.Requester())
+
+	s.AddNode(
+		Node{Name: "emqx@10.0.0.1", Role: "core", Version: "5.0.20"},
+		Node{Name: "emqx@10.0.0.2", Role: "replicant", Version: "5.0.20"},
+	)
+	nodes, err := c.Nodes(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "Opensource", nodes[0].Edition)
+
+	t.Run("force leave", func(t *testing.T) {
+		err := c.ForceLeave(context.Background(), "emqx@10.0.0.1")
+		assert.ErrorContains(t, err, "cannot_force_leave_self")
+
+		assert.Nil(t, c.ForceLeave(context.Background(), "emqx@10.0.0.2"))
+		err = c.ForceLeave(context.Background(), "emqx@10.0.0.2")
+		assert.True(t, emqxapi.IsCode(err, "BAD_REQUEST"))
+		assert.ErrorContains(t, err, "node_not_found")
+	})
+
+	t.Run("leave", func(t *testing.T) {
+		assert.True(t, s.RemoveNode("emqx@10.0.0.1"))
+		nodes, err := c.Nodes(context.Background())
+		assert.Nil(t, err)
+		assert.Empty(t, nodes)
+
+		resp, _, err := s.Requester().Request(context.Background(), "GET", "status", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+}
+
+func TestBasicAuth(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddNode(Node{Name: "emqx@10.0.0.1"})
+
+	r := s.Requester()
+	s.SetBasicAuth("emqx-operator-controller", "secret")
+	_, err := emqxapi.NewV5(r).Nodes(context.Background())
+	assert.True(t, emqxapi.IsCode(err, "BAD_API_KEY_OR_SECRET"))
+
+	_, err = emqxapi.NewV5(s.Requester()).Nodes(context.Background())
+	assert.Nil(t, err)
+
+	// The status is not authorized
+	resp, _, err := r.Request(context.Background(), "GET", "status", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAPIKeys(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddNode(Node{Name: "emqx@10.0.0.1"})
+	s.SetBasicAuth("emqx-operator-controller", "secret")
+
+	c := emqxapi.NewV5(s.Requester())
+	created, err := c.CreateAPIKey(context.Background(), emqxapi.APIKey{Name: "rotated", Enable: true})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, created.APISecret)
+	_, err = c.CreateAPIKey(context.Background(), emqxapi.APIKey{Name: "rotated", Enable: true})
+	assert.True(t, emqxapi.IsCode(err, "BAD_REQUEST"))
+
+	keys, err := c.APIKeys(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []emqxapi.APIKey{
+		{Name: "from_bootstrap_file_emqx-operator-controller", APIKey: "emqx-operator-controller", Enable: true},
+		{Name: "rotated", APIKey: created.APIKey, Enable: true},
+	}, keys)
+
+	// The created API key is authorized, and the bootstrap one is not after it is deleted
+	r := s.Requester()
+	r.Username, r.Password = created.APIKey, created.APISecret
+	assert.Nil(t, emqxapi.NewV5(r).DeleteAPIKey(context.Background(), "from_bootstrap_file_emqx-operator-controller"))
+	assert.Equal(t, []string{"rotated"}, s.APIKeys())
+	_, err = c.Nodes(context.Background())
+	assert.True(t, emqxapi.IsCode(err, "BAD_API_KEY_OR_SECRET"))
+	_, err = emqxapi.NewV5(r).Nodes(context.Background())
+	assert.Nil(t, err)
+
+	assert.True(t, emqxapi.IsNotFound(emqxapi.NewV5(r).DeleteAPIKey(context.Background(), "not-found")))
+}
+
+func TestListeners(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddNode(Node{Name: "emqx@10.0.0.1"}, Node{Name: "emqx@10.0.0.2"})
+	s.AddListener(emqxapi.Listener{ID: "tcp:default", Type: "tcp", Bind: "0.0.0.0:1883", Enable: true})
+	s.AddGateway("lwm2m", emqxapi.Listener{ID: "lwm2m:udp:default", Type: "udp", Bind: "5783", Enable: true})
+
+	c := emqxapi.NewV5(s.Requester())
+	listeners, err := c.Listeners(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []emqxapi.Listener{{ID: "tcp:default", Type: "tcp", Bind: "0.0.0.0:1883", Enable: true}}, listeners)
+
+	gateways, err := c.Gateways(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []emqxapi.Gateway{{Name: "lwm2m", Status: "running"}}, gateways)
+	listeners, err = c.GatewayListeners(context.Background(), "lwm2m")
+	assert.Nil(t, err)
+	assert.Len(t, listeners, 1)
+	_, err = c.GatewayListeners(context.Background(), "coap")
+	assert.True(t, emqxapi.IsNotFound(err))
+
+	v4Listeners, err := emqxapi.NewV4(s.Requester()).Listeners(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []emqxapi.V4NodeListeners{
+		{Node: "emqx@10.0.0.1", Listeners: []emqxapi.V4Listener{{Protocol: "mqtt:tcp", ListenOn: "0.0.0.0:1883"}}},
+		{Node: "emqx@10.0.0.2", Listeners: []emqxapi.V4Listener{{Protocol: "mqtt:tcp", ListenOn: "0.0.0.0:1883"}}},
+	}, v4Listeners)
+}
+
+func TestEvacuation(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddNode(
+		Node{Name: "emqx@10.0.0.1", Edition: "Enterprise", Connections: 5, Sessions: 10},
+		Node{Name: "emqx@10.0.0.2", Edition: "Enterprise"},
+		Node{Name: "emqx@10.0.0.3", Edition: "Enterprise"},
+	)
+	c := emqxapi.NewV5(s.Requester())
+
+	err := c.StartEvacuation(context.Background(), "emqx@10.0.0.1", emqxapi.EvacuationRequest{
+		ConnEvictRate: 4,
+		SessEvictRate: 10,
+		MigrateTo:     []string{"emqx@10.0.0.2", "emqx@10.0.0.3"},
+	})
+	assert.Nil(t, err)
+	err = c.StartEvacuation(context.Background(), "emqx@10.0.0.1", emqxapi.EvacuationRequest{MigrateTo: []string{"emqx@10.0.0.2"}})
+	assert.ErrorContains(t, err, "already_started")
+
+	// The local node is being evacuated
+	available, err := c.AvailabilityCheck(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, available)
+
+	var states []string
+	for i := 0; i < 4; i++ {
+		evacuations, err := c.EvacuationStatus(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, evacuations, 1)
+		states = append(states, evacuations[0].State)
+		s.Step()
+	}
+	assert.Equal(t, []string{"evicting_conns", "evicting_conns", "evicting_sessions", "prohibiting"}, states)
+
+	nodes := s.Nodes()
+	assert.Equal(t, int64(0), nodes[0].Connections+nodes[0].Sessions)
+	assert.Equal(t, int64(3), nodes[1].Connections)
+	assert.Equal(t, int64(2), nodes[2].Connections)
+	assert.Equal(t, int64(10), nodes[1].Sessions+nodes[2].Sessions)
+
+	// The evacuation is done when the node leaves the cluster
+	s.SetLocalNode("emqx@10.0.0.2")
+	assert.Nil(t, c.ForceLeave(context.Background(), "emqx@10.0.0.1"))
+	evacuations, err := c.EvacuationStatus(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, evacuations)
+
+	v4Status, err := emqxapi.NewV4(s.Requester()).RebalanceStatus(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, v4Status.Evacuations)
+}
+
+func TestRebalance(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddNode(
+		Node{Name: "emqx@10.0.0.1", Connections: 30},
+		Node{Name: "emqx@10.0.0.2"},
+		Node{Name: "emqx@10.0.0.3"},
+	)
+	c := emqxapi.NewV4(s.Requester())
+	req := emqxapi.RebalanceRequest{
+		ConnEvictRate:    10,
+		AbsConnThreshold: 2,
+		Nodes:            []string{"emqx@10.0.0.1", "emqx@10.0.0.2", "emqx@10.0.0.3"},
+	}
+
+	assert.Nil(t, c.StartRebalance(context.Background(), "emqx@10.0.0.2", req))
+	err := c.StartRebalance(context.Background(), "emqx@10.0.0.2", req)
+	assert.ErrorContains(t, err, "already_started")
+	assert.True(t, emqxapi.IsCode(err, "400"))
+
+	status, err := c.RebalanceStatus(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, status.Rebalances, 1)
+	assert.Equal(t, "wait_health_check", status.Rebalances[0].State)
+	assert.Equal(t, "emqx@10.0.0.2", status.Rebalances[0].CoordinatorNode)
+	assert.Equal(t, []string{"emqx@10.0.0.1"}, status.Rebalances[0].Donors)
+	assert.Equal(t, []string{"emqx@10.0.0.2", "emqx@10.0.0.3"}, status.Rebalances[0].Recipients)
+
+	available, err := c.AvailabilityCheck(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, available)
+
+	s.Step()
+	status, _ = c.RebalanceStatus(context.Background())
+	assert.Equal(t, "evicting_conns", status.Rebalances[0].State)
+
+	// 30 connections are balanced after 2 steps by the rate 10
+	s.Step()
+	status, _ = c.RebalanceStatus(context.Background())
+	assert.Len(t, status.Rebalances, 1)
+	s.Step()
+	status, _ = c.RebalanceStatus(context.Background())
+	assert.Empty(t, status.Rebalances)
+	for _, node := range s.Nodes() {
+		assert.Equal(t, int64(10), node.Connections)
+	}
+
+	t.Run("stop rebalance", func(t *testing.T) {
+		err := c.StopRebalance(context.Background(), "emqx@10.0.0.2")
+		assert.ErrorContains(t, err, "not_started")
+
+		s.UpdateNode("emqx@10.0.0.3", func(node *Node) { node.Connections = 50 })
+		assert.Nil(t, c.StartRebalance(context.Background(), "emqx@10.0.0.2", req))
+		assert.Nil(t, c.StopRebalance(context.Background(), "emqx@10.0.0.2"))
+		status, _ = c.RebalanceStatus(context.Background())
+		assert.Empty(t, status.Rebalances)
+	})
+
+	t.Run("nothing to balance", func(t *testing.T) {
+		s.UpdateNode("emqx@10.0.0.3", func(node *Node) { node.Connections = 11 })
+		err := c.StartRebalance(context.Background(), "emqx@10.0.0.2", req)
+		assert.ErrorContains(t, err, "nothing_to_balance")
+	})
+}
+
+func TestPlugins(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddNode(Node{Name: "emqx@10.0.0.1"})
+	s.AddPlugin(Plugin{Name: "emqx_prometheus", Version: "4.4.14"}, false)
+
+	c := emqxapi.NewV4(s.Requester())
+	plugins, err := c.Plugins(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, plugins[0].Plugins[0].Active)
+
+	assert.Nil(t, c.ReloadPlugin(context.Background(), "emqx@10.0.0.1", "emqx_prometheus"))
+	assert.True(t, s.IsPluginRunning("emqx_prometheus", "emqx@10.0.0.1"))
+
+	// The running plugins are started on the nodes joining the cluster
+	s.AddNode(Node{Name: "emqx@10.0.0.2"})
+	plugins, err = c.Plugins(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, plugins[1].Plugins[0].Active)
+
+	assert.Nil(t, c.UnloadPlugin(context.Background(), "emqx@10.0.0.2", "emqx_prometheus"))
+	assert.False(t, s.IsPluginRunning("emqx_prometheus", "emqx@10.0.0.2"))
+
+	err = c.ReloadPlugin(context.Background(), "emqx@10.0.0.1", "emqx_fake")
+	assert.True(t, emqxapi.IsNotFound(err))
+
+	v5Plugins, err := emqxapi.NewV5(s.Requester()).Plugins(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []emqxapi.PluginStatus{
+		{Node: "emqx@10.0.0.1", Status: "running"},
+		{Node: "emqx@10.0.0.2", Status: "stopped"},
+	}, v5Plugins[0].RunningStatus)
+}
+
+func TestAlarms(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddNode(Node{Name: "emqx@10.0.0.1"}, Node{Name: "emqx@10.0.0.2"})
+	s.ActivateAlarm(Alarm{Node: "emqx@10.0.0.2", Name: "high_cpu_usage", Message: "CPU usage is high"})
+
+	alarms, err := emqxapi.NewV5(s.Requester()).Alarms(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, alarms, 1)
+	v4Alarms, err := emqxapi.NewV4(s.Requester()).Alarms(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, v4Alarms, 1)
+	assert.Equal(t, "emqx@10.0.0.2", v4Alarms[0].Node)
+
+	s.DeactivateAlarm("emqx@10.0.0.2", "high_cpu_usage")
+	alarms, err = emqxapi.NewV5(s.Requester()).Alarms(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, alarms)
+}
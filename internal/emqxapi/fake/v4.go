@@ -0,0 +1,228 @@
+package fake
+
+import (
+	"net/http"
+	"sort"
+
+	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"k8s.io/utils/pointer"
+)
+
+func (s *Server) v4Routes() []route {
+	return []route{
+		{http.MethodGet, "nodes", s.v4Nodes},
+		{http.MethodGet, "listeners", s.v4Listeners},
+		{http.MethodGet, "alarms/activated", s.v4Alarms},
+		{http.MethodGet, "load_rebalance/global_status", s.v4GlobalStatus},
+		{http.MethodPost, "load_rebalance/:node/start", s.v4StartRebalance},
+		{http.MethodPost, "load_rebalance/:node/stop", s.v4StopRebalance},
+		{http.MethodPost, "load_rebalance/:node/evacuation/start", s.v4StartEvacuation},
+		{http.MethodGet, "load_rebalance/availability_check", func(w http.ResponseWriter, r *http.Request, _ []string) {
+			if !s.isAvailable() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			v4Data(w, map[string]any{})
+		}},
+		{http.MethodGet, "plugins", s.v4Plugins},
+		{http.MethodPut, "nodes/:node/plugins/:name/:operation", s.v4UpdatePlugin},
+	}
+}
+
+// v4Data writes the data in the response like {"code": 0, "data": ...}
+func v4Data(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusOK, map[string]any{"code": 0, "data": data})
+}
+
+// v4Error writes the error in the response like {"code": 400, "message": ...}, the status code is 200 as EMQX 4 does
+func v4Error(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusOK, map[string]any{"code": 400, "message": message})
+}
+
+func (s *Server) v4Nodes(w http.ResponseWriter, r *http.Request, _ []string) {
+	nodes := []appsv1beta4.EmqxNode{}
+	for _, node := range s.nodes {
+		nodes = append(nodes, appsv1beta4.EmqxNode{
+			Node:        node.Name,
+			NodeStatus:  "Running",
+			Version:     node.Version,
+			Connections: node.Connections,
+		})
+	}
+	v4Data(w, nodes)
+}
+
+func (s *Server) v4Listeners(w http.ResponseWriter, r *http.Request, _ []string) {
+	data := []emqxapi.V4NodeListeners{}
+	for _, node := range s.nodes {
+		listeners := []emqxapi.V4Listener{}
+		for _, l := range s.listeners {
+			listeners = append(listeners, emqxapi.V4Listener{Protocol: "mqtt:" + l.Type, ListenOn: l.Bind})
+		}
+		data = append(data, emqxapi.V4NodeListeners{Node: node.Name, Listeners: listeners})
+	}
+	v4Data(w, data)
+}
+
+func (s *Server) v4Alarms(w http.ResponseWriter, r *http.Request, _ []string) {
+	type nodeAlarms struct {
+		Node   string                  `json:"node"`
+		Alarms []appsv1beta4.EmqxAlarm `json:"alarms"`
+	}
+	data := []nodeAlarms{}
+	for _, node := range s.nodes {
+		alarms := []appsv1beta4.EmqxAlarm{}
+		for _, alarm := range s.alarms {
+			if alarm.Node == node.Name {
+				alarms = append(alarms, appsv1beta4.EmqxAlarm{Name: alarm.Name, Message: alarm.Message})
+			}
+		}
+		data = append(data, nodeAlarms{Node: node.Name, Alarms: alarms})
+	}
+	v4Data(w, data)
+}
+
+func (s *Server) v4GlobalStatus(w http.ResponseWriter, r *http.Request, _ []string) {
+	status := emqxapi.V4RebalanceStatus{
+		Rebalances:  []appsv1beta4.RebalanceState{},
+		Evacuations: []appsv1beta4.EmqxEvacuationStatus{},
+	}
+	for _, r := range s.rebalances {
+		status.Rebalances = append(status.Rebalances, appsv1beta4.RebalanceState{
+			State:                  r.state,
+			CoordinatorNode:        r.coordinator,
+			Node:                   r.coordinator,
+			Donors:                 r.donors,
+			Recipients:             r.recipients,
+			ConnectionEvictionRate: r.req.ConnEvictRate,
+			SessionEvictionRate:    r.req.SessEvictRate,
+		})
+	}
+	for _, e := range s.evacuations {
+		node := s.findNode(e.node)
+		status.Evacuations = append(status.Evacuations, appsv1beta4.EmqxEvacuationStatus{
+			Node:  e.node,
+			State: e.state,
+			Stats: appsv1beta4.EmqxEvacuationStats{
+				InitialConnected: pointer.Int32(int32(e.initialConnections)),
+				InitialSessions:  pointer.Int32(int32(e.initialSessions)),
+				CurrentConnected: pointer.Int32(int32(node.Connections)),
+				CurrentSessions:  pointer.Int32(int32(node.Sessions)),
+			},
+			SessionRecipients:      e.req.MigrateTo,
+			ConnectionEvictionRate: e.req.ConnEvictRate,
+			SessionEvictionRate:    e.req.SessEvictRate,
+		})
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// v4StartRebalance starts to move the connections from the nodes above the average to the nodes below it
+func (s *Server) v4StartRebalance(w http.ResponseWriter, r *http.Request, params []string) {
+	req := emqxapi.RebalanceRequest{}
+	if err := readJSON(r, &req); err != nil {
+		v4Error(w, err.Error())
+		return
+	}
+	if s.findNode(params[0]) == nil {
+		v4Error(w, "node_not_found")
+		return
+	}
+	if len(s.rebalances) > 0 {
+		v4Error(w, "already_started")
+		return
+	}
+	if len(req.Nodes) == 0 || len(s.findNodes(req.Nodes)) != len(req.Nodes) {
+		v4Error(w, "invalid_nodes")
+		return
+	}
+
+	rebalance := &rebalance{coordinator: params[0], req: req, state: "wait_health_check"}
+	average := s.averageConnections(req.Nodes)
+	for _, node := range s.findNodes(req.Nodes) {
+		if node.Connections-average > int64(req.AbsConnThreshold) {
+			rebalance.donors = append(rebalance.donors, node.Name)
+		} else {
+			rebalance.recipients = append(rebalance.recipients, node.Name)
+		}
+	}
+	if len(rebalance.donors) == 0 || len(rebalance.recipients) == 0 {
+		v4Error(w, "nothing_to_balance")
+		return
+	}
+	sort.Strings(rebalance.donors)
+	sort.Strings(rebalance.recipients)
+	s.rebalances = append(s.rebalances, rebalance)
+	v4Data(w, []any{})
+}
+
+func (s *Server) v4StopRebalance(w http.ResponseWriter, r *http.Request, params []string) {
+	rebalances := []*rebalance{}
+	for _, r := range s.rebalances {
+		if r.coordinator != params[0] {
+			rebalances = append(rebalances, r)
+		}
+	}
+	if len(rebalances) == len(s.rebalances) {
+		v4Error(w, "not_started")
+		return
+	}
+	s.rebalances = rebalances
+	v4Data(w, []any{})
+}
+
+func (s *Server) v4StartEvacuation(w http.ResponseWriter, r *http.Request, params []string) {
+	req := emqxapi.EvacuationRequest{}
+	if err := readJSON(r, &req); err != nil {
+		v4Error(w, err.Error())
+		return
+	}
+	if msg := s.startEvacuation(params[0], req); msg != "" {
+		v4Error(w, msg)
+		return
+	}
+	v4Data(w, []any{})
+}
+
+func (s *Server) v4Plugins(w http.ResponseWriter, r *http.Request, _ []string) {
+	data := []emqxapi.V4NodePlugins{}
+	for _, node := range s.nodes {
+		plugins := []emqxapi.V4Plugin{}
+		for _, p := range s.plugins {
+			plugins = append(plugins, emqxapi.V4Plugin{
+				Name:        p.Name,
+				Version:     p.Version,
+				Description: p.Description,
+				Active:      p.running[node.Name],
+				Type:        "feature",
+			})
+		}
+		data = append(data, emqxapi.V4NodePlugins{Node: node.Name, Plugins: plugins})
+	}
+	v4Data(w, data)
+}
+
+// v4UpdatePlugin reloads or unloads the plugin on the node
+func (s *Server) v4UpdatePlugin(w http.ResponseWriter, r *http.Request, params []string) {
+	node, name, operation := params[0], params[1], params[2]
+	if s.findNode(node) == nil {
+		v4Error(w, "node_not_found")
+		return
+	}
+	p := s.findPlugin(name)
+	if p == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch operation {
+	case "reload", "load":
+		p.running[node] = true
+	case "unload":
+		p.running[node] = false
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	v4Data(w, []any{})
+}
@@ -0,0 +1,248 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	"github.com/emqx/emqx-operator/internal/emqxapi"
+	"k8s.io/utils/pointer"
+)
+
+func (s *Server) v5Routes() []route {
+	return []route{
+		{http.MethodGet, "nodes", s.v5Nodes},
+		{http.MethodGet, "listeners", func(w http.ResponseWriter, r *http.Request, _ []string) {
+			writeJSON(w, http.StatusOK, append([]emqxapi.Listener{}, s.listeners...))
+		}},
+		{http.MethodGet, "gateways", s.v5Gateways},
+		{http.MethodGet, "gateways/:name/listeners", s.v5GatewayListeners},
+		{http.MethodGet, "alarms", s.v5Alarms},
+		{http.MethodGet, "metrics", s.v5Metrics},
+		{http.MethodGet, "stats", s.v5Metrics},
+		{http.MethodDelete, "cluster/:node/force_leave", s.v5ForceLeave},
+		{http.MethodGet, "load_rebalance/global_status", s.v5GlobalStatus},
+		{http.MethodPost, "load_rebalance/:node/evacuation/start", s.v5StartEvacuation},
+		{http.MethodPost, "load_rebalance/:node/evacuation/stop", s.v5StopEvacuation},
+		{http.MethodGet, "load_rebalance/availability_check", func(w http.ResponseWriter, r *http.Request, _ []string) {
+			if !s.isAvailable() {
+				v5Error(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "node is not available")
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{})
+		}},
+		{http.MethodGet, "plugins", s.v5Plugins},
+		{http.MethodPut, "plugins/:name/:operation", s.v5UpdatePlugin},
+		{http.MethodGet, "api_key", s.v5APIKeys},
+		{http.MethodPost, "api_key", s.v5CreateAPIKey},
+		{http.MethodDelete, "api_key/:name", s.v5DeleteAPIKey},
+	}
+}
+
+func v5Error(w http.ResponseWriter, statusCode int, code, message string) {
+	writeJSON(w, statusCode, map[string]any{"code": code, "message": message})
+}
+
+func (s *Server) v5Nodes(w http.ResponseWriter, r *http.Request, _ []string) {
+	nodes := []appsv2alpha2.EMQXNode{}
+	for _, node := range s.nodes {
+		nodes = append(nodes, appsv2alpha2.EMQXNode{
+			Node:        node.Name,
+			NodeStatus:  "running",
+			Version:     node.Version,
+			Role:        node.Role,
+			Edition:     node.Edition,
+			Session:     node.Sessions,
+			Connections: node.Connections,
+		})
+	}
+	writeJSON(w, http.StatusOK, nodes)
+}
+
+func (s *Server) v5Gateways(w http.ResponseWriter, r *http.Request, _ []string) {
+	gateways := []emqxapi.Gateway{}
+	for _, g := range s.gateways {
+		gateways = append(gateways, emqxapi.Gateway{Name: g.name, Status: "running"})
+	}
+	writeJSON(w, http.StatusOK, gateways)
+}
+
+func (s *Server) v5GatewayListeners(w http.ResponseWriter, r *http.Request, params []string) {
+	for _, g := range s.gateways {
+		if g.name == params[0] {
+			writeJSON(w, http.StatusOK, append([]emqxapi.Listener{}, g.listeners...))
+			return
+		}
+	}
+	v5Error(w, http.StatusNotFound, "RESOURCE_NOT_FOUND", "gateway not found")
+}
+
+func (s *Server) v5Alarms(w http.ResponseWriter, r *http.Request, _ []string) {
+	alarms := []appsv2alpha2.EMQXAlarm{}
+	for _, alarm := range s.alarms {
+		alarms = append(alarms, appsv2alpha2.EMQXAlarm{Node: alarm.Node, Name: alarm.Name, Message: alarm.Message})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data": alarms,
+		"meta": map[string]any{"count": len(alarms)},
+	})
+}
+
+// v5Metrics returns the metrics and stats aggregated of all the nodes
+func (s *Server) v5Metrics(w http.ResponseWriter, r *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, s.metrics)
+}
+
+func (s *Server) v5ForceLeave(w http.ResponseWriter, r *http.Request, params []string) {
+	if params[0] == s.localNode {
+		v5Error(w, http.StatusBadRequest, "BAD_REQUEST", "cannot_force_leave_self")
+		return
+	}
+	if !s.removeNode(params[0]) {
+		v5Error(w, http.StatusBadRequest, "BAD_REQUEST", "node_not_found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) v5GlobalStatus(w http.ResponseWriter, r *http.Request, _ []string) {
+	evacuations := []appsv2alpha2.NodeEvacuationStatus{}
+	for _, e := range s.evacuations {
+		node := s.findNode(e.node)
+		evacuations = append(evacuations, appsv2alpha2.NodeEvacuationStatus{
+			Node:  e.node,
+			State: e.state,
+			Stats: appsv2alpha2.NodeEvacuationStats{
+				InitialConnected: pointer.Int32(int32(e.initialConnections)),
+				InitialSessions:  pointer.Int32(int32(e.initialSessions)),
+				CurrentConnected: pointer.Int32(int32(node.Connections)),
+				CurrentSessions:  pointer.Int32(int32(node.Sessions)),
+			},
+			SessionRecipients:      e.req.MigrateTo,
+			ConnectionEvictionRate: e.req.ConnEvictRate,
+			SessionEvictionRate:    e.req.SessEvictRate,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":      "enabled",
+		"evacuations": evacuations,
+		"rebalances":  []any{},
+	})
+}
+
+func (s *Server) v5StartEvacuation(w http.ResponseWriter, r *http.Request, params []string) {
+	req := emqxapi.EvacuationRequest{}
+	if err := readJSON(r, &req); err != nil {
+		v5Error(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+	if msg := s.startEvacuation(params[0], req); msg != "" {
+		v5Error(w, http.StatusBadRequest, "BAD_REQUEST", msg)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) v5StopEvacuation(w http.ResponseWriter, r *http.Request, params []string) {
+	evacuations := []*evacuation{}
+	for _, e := range s.evacuations {
+		if e.node != params[0] {
+			evacuations = append(evacuations, e)
+		}
+	}
+	if len(evacuations) == len(s.evacuations) {
+		v5Error(w, http.StatusBadRequest, "BAD_REQUEST", "not_started")
+		return
+	}
+	s.evacuations = evacuations
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) v5Plugins(w http.ResponseWriter, r *http.Request, _ []string) {
+	plugins := []emqxapi.Plugin{}
+	for _, p := range s.plugins {
+		plugin := emqxapi.Plugin{
+			Name:        p.Name,
+			RelVsn:      p.Version,
+			Description: p.Description,
+		}
+		for _, node := range s.nodes {
+			status := "stopped"
+			if p.running[node.Name] {
+				status = "running"
+			}
+			plugin.RunningStatus = append(plugin.RunningStatus, emqxapi.PluginStatus{Node: node.Name, Status: status})
+		}
+		plugins = append(plugins, plugin)
+	}
+	writeJSON(w, http.StatusOK, plugins)
+}
+
+// v5UpdatePlugin starts or stops the plugin on all the nodes, the name of the plugin is name-version in EMQX 5
+func (s *Server) v5UpdatePlugin(w http.ResponseWriter, r *http.Request, params []string) {
+	var p *plugin
+	for _, installed := range s.plugins {
+		if params[0] == installed.Name || params[0] == installed.Name+"-"+installed.Version {
+			p = installed
+			break
+		}
+	}
+	if p == nil {
+		v5Error(w, http.StatusNotFound, "NOT_FOUND", "plugin not found")
+		return
+	}
+	switch params[1] {
+	case "start", "stop":
+		for _, node := range s.nodes {
+			p.running[node.Name] = params[1] == "start"
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		v5Error(w, http.StatusBadRequest, "BAD_REQUEST", "unknown operation "+params[1])
+	}
+}
+
+// v5APIKeys lists the API keys without the secrets
+func (s *Server) v5APIKeys(w http.ResponseWriter, r *http.Request, _ []string) {
+	keys := []emqxapi.APIKey{}
+	for _, key := range s.apiKeys {
+		listed := *key
+		listed.APISecret = ""
+		keys = append(keys, listed)
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// v5CreateAPIKey creates the API key with a generated key and secret, the name must be unique
+func (s *Server) v5CreateAPIKey(w http.ResponseWriter, r *http.Request, _ []string) {
+	key := emqxapi.APIKey{}
+	if err := readJSON(r, &key); err != nil {
+		v5Error(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+	if key.Name == "" {
+		v5Error(w, http.StatusBadRequest, "BAD_REQUEST", "name is required")
+		return
+	}
+	for _, existing := range s.apiKeys {
+		if existing.Name == key.Name {
+			v5Error(w, http.StatusBadRequest, "BAD_REQUEST", "Name Already Exists")
+			return
+		}
+	}
+	key.APIKey = fmt.Sprintf("key-%d", len(s.requests))
+	key.APISecret = fmt.Sprintf("secret-%d", len(s.requests))
+	s.apiKeys = append(s.apiKeys, &key)
+	writeJSON(w, http.StatusOK, key)
+}
+
+func (s *Server) v5DeleteAPIKey(w http.ResponseWriter, r *http.Request, params []string) {
+	for i, key := range s.apiKeys {
+		if key.Name == params[0] {
+			s.apiKeys = append(s.apiKeys[:i], s.apiKeys[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	v5Error(w, http.StatusNotFound, "NOT_FOUND", "api_key not found")
+}
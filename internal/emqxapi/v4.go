@@ -0,0 +1,261 @@
+package emqxapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	"github.com/tidwall/gjson"
+)
+
+// V4 is the client of the EMQX 4 management API
+type V4 struct {
+	client
+}
+
+func NewV4(r innerReq.RequesterInterface) *V4 {
+	return &V4{client{requester: r}}
+}
+
+type V4Listener struct {
+	Protocol string `json:"protocol"`
+	ListenOn string `json:"listen_on"`
+}
+
+type V4NodeListeners struct {
+	Node      string       `json:"node"`
+	Listeners []V4Listener `json:"listeners"`
+}
+
+type V4Plugin struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	Active      bool   `json:"active"`
+	Type        string `json:"type"`
+}
+
+type V4NodePlugins struct {
+	Node    string     `json:"node"`
+	Plugins []V4Plugin `json:"plugins"`
+}
+
+// V4RebalanceStatus is the rebalances and evacuations in progress of the EMQX cluster
+type V4RebalanceStatus struct {
+	Rebalances  []appsv1beta4.RebalanceState       `json:"rebalances"`
+	Evacuations []appsv1beta4.EmqxEvacuationStatus `json:"evacuations"`
+}
+
+// RebalanceRequest is the request to rebalance the connections and sessions between the nodes
+type RebalanceRequest struct {
+	ConnEvictRate    int32    `json:"conn_evict_rate"`
+	SessEvictRate    int32    `json:"sess_evict_rate"`
+	WaitTakeover     int32    `json:"wait_takeover"`
+	WaitHealthCheck  int32    `json:"wait_health_check"`
+	AbsConnThreshold int32    `json:"abs_conn_threshold"`
+	AbsSessThreshold int32    `json:"abs_sess_threshold"`
+	RelConnThreshold *float64 `json:"rel_conn_threshold,omitempty"`
+	RelSessThreshold *float64 `json:"rel_sess_threshold,omitempty"`
+	Nodes            []string `json:"nodes"`
+}
+
+type V4License struct {
+	Customer       string `json:"customer,omitempty"`
+	CustomerType   int    `json:"customer_type,omitempty"`
+	MaxConnections int64  `json:"max_connections,omitempty"`
+	ExpiryAt       string `json:"expiry_at,omitempty"`
+}
+
+type V4NodeConfigs struct {
+	Node    string                     `json:"node"`
+	Configs map[string]json.RawMessage `json:"configs"`
+}
+
+// V4App is the application, like the API key of EMQX 5, to request the EMQX 4 management API
+type V4App struct {
+	AppID  string `json:"app_id"`
+	Name   string `json:"name,omitempty"`
+	Secret string `json:"secret,omitempty"`
+	Desc   string `json:"desc,omitempty"`
+	Status bool   `json:"status"`
+	// Expired is the unix timestamp when the application expires, it never expires if it is empty
+	Expired json.RawMessage `json:"expired,omitempty"`
+}
+
+// request sends the request to the EMQX 4 management API, which responds like {"code": 0, "data": ...},
+// the non-zero code is returned as an *Error even if the status code is 200.
+func (c *V4) request(ctx context.Context, method, path string, reqBody any) ([]byte, error) {
+	body, err := c.client.request(ctx, method, path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if code := gjson.GetBytes(body, "code"); code.Exists() && code.String() != "0" {
+		return nil, &Error{
+			Method:     method,
+			Path:       path,
+			StatusCode: http.StatusOK,
+			Code:       code.String(),
+			Message:    gjson.GetBytes(body, "message").String(),
+		}
+	}
+	return body, nil
+}
+
+func (c *V4) Nodes(ctx context.Context) ([]appsv1beta4.EmqxNode, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v4/nodes", nil)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []appsv1beta4.EmqxNode{}
+	if err := decode("api/v4/nodes", body, "data", &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Listeners returns the listeners of each EMQX node
+func (c *V4) Listeners(ctx context.Context) ([]V4NodeListeners, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v4/listeners", nil)
+	if err != nil {
+		return nil, err
+	}
+	listeners := []V4NodeListeners{}
+	if err := decode("api/v4/listeners", body, "data", &listeners); err != nil {
+		return nil, err
+	}
+	return listeners, nil
+}
+
+// Alarms returns the activated alarms of all the EMQX nodes
+func (c *V4) Alarms(ctx context.Context) ([]appsv1beta4.EmqxAlarm, error) {
+	path := "api/v4/alarms/activated"
+	body, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The alarms are grouped by node, like [{"node": "emqx@127.0.0.1", "alarms": [...]}]
+	nodeAlarms := []struct {
+		Node   string                  `json:"node"`
+		Alarms []appsv1beta4.EmqxAlarm `json:"alarms"`
+	}{}
+	if err := decode(path, body, "data", &nodeAlarms); err != nil {
+		return nil, err
+	}
+	alarms := []appsv1beta4.EmqxAlarm{}
+	for _, n := range nodeAlarms {
+		for _, alarm := range n.Alarms {
+			alarm.Node = n.Node
+			alarms = append(alarms, alarm)
+		}
+	}
+	return alarms, nil
+}
+
+// RebalanceStatus returns the rebalances and evacuations in progress, only for EMQX Enterprise
+func (c *V4) RebalanceStatus(ctx context.Context) (*V4RebalanceStatus, error) {
+	path := "api/v4/load_rebalance/global_status"
+	body, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	status := &V4RebalanceStatus{}
+	if err := decode(path, body, "", status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// StartRebalance starts to rebalance the nodes in the request, the node in the path is the coordinator
+func (c *V4) StartRebalance(ctx context.Context, node string, req RebalanceRequest) error {
+	_, err := c.request(ctx, http.MethodPost, "api/v4/load_rebalance/"+node+"/start", req)
+	return err
+}
+
+// StopRebalance stops the rebalance coordinated by the node
+func (c *V4) StopRebalance(ctx context.Context, node string) error {
+	_, err := c.request(ctx, http.MethodPost, "api/v4/load_rebalance/"+node+"/stop", nil)
+	return err
+}
+
+func (c *V4) StartEvacuation(ctx context.Context, node string, req EvacuationRequest) error {
+	_, err := c.request(ctx, http.MethodPost, "api/v4/load_rebalance/"+node+"/evacuation/start", req)
+	return err
+}
+
+// AvailabilityCheck returns false if the EMQX node requested is being evacuated or rebalanced
+func (c *V4) AvailabilityCheck(ctx context.Context) (bool, error) {
+	return availabilityCheck(ctx, &c.client, "api/v4/load_rebalance/availability_check")
+}
+
+// Plugins returns the plugins of each EMQX node
+func (c *V4) Plugins(ctx context.Context) ([]V4NodePlugins, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v4/plugins", nil)
+	if err != nil {
+		return nil, err
+	}
+	plugins := []V4NodePlugins{}
+	if err := decode("api/v4/plugins", body, "data", &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
+
+func (c *V4) ReloadPlugin(ctx context.Context, node, plugin string) error {
+	_, err := c.request(ctx, http.MethodPut, "api/v4/nodes/"+node+"/plugins/"+url.PathEscape(plugin)+"/reload", nil)
+	return err
+}
+
+func (c *V4) UnloadPlugin(ctx context.Context, node, plugin string) error {
+	_, err := c.request(ctx, http.MethodPut, "api/v4/nodes/"+node+"/plugins/"+url.PathEscape(plugin)+"/unload", nil)
+	return err
+}
+
+func (c *V4) License(ctx context.Context) (*V4License, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v4/license_info", nil)
+	if err != nil {
+		return nil, err
+	}
+	license := &V4License{}
+	if err := decode("api/v4/license_info", body, "data", license); err != nil {
+		return nil, err
+	}
+	return license, nil
+}
+
+// Configs returns the configs of each EMQX node
+func (c *V4) Configs(ctx context.Context) ([]V4NodeConfigs, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v4/configs", nil)
+	if err != nil {
+		return nil, err
+	}
+	configs := []V4NodeConfigs{}
+	if err := decode("api/v4/configs", body, "data", &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+func (c *V4) Apps(ctx context.Context) ([]V4App, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v4/apps", nil)
+	if err != nil {
+		return nil, err
+	}
+	apps := []V4App{}
+	if err := decode("api/v4/apps", body, "data", &apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+func (c *V4) CreateApp(ctx context.Context, app V4App) error {
+	_, err := c.request(ctx, http.MethodPost, "api/v4/apps", app)
+	return err
+}
+
+func (c *V4) DeleteApp(ctx context.Context, appID string) error {
+	_, err := c.request(ctx, http.MethodDelete, "api/v4/apps/"+url.PathEscape(appID), nil)
+	return err
+}
@@ -0,0 +1,108 @@
+package emqxapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	appsv1beta4 "github.com/emqx/emqx-operator/apis/apps/v1beta4"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func TestV4Request(t *testing.T) {
+	t.Run("non-zero code", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"code":400,"message":"rebalance is disabled"}`))
+		})
+		err := NewV4(r).StopRebalance(context.Background(), "emqx@10.0.0.1")
+		assert.ErrorContains(t, err, "rebalance is disabled")
+		assert.True(t, IsCode(err, "400"))
+	})
+
+	t.Run("error status code", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Not Found"))
+		})
+		err := NewV4(r).ReloadPlugin(context.Background(), "emqx@10.0.0.1", "emqx_prometheus")
+		assert.EqualError(t, err, "failed to request API PUT api/v4/nodes/emqx@10.0.0.1/plugins/emqx_prometheus/reload, status: 404 Not Found, message: Not Found")
+		assert.True(t, IsNotFound(err))
+	})
+}
+
+func TestV4Alarms(t *testing.T) {
+	r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/alarms/activated", r.URL.Path)
+		_, _ = w.Write([]byte(`{"code":0,"data":[
+			{"node":"emqx@10.0.0.1","alarms":[{"name":"high_system_memory_usage","message":"System memory usage is higher than 70%"}]},
+			{"node":"emqx@10.0.0.2","alarms":[]}
+		]}`))
+	})
+
+	alarms, err := NewV4(r).Alarms(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []appsv1beta4.EmqxAlarm{
+		{Node: "emqx@10.0.0.1", Name: "high_system_memory_usage", Message: "System memory usage is higher than 70%"},
+	}, alarms)
+}
+
+func TestV4Listeners(t *testing.T) {
+	t.Run("listeners", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"code":0,"data":[{"node":"emqx@10.0.0.1","listeners":[{"protocol":"mqtt:tcp","listen_on":"0.0.0.0:1883"}]}]}`))
+		})
+		listeners, err := NewV4(r).Listeners(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, []V4NodeListeners{
+			{Node: "emqx@10.0.0.1", Listeners: []V4Listener{{Protocol: "mqtt:tcp", ListenOn: "0.0.0.0:1883"}}},
+		}, listeners)
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"code":0}`))
+		})
+		_, err := NewV4(r).Listeners(context.Background())
+		assert.ErrorContains(t, err, "failed to unmarshal data of API api/v4/listeners: data not found")
+	})
+}
+
+func TestV4StartRebalance(t *testing.T) {
+	r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/api/v4/load_rebalance/emqx@10.0.0.1/start", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{
+			"conn_evict_rate":5,"sess_evict_rate":5,"wait_takeover":10,"wait_health_check":10,
+			"abs_conn_threshold":10,"abs_sess_threshold":10,"rel_conn_threshold":1.1,
+			"nodes":["emqx@10.0.0.1","emqx@10.0.0.2"]
+		}`, string(body))
+		_, _ = w.Write([]byte(`{"code":0,"data":[]}`))
+	})
+
+	assert.Nil(t, NewV4(r).StartRebalance(context.Background(), "emqx@10.0.0.1", RebalanceRequest{
+		ConnEvictRate:    5,
+		SessEvictRate:    5,
+		WaitTakeover:     10,
+		WaitHealthCheck:  10,
+		AbsConnThreshold: 10,
+		AbsSessThreshold: 10,
+		RelConnThreshold: pointer.Float64(1.1),
+		Nodes:            []string{"emqx@10.0.0.1", "emqx@10.0.0.2"},
+	}))
+}
+
+func TestV4RebalanceStatus(t *testing.T) {
+	r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"rebalances":[{"state":"wait_health_check","coordinator_node":"emqx@10.0.0.1"}],"evacuations":[]}`))
+	})
+
+	status, err := NewV4(r).RebalanceStatus(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, &V4RebalanceStatus{
+		Rebalances:  []appsv1beta4.RebalanceState{{State: "wait_health_check", CoordinatorNode: "emqx@10.0.0.1"}},
+		Evacuations: []appsv1beta4.EmqxEvacuationStatus{},
+	}, status)
+}
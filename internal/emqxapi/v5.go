@@ -0,0 +1,287 @@
+package emqxapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+)
+
+// V5 is the client of the EMQX 5 management API
+type V5 struct {
+	client
+}
+
+func NewV5(r innerReq.RequesterInterface) *V5 {
+	return &V5{client{requester: r}}
+}
+
+type Listener struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Bind   string `json:"bind"`
+	Enable bool   `json:"enable"`
+}
+
+type Gateway struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// EvacuationRequest is the request to evacuate the clients of the EMQX node to the other nodes, it is used by EMQX 4 and 5
+type EvacuationRequest struct {
+	ConnEvictRate int32    `json:"conn_evict_rate"`
+	SessEvictRate int32    `json:"sess_evict_rate"`
+	WaitTakeover  int32    `json:"wait_takeover,omitempty"`
+	MigrateTo     []string `json:"migrate_to"`
+}
+
+type PluginStatus struct {
+	Node   string `json:"node"`
+	Status string `json:"status"`
+}
+
+type Plugin struct {
+	Name          string         `json:"name"`
+	RelVsn        string         `json:"rel_vsn"`
+	Description   string         `json:"description,omitempty"`
+	RunningStatus []PluginStatus `json:"running_status,omitempty"`
+}
+
+type License struct {
+	Customer       string `json:"customer,omitempty"`
+	CustomerType   int    `json:"customer_type,omitempty"`
+	MaxConnections int64  `json:"max_connections,omitempty"`
+	ExpiryAt       string `json:"expiry_at,omitempty"`
+	Expiry         bool   `json:"expiry,omitempty"`
+}
+
+// APIKey is the API key to request the EMQX management API, the secret is only returned when the API key is created
+type APIKey struct {
+	Name      string `json:"name"`
+	APIKey    string `json:"api_key,omitempty"`
+	APISecret string `json:"api_secret,omitempty"`
+	ExpiredAt string `json:"expired_at,omitempty"`
+	Desc      string `json:"desc,omitempty"`
+	Enable    bool   `json:"enable"`
+	Expired   bool   `json:"expired,omitempty"`
+}
+
+func (c *V5) Nodes(ctx context.Context) ([]appsv2alpha2.EMQXNode, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v5/nodes", nil)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []appsv2alpha2.EMQXNode{}
+	if err := decode("api/v5/nodes", body, "", &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (c *V5) Listeners(ctx context.Context) ([]Listener, error) {
+	return c.getListeners(ctx, "api/v5/listeners")
+}
+
+func (c *V5) Gateways(ctx context.Context) ([]Gateway, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v5/gateways", nil)
+	if err != nil {
+		return nil, err
+	}
+	gateways := []Gateway{}
+	if err := decode("api/v5/gateways", body, "", &gateways); err != nil {
+		return nil, err
+	}
+	return gateways, nil
+}
+
+func (c *V5) GatewayListeners(ctx context.Context, gateway string) ([]Listener, error) {
+	return c.getListeners(ctx, "api/v5/gateways/"+url.PathEscape(gateway)+"/listeners")
+}
+
+func (c *V5) getListeners(ctx context.Context, path string) ([]Listener, error) {
+	body, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	listeners := []Listener{}
+	if err := decode(path, body, "", &listeners); err != nil {
+		return nil, err
+	}
+	return listeners, nil
+}
+
+// Alarms returns the activated alarms of the EMQX cluster
+func (c *V5) Alarms(ctx context.Context) ([]appsv2alpha2.EMQXAlarm, error) {
+	path := "api/v5/alarms?activated=true&limit=1000"
+	body, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Data []appsv2alpha2.EMQXAlarm `json:"data"`
+	}{}
+	if err := decode(path, body, "", &resp); err != nil {
+		return nil, err
+	}
+	if resp.Data == nil {
+		return []appsv2alpha2.EMQXAlarm{}, nil
+	}
+	return resp.Data, nil
+}
+
+// Stats returns the stats of the EMQX cluster, like topics.count and subscriptions.count
+func (c *V5) Stats(ctx context.Context) (map[string]int64, error) {
+	return c.getCounters(ctx, "api/v5/stats?aggregate=true")
+}
+
+// Metrics returns the metrics of the EMQX cluster, like messages.received and messages.sent
+func (c *V5) Metrics(ctx context.Context) (map[string]int64, error) {
+	return c.getCounters(ctx, "api/v5/metrics?aggregate=true")
+}
+
+// getCounters returns the counters of the stats or metrics API, the counters are summed up if the API returns
+// the counters of each node, and the values which are not integers, like the node name, are skipped.
+func (c *V5) getCounters(ctx context.Context, path string) (map[string]int64, error) {
+	body, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	items := []map[string]json.RawMessage{}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = decode(path, body, "", &items)
+	} else {
+		item := map[string]json.RawMessage{}
+		err = decode(path, body, "", &item)
+		items = append(items, item)
+	}
+	if err != nil {
+		return nil, err
+	}
+	counters := map[string]int64{}
+	for _, item := range items {
+		for key, raw := range item {
+			var value int64
+			if json.Unmarshal(raw, &value) == nil {
+				counters[key] += value
+			}
+		}
+	}
+	return counters, nil
+}
+
+// ForceLeave removes the EMQX node from the cluster, the node can not force leave itself
+func (c *V5) ForceLeave(ctx context.Context, node string) error {
+	_, err := c.request(ctx, http.MethodDelete, "api/v5/cluster/"+node+"/force_leave", nil)
+	return err
+}
+
+// EvacuationStatus returns the evacuations in progress of the EMQX cluster
+func (c *V5) EvacuationStatus(ctx context.Context) ([]appsv2alpha2.NodeEvacuationStatus, error) {
+	path := "api/v5/load_rebalance/global_status"
+	body, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Evacuations []appsv2alpha2.NodeEvacuationStatus `json:"evacuations"`
+	}{}
+	if err := decode(path, body, "", &resp); err != nil {
+		return nil, err
+	}
+	if resp.Evacuations == nil {
+		return []appsv2alpha2.NodeEvacuationStatus{}, nil
+	}
+	return resp.Evacuations, nil
+}
+
+func (c *V5) StartEvacuation(ctx context.Context, node string, req EvacuationRequest) error {
+	_, err := c.request(ctx, http.MethodPost, "api/v5/load_rebalance/"+node+"/evacuation/start", req)
+	return err
+}
+
+// AvailabilityCheck returns false if the EMQX node requested is being evacuated or rebalanced
+func (c *V5) AvailabilityCheck(ctx context.Context) (bool, error) {
+	return availabilityCheck(ctx, &c.client, "api/v5/load_rebalance/availability_check")
+}
+
+func (c *V5) Plugins(ctx context.Context) ([]Plugin, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v5/plugins", nil)
+	if err != nil {
+		return nil, err
+	}
+	plugins := []Plugin{}
+	if err := decode("api/v5/plugins", body, "", &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
+}
+
+func (c *V5) License(ctx context.Context) (*License, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v5/license", nil)
+	if err != nil {
+		return nil, err
+	}
+	license := &License{}
+	if err := decode("api/v5/license", body, "", license); err != nil {
+		return nil, err
+	}
+	return license, nil
+}
+
+// Configs returns the configs of the EMQX cluster by the root keys, like mqtt and dashboard
+func (c *V5) Configs(ctx context.Context) (map[string]json.RawMessage, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v5/configs", nil)
+	if err != nil {
+		return nil, err
+	}
+	configs := map[string]json.RawMessage{}
+	if err := decode("api/v5/configs", body, "", &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+func (c *V5) APIKeys(ctx context.Context) ([]APIKey, error) {
+	body, err := c.request(ctx, http.MethodGet, "api/v5/api_key", nil)
+	if err != nil {
+		return nil, err
+	}
+	keys := []APIKey{}
+	if err := decode("api/v5/api_key", body, "", &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateAPIKey creates the API key, the returned API key has the secret
+func (c *V5) CreateAPIKey(ctx context.Context, key APIKey) (*APIKey, error) {
+	body, err := c.request(ctx, http.MethodPost, "api/v5/api_key", key)
+	if err != nil {
+		return nil, err
+	}
+	created := &APIKey{}
+	if err := decode("api/v5/api_key", body, "", created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (c *V5) DeleteAPIKey(ctx context.Context, name string) error {
+	_, err := c.request(ctx, http.MethodDelete, "api/v5/api_key/"+url.PathEscape(name), nil)
+	return err
+}
+
+func availabilityCheck(ctx context.Context, c *client, path string) (bool, error) {
+	if _, err := c.request(ctx, http.MethodGet, path, nil); err != nil {
+		if IsAPIError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
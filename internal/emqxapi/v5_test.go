@@ -0,0 +1,206 @@
+package emqxapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRequester returns the requester of the server, which is closed when the test finishes
+func newTestRequester(t *testing.T, handler http.HandlerFunc) *innerReq.Requester {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &innerReq.Requester{Host: server.Listener.Addr().String(), Retries: -1}
+}
+
+func TestV5Nodes(t *testing.T) {
+	r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/v5/nodes", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"node":"emqx@10.0.0.1","node_status":"running","role":"core"}]`))
+	})
+
+	nodes, err := NewV5(r).Nodes(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []appsv2alpha2.EMQXNode{
+		{Node: "emqx@10.0.0.1", NodeStatus: "running", Role: "core"},
+	}, nodes)
+}
+
+func TestV5Alarms(t *testing.T) {
+	t.Run("activated alarms", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v5/alarms", r.URL.Path)
+			assert.Equal(t, "true", r.URL.Query().Get("activated"))
+			_, _ = w.Write([]byte(`{"data":[{"node":"emqx@10.0.0.1","name":"high_cpu_usage"}],"meta":{"count":1}}`))
+		})
+		alarms, err := NewV5(r).Alarms(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, []appsv2alpha2.EMQXAlarm{{Node: "emqx@10.0.0.1", Name: "high_cpu_usage"}}, alarms)
+	})
+
+	t.Run("no alarm", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"meta":{"count":0}}`))
+		})
+		alarms, err := NewV5(r).Alarms(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, []appsv2alpha2.EMQXAlarm{}, alarms)
+	})
+
+	t.Run("unexpected JSON", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"data":"fake"}`))
+		})
+		_, err := NewV5(r).Alarms(context.Background())
+		assert.ErrorContains(t, err, "failed to unmarshal response of API api/v5/alarms")
+	})
+}
+
+func TestV5Stats(t *testing.T) {
+	t.Run("aggregated", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "GET", r.Method)
+			assert.Equal(t, "/api/v5/stats", r.URL.Path)
+			assert.Equal(t, "true", r.URL.Query().Get("aggregate"))
+			_, _ = w.Write([]byte(`{"topics.count":3,"subscriptions.count":5}`))
+		})
+		stats, err := NewV5(r).Stats(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]int64{"topics.count": 3, "subscriptions.count": 5}, stats)
+		assert.Equal(t, int64(0), stats["retained.count"])
+	})
+
+	t.Run("error status code", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		_, err := NewV5(r).Stats(context.Background())
+		assert.True(t, IsAPIError(err))
+	})
+}
+
+func TestV5Metrics(t *testing.T) {
+	t.Run("aggregated", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v5/metrics", r.URL.Path)
+			assert.Equal(t, "true", r.URL.Query().Get("aggregate"))
+			_, _ = w.Write([]byte(`{"messages.received":3,"messages.sent":5}`))
+		})
+		metrics, err := NewV5(r).Metrics(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]int64{"messages.received": 3, "messages.sent": 5}, metrics)
+	})
+
+	t.Run("per node", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[{"node":"emqx@10.0.0.1","messages.received":3},{"node":"emqx@10.0.0.2","messages.received":5}]`))
+		})
+		metrics, err := NewV5(r).Metrics(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]int64{"messages.received": 8}, metrics)
+	})
+
+	t.Run("unexpected JSON", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`"fake"`))
+		})
+		_, err := NewV5(r).Metrics(context.Background())
+		assert.ErrorContains(t, err, "failed to unmarshal response of API api/v5/metrics?aggregate=true")
+	})
+}
+
+func TestV5ForceLeave(t *testing.T) {
+	t.Run("force leave", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "DELETE", r.Method)
+			assert.Equal(t, "/api/v5/cluster/emqx@10.0.0.1/force_leave", r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		assert.Nil(t, NewV5(r).ForceLeave(context.Background(), "emqx@10.0.0.1"))
+	})
+
+	t.Run("node not found", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"BAD_REQUEST","message":"node_not_found"}`))
+		})
+		err := NewV5(r).ForceLeave(context.Background(), "emqx@10.0.0.1")
+		assert.ErrorContains(t, err, "node_not_found")
+		assert.True(t, IsCode(err, "BAD_REQUEST"))
+		assert.False(t, IsNotFound(err))
+	})
+}
+
+func TestV5StartEvacuation(t *testing.T) {
+	r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/api/v5/load_rebalance/emqx@10.0.0.1/evacuation/start", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"conn_evict_rate":10,"sess_evict_rate":10,"migrate_to":["emqx@10.0.0.2"]}`, string(body))
+	})
+
+	assert.Nil(t, NewV5(r).StartEvacuation(context.Background(), "emqx@10.0.0.1", EvacuationRequest{
+		ConnEvictRate: 10,
+		SessEvictRate: 10,
+		MigrateTo:     []string{"emqx@10.0.0.2"},
+	}))
+}
+
+func TestV5AvailabilityCheck(t *testing.T) {
+	t.Run("available", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v5/load_rebalance/availability_check", r.URL.Path)
+		})
+		available, err := NewV5(r).AvailabilityCheck(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, available)
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		available, err := NewV5(r).AvailabilityCheck(context.Background())
+		assert.Nil(t, err)
+		assert.False(t, available)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		r := &innerReq.Requester{Host: server.Listener.Addr().String(), Retries: -1}
+		_, err := NewV5(r).AvailabilityCheck(context.Background())
+		assert.ErrorContains(t, err, "failed to request API GET api/v5/load_rebalance/availability_check")
+		assert.False(t, IsAPIError(err))
+	})
+}
+
+func TestV5APIKeys(t *testing.T) {
+	r := newTestRequester(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"name":"emqx-operator","enable":true}`, string(body))
+			_, _ = w.Write([]byte(`{"name":"emqx-operator","api_key":"key","api_secret":"secret","enable":true}`))
+		case "DELETE":
+			assert.Equal(t, "/api/v5/api_key/emqx-operator", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"NOT_FOUND","message":"Name NotFound"}`))
+		}
+	})
+	c := NewV5(r)
+
+	key, err := c.CreateAPIKey(context.Background(), APIKey{Name: "emqx-operator", Enable: true})
+	assert.Nil(t, err)
+	assert.Equal(t, &APIKey{Name: "emqx-operator", APIKey: "key", APISecret: "secret", Enable: true}, key)
+
+	err = c.DeleteAPIKey(context.Background(), "emqx-operator")
+	assert.True(t, IsNotFound(err))
+}
@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "emqx_operator"
+
+// The results of the requests to the endpoints of the EMQX management API
+const (
+	APIEndpointOK          = "ok"
+	APIEndpointUnreachable = "unreachable"
+	APIEndpointFailover    = "failover"
+	APIEndpointUnhealthy   = "unhealthy"
+)
+
+// The metrics are labeled with the kind of the EMQX custom resource, since the custom resources of different kinds,
+// like EmqxBroker and EMQX, may have the same name in the same namespace.
+var (
+	subReconcilerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "subreconciler_duration_seconds",
+			Help:      "Duration of each sub reconciler of the EMQX custom resources",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"kind", "namespace", "instance", "subreconciler"},
+	)
+
+	apiRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_requests_total",
+			Help:      "Total number of requests to the EMQX management API, code is empty if the request failed without response",
+		},
+		[]string{"kind", "namespace", "instance", "method", "path", "code"},
+	)
+
+	apiRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "api_request_duration_seconds",
+			Help:      "Duration of the requests to the EMQX management API",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"kind", "namespace", "instance", "method", "path"},
+	)
+
+	apiEndpointRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_endpoint_requests_total",
+			Help:      "Total number of requests to the EMQX management API by endpoint, like the EMQX pod or the dashboard service, and result",
+		},
+		[]string{"kind", "namespace", "instance", "endpoint", "result"},
+	)
+
+	readyReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ready_replicas",
+			Help:      "Number of EMQX nodes in the cluster by role, taken from the status of the EMQX custom resources",
+		},
+		[]string{"kind", "namespace", "instance", "role"},
+	)
+
+	connections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "connections",
+			Help:      "Number of connected MQTT clients by role, taken from the status of the EMQX custom resources",
+		},
+		[]string{"kind", "namespace", "instance", "role"},
+	)
+
+	sessions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sessions",
+			Help:      "Number of MQTT sessions by role, taken from the status of the EMQX custom resources",
+		},
+		[]string{"kind", "namespace", "instance", "role"},
+	)
+
+	messagesReceivedRate = newClusterGaugeVec("messages_received_rate", "Rate of the messages received by the EMQX cluster, units: messages/second")
+	messagesSentRate     = newClusterGaugeVec("messages_sent_rate", "Rate of the messages sent by the EMQX cluster, units: messages/second")
+	messagesDroppedRate  = newClusterGaugeVec("messages_dropped_rate", "Rate of the messages dropped by the EMQX cluster, units: messages/second")
+	subscriptions        = newClusterGaugeVec("subscriptions", "Number of subscriptions in the EMQX cluster")
+	topics               = newClusterGaugeVec("topics", "Number of topics in the EMQX cluster")
+	retainedMessages     = newClusterGaugeVec("retained_messages", "Number of retained messages in the EMQX cluster")
+)
+
+// ClusterStats is the cluster-wide statistics of the EMQX cluster
+type ClusterStats struct {
+	MessagesReceivedRate float64
+	MessagesSentRate     float64
+	MessagesDroppedRate  float64
+	Subscriptions        int64
+	Topics               int64
+	RetainedMessages     int64
+}
+
+func newClusterGaugeVec(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		},
+		[]string{"kind", "namespace", "instance"},
+	)
+}
+
+func init() {
+	metrics.Registry.MustRegister(
+		subReconcilerDuration,
+		apiRequestsTotal,
+		apiRequestDuration,
+		apiEndpointRequestsTotal,
+		readyReplicas,
+		connections,
+		sessions,
+		messagesReceivedRate,
+		messagesSentRate,
+		messagesDroppedRate,
+		subscriptions,
+		topics,
+		retainedMessages,
+	)
+}
+
+// ObserveSubReconciler records the duration of the sub reconciler, the type name of the sub reconciler is used as the label
+func ObserveSubReconciler(kind, namespace, instance string, subReconciler any, duration time.Duration) {
+	subReconcilerDuration.WithLabelValues(kind, namespace, instance, getTypeName(subReconciler)).Observe(duration.Seconds())
+}
+
+// ObserveAPIRequest records the request to the EMQX management API, code is 0 if the request failed without response
+func ObserveAPIRequest(kind, namespace, instance, method, path string, code int, duration time.Duration) {
+	path = normalizePath(path)
+	var c string
+	if code != 0 {
+		c = strconv.Itoa(code)
+	}
+	apiRequestsTotal.WithLabelValues(kind, namespace, instance, method, path, c).Inc()
+	apiRequestDuration.WithLabelValues(kind, namespace, instance, method, path).Observe(duration.Seconds())
+}
+
+// ObserveAPIEndpoint records the endpoint used to request the EMQX management API, and the result of the request
+func ObserveAPIEndpoint(kind, namespace, instance, endpoint, result string) {
+	apiEndpointRequestsTotal.WithLabelValues(kind, namespace, instance, endpoint, result).Inc()
+}
+
+// SetNodesGauges sets the gauges of the EMQX nodes with the role
+func SetNodesGauges(kind, namespace, instance, role string, ready int32, conns, sess int64) {
+	readyReplicas.WithLabelValues(kind, namespace, instance, role).Set(float64(ready))
+	connections.WithLabelValues(kind, namespace, instance, role).Set(float64(conns))
+	sessions.WithLabelValues(kind, namespace, instance, role).Set(float64(sess))
+}
+
+// DeleteNodesGauges deletes the gauges of the EMQX nodes with the role,
+// all roles are deleted if role is empty.
+func DeleteNodesGauges(kind, namespace, instance, role string) {
+	labels := prometheus.Labels{"kind": kind, "namespace": namespace, "instance": instance}
+	if role != "" {
+		labels["role"] = role
+	}
+	for _, vec := range []*prometheus.GaugeVec{readyReplicas, connections, sessions} {
+		vec.DeletePartialMatch(labels)
+	}
+}
+
+// SetClusterStatsGauges sets the gauges of the cluster-wide statistics of the EMQX cluster
+func SetClusterStatsGauges(kind, namespace, instance string, stats ClusterStats) {
+	messagesReceivedRate.WithLabelValues(kind, namespace, instance).Set(stats.MessagesReceivedRate)
+	messagesSentRate.WithLabelValues(kind, namespace, instance).Set(stats.MessagesSentRate)
+	messagesDroppedRate.WithLabelValues(kind, namespace, instance).Set(stats.MessagesDroppedRate)
+	subscriptions.WithLabelValues(kind, namespace, instance).Set(float64(stats.Subscriptions))
+	topics.WithLabelValues(kind, namespace, instance).Set(float64(stats.Topics))
+	retainedMessages.WithLabelValues(kind, namespace, instance).Set(float64(stats.RetainedMessages))
+}
+
+// DeleteInstance deletes all the metrics of the EMQX custom resource of the kind
+func DeleteInstance(kind, namespace, instance string) {
+	labels := prometheus.Labels{"kind": kind, "namespace": namespace, "instance": instance}
+	subReconcilerDuration.DeletePartialMatch(labels)
+	apiRequestsTotal.DeletePartialMatch(labels)
+	apiRequestDuration.DeletePartialMatch(labels)
+	apiEndpointRequestsTotal.DeletePartialMatch(labels)
+	DeleteNodesGauges(kind, namespace, instance, "")
+	for _, vec := range []*prometheus.GaugeVec{messagesReceivedRate, messagesSentRate, messagesDroppedRate, subscriptions, topics, retainedMessages} {
+		vec.DeletePartialMatch(labels)
+	}
+}
+
+func getTypeName(obj any) string {
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// normalizePath replaces the EMQX node names in the path with ":node" to keep the cardinality of the label low,
+// e.g. api/v5/cluster/emqx@10.0.0.1/force_leave -> api/v5/cluster/:node/force_leave
+func normalizePath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, s := range segments {
+		if strings.Contains(s, "@") {
+			segments[i] = ":node"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSubReconciler struct{}
+
+func TestGetTypeName(t *testing.T) {
+	assert.Equal(t, "fakeSubReconciler", getTypeName(fakeSubReconciler{}))
+	assert.Equal(t, "fakeSubReconciler", getTypeName(&fakeSubReconciler{}))
+}
+
+func TestNormalizePath(t *testing.T) {
+	assert.Equal(t, "api/v5/nodes", normalizePath("api/v5/nodes"))
+	assert.Equal(t, "api/v5/cluster/:node/force_leave", normalizePath("api/v5/cluster/emqx@10.0.0.1/force_leave"))
+	assert.Equal(t, "api/v5/load_rebalance/:node/evacuation/start", normalizePath("/api/v5/load_rebalance/emqx@emqx-core-0.emqx-headless.default.svc.cluster.local/evacuation/start"))
+}
+
+func TestObserveAPIRequest(t *testing.T) {
+	ObserveAPIRequest("EMQX", "default", "emqx", "GET", "api/v5/nodes", 200, time.Second)
+	ObserveAPIRequest("EMQX", "default", "emqx", "GET", "api/v5/nodes", 0, time.Second)
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiRequestsTotal.WithLabelValues("EMQX", "default", "emqx", "GET", "api/v5/nodes", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiRequestsTotal.WithLabelValues("EMQX", "default", "emqx", "GET", "api/v5/nodes", "")))
+
+	DeleteInstance("EMQX", "default", "emqx")
+	assert.Equal(t, 0, testutil.CollectAndCount(apiRequestsTotal))
+}
+
+func TestObserveAPIEndpoint(t *testing.T) {
+	ObserveAPIEndpoint("EMQX", "default", "emqx", "emqx-core-0", APIEndpointFailover)
+	ObserveAPIEndpoint("EMQX", "default", "emqx", "emqx-core-1", APIEndpointOK)
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiEndpointRequestsTotal.WithLabelValues("EMQX", "default", "emqx", "emqx-core-0", "failover")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiEndpointRequestsTotal.WithLabelValues("EMQX", "default", "emqx", "emqx-core-1", "ok")))
+
+	DeleteInstance("EMQX", "default", "emqx")
+	assert.Equal(t, 0, testutil.CollectAndCount(apiEndpointRequestsTotal))
+}
+
+func TestSetNodesGauges(t *testing.T) {
+	SetNodesGauges("EMQX", "default", "emqx", "core", 3, 100, 200)
+	SetNodesGauges("EMQX", "default", "emqx", "replicant", 2, 300, 400)
+	assert.Equal(t, float64(3), testutil.ToFloat64(readyReplicas.WithLabelValues("EMQX", "default", "emqx", "core")))
+	assert.Equal(t, float64(300), testutil.ToFloat64(connections.WithLabelValues("EMQX", "default", "emqx", "replicant")))
+	assert.Equal(t, float64(400), testutil.ToFloat64(sessions.WithLabelValues("EMQX", "default", "emqx", "replicant")))
+
+	DeleteNodesGauges("EMQX", "default", "emqx", "replicant")
+	assert.Equal(t, 1, testutil.CollectAndCount(readyReplicas))
+
+	DeleteNodesGauges("EMQX", "default", "emqx", "")
+	assert.Equal(t, 0, testutil.CollectAndCount(readyReplicas))
+}
+
+func TestSetClusterStatsGauges(t *testing.T) {
+	SetClusterStatsGauges("EMQX", "default", "emqx", ClusterStats{MessagesReceivedRate: 1.5, Topics: 10})
+	assert.Equal(t, 1.5, testutil.ToFloat64(messagesReceivedRate.WithLabelValues("EMQX", "default", "emqx")))
+	assert.Equal(t, float64(10), testutil.ToFloat64(topics.WithLabelValues("EMQX", "default", "emqx")))
+
+	// The custom resource of another kind with the same name is not affected
+	SetClusterStatsGauges("EmqxBroker", "default", "emqx", ClusterStats{Topics: 20})
+	DeleteInstance("EmqxBroker", "default", "emqx")
+	assert.Equal(t, float64(10), testutil.ToFloat64(topics.WithLabelValues("EMQX", "default", "emqx")))
+
+	DeleteInstance("EMQX", "default", "emqx")
+	assert.Equal(t, 0, testutil.CollectAndCount(topics))
+}
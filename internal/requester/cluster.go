@@ -0,0 +1,245 @@
+package requester
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	emperror "emperror.dev/errors"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// QPS and Burst limit the rate of the requests to each EMQX cluster, shared by all the requesters of the cluster,
+// they are set by the flags of the operator before the controllers start.
+var (
+	QPS   float32 = 20
+	Burst         = 40
+)
+
+const (
+	// failureThreshold is the number of consecutive failed requests to open the circuit of the cluster
+	failureThreshold = 5
+	// openTimeout is how long the circuit stays open before a request is let through to probe the cluster
+	openTimeout = 30 * time.Second
+	// cacheTTL is short enough that the responses are only shared by the requests in the same reconciliation
+	cacheTTL = 2 * time.Second
+)
+
+// ErrCircuitOpen is returned without requesting the API when the management API of the cluster keeps failing
+var ErrCircuitOpen = emperror.NewPlain("circuit breaker is open")
+
+// CircuitState is the state of the circuit breaker of an EMQX cluster
+type CircuitState struct {
+	Open bool
+	// Since is the time the circuit was opened
+	Since time.Time
+	// LastError is the failure of the last request that opened the circuit
+	LastError string
+}
+
+// clusterGuard limits the rate of the requests to an EMQX cluster, stops requesting it by the Pool while its
+// management API keeps failing, and caches the responses of the GET requests for a short time.
+// The circuit breaker is fed only by the Pool, the requests to a single node say nothing about the whole cluster.
+type clusterGuard struct {
+	limiter flowcontrol.RateLimiter
+
+	mu        sync.Mutex
+	failures  int
+	openedAt  time.Time
+	lastError string
+	// probing is true when the circuit is half-open and a request is sent to check whether the cluster recovers
+	probing bool
+	cache   map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	resp    *http.Response
+	body    []byte
+	expires time.Time
+}
+
+var clusters = struct {
+	sync.Mutex
+	m map[string]*clusterGuard
+}{m: map[string]*clusterGuard{}}
+
+// clusterKey contains the kind of the EMQX custom resource, since the EmqxBroker and the EMQX with the same name
+// in the same namespace are different clusters, like during the migration from EMQX 4 to EMQX 5.
+func clusterKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// getClusterGuard returns the guard of the EMQX cluster, the requesters not labeled with the cluster are not guarded
+func getClusterGuard(kind, namespace, name string) *clusterGuard {
+	if name == "" {
+		return nil
+	}
+	clusters.Lock()
+	defer clusters.Unlock()
+	key := clusterKey(kind, namespace, name)
+	if g, ok := clusters.m[key]; ok {
+		return g
+	}
+	g := &clusterGuard{
+		limiter: flowcontrol.NewTokenBucketRateLimiter(QPS, Burst),
+		cache:   map[string]cachedResponse{},
+	}
+	clusters.m[key] = g
+	return g
+}
+
+// GetCircuitState returns the state of the circuit breaker of the EMQX cluster
+func GetCircuitState(kind, namespace, name string) CircuitState {
+	clusters.Lock()
+	g, ok := clusters.m[clusterKey(kind, namespace, name)]
+	clusters.Unlock()
+	if !ok {
+		return CircuitState{}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return CircuitState{Open: !g.openedAt.IsZero(), Since: g.openedAt, LastError: g.lastError}
+}
+
+// ForgetCluster drops the rate limiter, circuit breaker, cache and transport of the deleted EMQX cluster
+func ForgetCluster(kind, namespace, name string) {
+	clusters.Lock()
+	delete(clusters.m, clusterKey(kind, namespace, name))
+	clusters.Unlock()
+
+	transports.Lock()
+	defer transports.Unlock()
+	releaseTransport(clusterKey(kind, namespace, name))
+}
+
+// do sends the request unless the response is cached, the host is a part of the cache key since some APIs answer
+// for the node receiving the request, it is empty if any node of the cluster may answer. The username is a part of
+// the cache key too, so that the responses to an API key are never served to another one. If breaker is true,
+// the request is not sent while the circuit is open, and the request records the result of each endpoint by record.
+func (g *clusterGuard) do(
+	ctx context.Context, host, username, method, path string, breaker bool,
+	request func() (*http.Response, []byte, error),
+) (*http.Response, []byte, error) {
+	key := username + "@" + host + " " + path
+	if method == http.MethodGet {
+		if resp, body, ok := g.getCache(key); ok {
+			return resp, body, nil
+		}
+	} else {
+		// The changes are visible to the next GET requests
+		g.clearCache()
+	}
+
+	if breaker {
+		probe, err := g.allow()
+		if err != nil {
+			return nil, nil, err
+		}
+		if probe {
+			// The probe is over even if the request records nothing, like when there is no endpoint to request
+			defer g.cancelProbe()
+		}
+	}
+	if err := g.limiter.Wait(ctx); err != nil {
+		return nil, nil, emperror.Wrap(err, "failed to wait for the rate limiter")
+	}
+	resp, body, err := request()
+	if method == http.MethodGet && err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		g.setCache(key, resp, body)
+	}
+	return resp, body, err
+}
+
+// allow returns ErrCircuitOpen if the circuit is open, after openTimeout only one request is let through at a time
+// to probe the cluster.
+func (g *clusterGuard) allow() (probe bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.openedAt.IsZero() {
+		return false, nil
+	}
+	if !g.probing && time.Since(g.openedAt) >= openTimeout {
+		g.probing = true
+		return true, nil
+	}
+	return false, emperror.WithMessagef(ErrCircuitOpen, "the last %d requests failed, last error: %s", g.failures, g.lastError)
+}
+
+// cancelProbe lets another request probe the cluster if the probing request is not sent or records nothing
+func (g *clusterGuard) cancelProbe() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.probing = false
+}
+
+// record counts the consecutive failed requests to the endpoints of the cluster, the circuit is opened when they
+// reach failureThreshold, and closed by a successful request.
+func (g *clusterGuard) record(ctx context.Context, resp *http.Response, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	probing := g.probing
+	g.probing = false
+
+	// The caller gives up, it says nothing about the cluster
+	if ctx.Err() != nil {
+		return
+	}
+	if !isFailure(resp, err) {
+		g.failures = 0
+		g.openedAt = time.Time{}
+		g.lastError = ""
+		return
+	}
+
+	g.failures++
+	if err != nil {
+		g.lastError = err.Error()
+	} else {
+		g.lastError = resp.Status
+	}
+	if probing || g.failures >= failureThreshold {
+		g.openedAt = time.Now()
+	}
+}
+
+// isFailure returns true if the management API is unreachable or broken, 503 is not a failure since it is
+// the answer of the availability check of the nodes being evacuated, and 429 means the request is too frequent.
+func isFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (g *clusterGuard) getCache(key string) (*http.Response, []byte, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	cached, ok := g.cache[key]
+	if !ok || time.Now().After(cached.expires) {
+		return nil, nil, false
+	}
+	return cached.resp, cached.body, true
+}
+
+func (g *clusterGuard) setCache(key string, resp *http.Response, body []byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	for k, cached := range g.cache {
+		if now.After(cached.expires) {
+			delete(g.cache, k)
+		}
+	}
+	g.cache[key] = cachedResponse{resp: resp, body: body, expires: now.Add(cacheTTL)}
+}
+
+func (g *clusterGuard) clearCache() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cache = map[string]cachedResponse{}
+}
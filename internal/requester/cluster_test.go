@@ -0,0 +1,268 @@
+package requester
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	emperror "emperror.dev/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+	defer server.Close()
+	defer ForgetCluster("EMQX", "default", "cache")
+
+	r := &Requester{Host: server.Listener.Addr().String(), Kind: "EMQX", Namespace: "default", Instance: "cache"}
+
+	t.Run("cache the GET requests", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			resp, body, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "GET /api/v5/nodes", string(body))
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/alarms", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("the host is a part of the cache key", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		other := *r
+		other.Host = "localhost:" + r.Host[len("127.0.0.1:"):]
+		_, _, err := other.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("the other requests clear the cache", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		_, _, err := r.Request(context.Background(), "POST", "api/v5/load_rebalance/emqx@10.0.0.1/evacuation/start", nil)
+		assert.Nil(t, err)
+		_, _, err = r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("the username is a part of the cache key", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		other := *r
+		other.Username = "rotated"
+		_, _, err := other.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("the custom resources of different kinds do not share the cache", func(t *testing.T) {
+		defer ForgetCluster("EmqxBroker", "default", "cache")
+		atomic.StoreInt32(&requests, 0)
+		other := *r
+		other.Kind = "EmqxBroker"
+		_, _, err := other.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("not labeled with the cluster", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		r := &Requester{Host: server.Listener.Addr().String()}
+		for i := 0; i < 2; i++ {
+			_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	})
+}
+
+func TestClusterCircuitBreaker(t *testing.T) {
+	var statusCode int32 = http.StatusBadGateway
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/api/v5/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.WriteHeader(int(atomic.LoadInt32(&statusCode)))
+	}))
+	defer server.Close()
+	defer ForgetCluster("EMQX", "default", "circuit")
+
+	endpoint := Endpoint{Name: "emqx-core-0", Host: server.Listener.Addr().String()}
+	r := &Pool{
+		Requester: Requester{Retries: -1, Kind: "EMQX", Namespace: "default", Instance: "circuit"},
+		Endpoints: []Endpoint{endpoint},
+	}
+
+	t.Run("the requests to a single node do not open the circuit", func(t *testing.T) {
+		node := &Requester{Host: endpoint.Host, Retries: -1, Kind: "EMQX", Namespace: "default", Instance: "circuit"}
+		for i := 0; i < failureThreshold; i++ {
+			resp, _, err := node.Request(context.Background(), "GET", "api/v5/load_rebalance/availability_check", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		}
+		assert.False(t, GetCircuitState("EMQX", "default", "circuit").Open)
+		atomic.StoreInt32(&requests, 0)
+	})
+
+	t.Run("open after repeated failures", func(t *testing.T) {
+		for i := 0; i < failureThreshold; i++ {
+			assert.False(t, GetCircuitState("EMQX", "default", "circuit").Open)
+			resp, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		}
+		state := GetCircuitState("EMQX", "default", "circuit")
+		assert.True(t, state.Open)
+		assert.False(t, GetCircuitState("EmqxBroker", "default", "circuit").Open)
+		assert.Equal(t, "502 Bad Gateway", state.LastError)
+
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.True(t, emperror.Is(err, ErrCircuitOpen))
+		assert.ErrorContains(t, err, "the last 5 requests failed, last error: 502 Bad Gateway")
+		assert.Equal(t, int32(failureThreshold), atomic.LoadInt32(&requests))
+	})
+
+	g := getClusterGuard("EMQX", "default", "circuit")
+
+	t.Run("reopen if the probe fails", func(t *testing.T) {
+		g.mu.Lock()
+		g.openedAt = time.Now().Add(-openTimeout)
+		g.mu.Unlock()
+
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(failureThreshold+1), atomic.LoadInt32(&requests))
+		assert.True(t, GetCircuitState("EMQX", "default", "circuit").Open)
+		_, _, err = r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.True(t, emperror.Is(err, ErrCircuitOpen))
+	})
+
+	t.Run("close if the probe succeeds", func(t *testing.T) {
+		g.mu.Lock()
+		g.openedAt = time.Now().Add(-openTimeout)
+		g.mu.Unlock()
+		atomic.StoreInt32(&statusCode, http.StatusOK)
+
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, CircuitState{}, GetCircuitState("EMQX", "default", "circuit"))
+	})
+
+	t.Run("unavailable is not a failure", func(t *testing.T) {
+		atomic.StoreInt32(&statusCode, http.StatusServiceUnavailable)
+		for i := 0; i < failureThreshold; i++ {
+			_, _, err := r.Request(context.Background(), "GET", "api/v5/load_rebalance/availability_check", nil)
+			assert.Nil(t, err)
+		}
+		assert.False(t, GetCircuitState("EMQX", "default", "circuit").Open)
+	})
+
+	t.Run("canceled requests are not failures", func(t *testing.T) {
+		atomic.StoreInt32(&statusCode, http.StatusBadGateway)
+		for i := 0; i < failureThreshold; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			_, _, err := r.Request(ctx, "GET", "api/v5/slow", nil)
+			cancel()
+			assert.NotNil(t, err)
+		}
+		assert.False(t, GetCircuitState("EMQX", "default", "circuit").Open)
+	})
+}
+
+func TestClusterCircuitBreakerFailover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	defer ForgetCluster("EMQX", "default", "failover")
+
+	// Each endpoint failed over counts as a failure
+	var endpoints []Endpoint
+	for i := 0; i < failureThreshold; i++ {
+		endpoints = append(endpoints, Endpoint{Name: "emqx-core-" + strconv.Itoa(i), Host: server.Listener.Addr().String()})
+	}
+	p := &Pool{
+		Requester: Requester{Retries: -1, Kind: "EMQX", Namespace: "default", Instance: "failover"},
+		Endpoints: endpoints,
+	}
+	resp, _, err := p.Request(context.Background(), "GET", "api/v5/nodes", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.True(t, GetCircuitState("EMQX", "default", "failover").Open)
+
+	t.Run("no endpoint to probe", func(t *testing.T) {
+		g := getClusterGuard("EMQX", "default", "failover")
+		g.mu.Lock()
+		g.openedAt = time.Now().Add(-openTimeout)
+		g.mu.Unlock()
+
+		_, _, err := (&Pool{Requester: p.Requester}).Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.ErrorContains(t, err, "no endpoint")
+		// The next request probes the cluster
+		_, _, err = p.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+	})
+}
+
+func TestClusterRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	qps, burst := QPS, Burst
+	QPS, Burst = 10, 1
+	defer func() { QPS, Burst = qps, burst }()
+	defer ForgetCluster("EMQX", "default", "rate-limit")
+
+	// The requesters of the same cluster share the limiter
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		r := &Requester{Host: server.Listener.Addr().String(), Kind: "EMQX", Namespace: "default", Instance: "rate-limit"}
+		_, _, err := r.Request(context.Background(), "POST", "api/v5/load_rebalance/emqx@10.0.0.1/evacuation/start", nil)
+		assert.Nil(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	t.Run("the deadline is shorter than the wait", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		r := &Requester{Host: server.Listener.Addr().String(), Kind: "EMQX", Namespace: "default", Instance: "rate-limit"}
+		_, _, err := r.Request(ctx, "POST", "api/v5/load_rebalance/emqx@10.0.0.1/evacuation/start", nil)
+		assert.ErrorContains(t, err, "failed to wait for the rate limiter")
+	})
+}
+
+func TestPoolCache(t *testing.T) {
+	var requests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	})
+	first, second := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer first.Close()
+	defer second.Close()
+	defer ForgetCluster("EMQX", "default", "pool")
+
+	newPool := func(endpoints ...Endpoint) *Pool {
+		return &Pool{
+			Requester: Requester{Kind: "EMQX", Namespace: "default", Instance: "pool"},
+			Endpoints: endpoints,
+		}
+	}
+	// Any endpoint of the cluster may answer the cached request
+	_, _, err := newPool(Endpoint{Host: first.Listener.Addr().String()}).Request(context.Background(), "GET", "api/v5/nodes", nil)
+	assert.Nil(t, err)
+	_, _, err = newPool(Endpoint{Host: second.Listener.Addr().String()}).Request(context.Background(), "GET", "api/v5/nodes", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
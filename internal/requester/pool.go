@@ -0,0 +1,218 @@
+package requester
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	emperror "emperror.dev/errors"
+	"github.com/emqx/emqx-operator/internal/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// healthCheckTimeout is the timeout to check the health of each endpoint,
+	// it is shorter than the timeout of the requests to find an unreachable endpoint quickly.
+	healthCheckTimeout = 3 * time.Second
+	// requestTimeout bounds each request of the pool, including the health check, the fail over and the retries,
+	// so that the unreachable endpoints do not block the reconciliation
+	requestTimeout = 15 * time.Second
+)
+
+// Endpoint is a candidate to request the EMQX management API, like an EMQX pod or the dashboard service
+type Endpoint struct {
+	// Name is used to identify the endpoint in the logs and metrics, like the name of the pod
+	Name string
+	Host string
+}
+
+// Pool requests the EMQX management API by the first healthy endpoint,
+// and falls back to the next endpoint if the current one is unreachable.
+type Pool struct {
+	// Requester is the template of the requests to each endpoint, its host is ignored
+	Requester Requester
+	Endpoints []Endpoint
+	// HealthCheckPath is requested before the first request to find a healthy endpoint,
+	// the endpoints are not checked if it is empty
+	HealthCheckPath string
+
+	mu      sync.Mutex
+	checked bool
+	current int
+}
+
+func (p *Pool) GetScheme() string {
+	return p.Requester.GetScheme()
+}
+
+func (p *Pool) GetUsername() string {
+	return p.Requester.GetUsername()
+}
+
+func (p *Pool) GetPassword() string {
+	return p.Requester.GetPassword()
+}
+
+func (p *Pool) GetTLSConfig() *TLSConfig {
+	return p.Requester.GetTLSConfig()
+}
+
+// GetHost returns the host of the endpoint that is currently used
+func (p *Pool) GetHost() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.Endpoints) == 0 {
+		return ""
+	}
+	return p.Endpoints[p.current].Host
+}
+
+// Request sends the request by the current endpoint, the other endpoints are tried in order if it is unreachable,
+// only the last endpoint retries the request, the others fail over to the next endpoint instead.
+// The whole request is bounded by requestTimeout, and each failed endpoint counts to the circuit breaker of the cluster.
+func (p *Pool) Request(ctx context.Context, method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+	caller := ctx
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	g := getClusterGuard(p.Requester.Kind, p.Requester.Namespace, p.Requester.Instance)
+	if g == nil {
+		return p.request(ctx, method, path, body, nil)
+	}
+	// The endpoints are of the same cluster, any of them may answer the cached request
+	return g.do(ctx, "", p.Requester.Username, method, path, true, func() (*http.Response, []byte, error) {
+		return p.request(ctx, method, path, body, func(resp *http.Response, err error) {
+			// The endpoints not answering in requestTimeout are failures, unless the caller gives up
+			g.record(caller, resp, err)
+		})
+	})
+}
+
+func (p *Pool) request(
+	ctx context.Context, method, path string, body []byte,
+	record func(resp *http.Response, err error),
+) (resp *http.Response, respBody []byte, err error) {
+	if len(p.Endpoints) == 0 {
+		return nil, nil, emperror.New("no endpoint to request API")
+	}
+	p.healthCheck(ctx)
+
+	logger := log.FromContext(ctx)
+	order := p.getOrder()
+	for i, index := range order {
+		endpoint := p.Endpoints[index]
+		requester := p.newRequester(endpoint)
+		last := i == len(order)-1
+		if !last {
+			requester.Retries = -1
+		}
+
+		resp, respBody, err = requester.request(ctx, method, path, body)
+		if record != nil {
+			record(resp, err)
+		}
+		if last || ctx.Err() != nil || !shouldFailover(method, resp, err) {
+			metrics.ObserveAPIEndpoint(p.Requester.Kind, p.Requester.Namespace, p.Requester.Instance, endpoint.Name, getEndpointResult(resp, err))
+			logger.V(1).Info("requested EMQX API", "endpoint", endpoint.Name, "method", method, "path", path)
+			if err == nil {
+				p.setCurrent(index)
+			}
+			return resp, respBody, err
+		}
+		metrics.ObserveAPIEndpoint(p.Requester.Kind, p.Requester.Namespace, p.Requester.Instance, endpoint.Name, metrics.APIEndpointFailover)
+		logger.V(1).Info("failed to request EMQX API, fall back to the next endpoint", "endpoint", endpoint.Name, "method", method, "path", path, "error", getFailoverReason(resp, err))
+	}
+	return resp, respBody, err
+}
+
+// healthCheck finds the first healthy endpoint, the endpoints are checked only once and in parallel,
+// the first endpoint is used if none of them is healthy.
+func (p *Pool) healthCheck(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.checked || p.HealthCheckPath == "" {
+		return
+	}
+	p.checked = true
+
+	// The checks of the following endpoints are canceled once an endpoint is found healthy
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	logger := log.FromContext(ctx)
+	healthy := make([]chan bool, len(p.Endpoints))
+	for i, endpoint := range p.Endpoints {
+		healthy[i] = make(chan bool, 1)
+		go func(endpoint Endpoint, healthy chan<- bool) {
+			requester := p.newRequester(endpoint)
+			requester.Retries = -1
+			requester.Timeout = healthCheckTimeout
+			resp, _, err := requester.request(ctx, http.MethodGet, p.HealthCheckPath, nil)
+			if err == nil && resp.StatusCode == http.StatusOK {
+				healthy <- true
+				return
+			}
+			if ctx.Err() == nil {
+				metrics.ObserveAPIEndpoint(p.Requester.Kind, p.Requester.Namespace, p.Requester.Instance, endpoint.Name, metrics.APIEndpointUnhealthy)
+				logger.V(1).Info("EMQX API endpoint is unhealthy", "endpoint", endpoint.Name, "error", getFailoverReason(resp, err))
+			}
+			healthy <- false
+		}(endpoint, healthy[i])
+	}
+	// The endpoints are in order of priority, the result of an endpoint is waited only if the previous ones are unhealthy
+	for i := range healthy {
+		if <-healthy[i] {
+			p.current = i
+			return
+		}
+	}
+}
+
+// getOrder returns the indexes of the endpoints to try, starting from the current one
+func (p *Pool) getOrder() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	order := make([]int, 0, len(p.Endpoints))
+	for i := range p.Endpoints {
+		order = append(order, (p.current+i)%len(p.Endpoints))
+	}
+	return order
+}
+
+func (p *Pool) setCurrent(index int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = index
+}
+
+func (p *Pool) newRequester(endpoint Endpoint) *Requester {
+	requester := p.Requester
+	requester.Host = endpoint.Host
+	return &requester
+}
+
+// shouldFailover returns true if the request may succeed by the other endpoints,
+// the non-idempotent requests fail over only if they are not sent to the current endpoint.
+func shouldFailover(method string, resp *http.Response, err error) bool {
+	if method != http.MethodGet {
+		var opErr *net.OpError
+		return err != nil && emperror.As(err, &opErr) && opErr.Op == "dial"
+	}
+	return shouldRetry(resp, err)
+}
+
+func getEndpointResult(resp *http.Response, err error) string {
+	if err != nil {
+		return metrics.APIEndpointUnreachable
+	}
+	return metrics.APIEndpointOK
+}
+
+func getFailoverReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
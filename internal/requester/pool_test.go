@@ -0,0 +1,111 @@
+package requester
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool(t *testing.T) {
+	var requests int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" {
+			atomic.AddInt32(&requests, 1)
+		}
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	newPool := func(healthCheckPath string, endpoints ...Endpoint) *Pool {
+		return &Pool{
+			Requester:       Requester{Backoff: time.Millisecond},
+			Endpoints:       endpoints,
+			HealthCheckPath: healthCheckPath,
+		}
+	}
+	healthyEndpoint := Endpoint{Name: "emqx-core-1", Host: healthy.Listener.Addr().String()}
+	unhealthyEndpoint := Endpoint{Name: "emqx-core-2", Host: unhealthy.Listener.Addr().String()}
+	closedEndpoint := Endpoint{Name: "emqx-core-0", Host: closed.Listener.Addr().String()}
+
+	t.Run("no endpoint", func(t *testing.T) {
+		_, _, err := newPool("").Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.ErrorContains(t, err, "no endpoint")
+	})
+
+	t.Run("health check", func(t *testing.T) {
+		p := newPool("status", closedEndpoint, unhealthyEndpoint, healthyEndpoint)
+		assert.Equal(t, closedEndpoint.Host, p.GetHost())
+
+		resp, body, err := p.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "GET /api/v5/nodes", string(body))
+		assert.Equal(t, healthyEndpoint.Host, p.GetHost())
+	})
+
+	t.Run("health check in parallel", func(t *testing.T) {
+		hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer hanging.Close()
+		hangingEndpoint := Endpoint{Name: "emqx-core-3", Host: hanging.Listener.Addr().String()}
+
+		p := newPool("status", hangingEndpoint, hangingEndpoint, healthyEndpoint)
+		start := time.Now()
+		_, _, err := p.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Less(t, time.Since(start), 2*healthCheckTimeout)
+		assert.Equal(t, healthyEndpoint.Host, p.GetHost())
+	})
+
+	t.Run("fail over on connection error", func(t *testing.T) {
+		p := newPool("", closedEndpoint, healthyEndpoint)
+		resp, _, err := p.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		// The healthy endpoint is used by the following requests
+		assert.Equal(t, healthyEndpoint.Host, p.GetHost())
+	})
+
+	t.Run("fail over on unavailable endpoint", func(t *testing.T) {
+		p := newPool("", unhealthyEndpoint, healthyEndpoint)
+		resp, _, err := p.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("non-idempotent request", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		p := newPool("", closedEndpoint, healthyEndpoint)
+		resp, body, err := p.Request(context.Background(), "POST", "api/v5/load_rebalance/emqx@10.0.0.1/evacuation/start", []byte(`{}`))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "POST /api/v5/load_rebalance/emqx@10.0.0.1/evacuation/start", string(body))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+		// The request is received by the endpoint, it must not be sent again
+		p = newPool("", unhealthyEndpoint, healthyEndpoint)
+		resp, _, err = p.Request(context.Background(), "POST", "api/v5/load_rebalance/emqx@10.0.0.1/evacuation/start", []byte(`{}`))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("all endpoints are unreachable", func(t *testing.T) {
+		p := newPool("status", closedEndpoint, closedEndpoint)
+		_, _, err := p.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.ErrorContains(t, err, "failed to request API")
+		assert.Equal(t, closedEndpoint.Host, p.GetHost())
+	})
+}
@@ -2,24 +2,108 @@ package requester
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	emperror "emperror.dev/errors"
+	"github.com/emqx/emqx-operator/internal/metrics"
 )
 
 type RequesterInterface interface {
+	GetScheme() string
 	GetHost() string
 	GetUsername() string
 	GetPassword() string
-	Request(method, path string, body []byte) (resp *http.Response, respBody []byte, err error)
+	GetTLSConfig() *TLSConfig
+	Request(ctx context.Context, method, path string, body []byte) (resp *http.Response, respBody []byte, err error)
 }
 
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRetries = 2
+	DefaultBackoff = 500 * time.Millisecond
+	// maxBackoff is the max interval between the retries
+	maxBackoff = 5 * time.Second
+)
+
 type Requester struct {
+	// Scheme is http or https, defaults to http
+	Scheme   string
 	Host     string
 	Username string
 	Password string
+	// TLS is used when the scheme is https, the system CA bundle is used to verify the server if it is nil
+	TLS *TLSConfig
+	// Timeout is the timeout of each attempt of the request, DefaultTimeout is used if it is 0,
+	// the whole request, including the retries, is bounded by the deadline of the context
+	Timeout time.Duration
+	// Retries is the max number of retries of the GET requests, DefaultRetries is used if it is 0,
+	// and the requests are not retried if it is negative
+	Retries int
+	// Backoff is the interval before the first retry, it is doubled after each retry, DefaultBackoff is used if it is 0
+	Backoff time.Duration
+	// Kind, Namespace and Instance are the kind, namespace and name of the EMQX custom resource, used to label the metrics
+	// of the requests, the custom resources of different kinds, like EmqxBroker and EMQX, may have the same name
+	Kind      string
+	Namespace string
+	Instance  string
+}
+
+// TLSConfig holds the PEM encoded certificates to request the EMQX management API over HTTPS
+type TLSConfig struct {
+	// CA bundle to verify the certificate of the server, the system CA bundle is used if it is empty
+	CACert []byte
+	// Client certificate and key, required if the server verifies the client
+	ClientCert []byte
+	ClientKey  []byte
+	// ServerName is used to verify the hostname of the certificate of the server,
+	// the requests are usually sent to the pod IPs, which are not in the certificate
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+func (c *TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if len(c.CACert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.CACert) {
+			return nil, emperror.New("failed to parse CA certificate")
+		}
+		config.RootCAs = pool
+	}
+	if len(c.ClientCert) > 0 || len(c.ClientKey) > 0 {
+		cert, err := tls.X509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, emperror.Wrap(err, "failed to parse client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (requester *Requester) GetScheme() string {
+	if requester.Scheme == "" {
+		return "http"
+	}
+	return requester.Scheme
+}
+
+func (requester *Requester) GetTLSConfig() *TLSConfig {
+	return requester.TLS
 }
 
 func (requester *Requester) GetUsername() string {
@@ -34,29 +118,201 @@ func (requester *Requester) GetHost() string {
 	return requester.Host
 }
 
-func (requester *Requester) Request(method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+func (requester *Requester) getTimeout() time.Duration {
+	if requester.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return requester.Timeout
+}
+
+func (requester *Requester) getRetries(method string) int {
+	// Only the GET requests are idempotent in the EMQX management API
+	if method != http.MethodGet || requester.Retries < 0 {
+		return 0
+	}
+	if requester.Retries == 0 {
+		return DefaultRetries
+	}
+	return requester.Retries
+}
+
+func (requester *Requester) getBackoff(retry int) time.Duration {
+	backoff := requester.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// Request sends the request to the EMQX management API, the GET requests are retried with exponential backoff
+// if the EMQX node is unreachable or temporarily unavailable. The requests of the requesters labeled with the
+// EMQX cluster are rate limited and cached, but not stopped by the circuit breaker of the cluster since the node
+// may be restarting while the cluster is healthy.
+func (requester *Requester) Request(ctx context.Context, method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
+	if g := getClusterGuard(requester.Kind, requester.Namespace, requester.Instance); g != nil {
+		return g.do(ctx, requester.Host, requester.Username, method, path, false, func() (*http.Response, []byte, error) {
+			return requester.request(ctx, method, path, body)
+		})
+	}
+	return requester.request(ctx, method, path, body)
+}
+
+func (requester *Requester) request(ctx context.Context, method, path string, body []byte) (resp *http.Response, respBody []byte, err error) {
 	url := url.URL{
-		Scheme: "http",
+		Scheme: requester.GetScheme(),
 		Host:   requester.GetHost(),
 		Path:   path,
 	}
+	// The path may contain the query, like api/v5/alarms?activated=true
+	if p, query, ok := strings.Cut(path, "?"); ok {
+		url.Path = p
+		url.RawQuery = query
+	}
 
-	httpClient := http.Client{}
-	req, err := http.NewRequest(method, url.String(), bytes.NewReader(body))
+	var cluster string
+	if requester.Instance != "" {
+		cluster = clusterKey(requester.Kind, requester.Namespace, requester.Instance)
+	}
+	transport, err := getTransport(cluster, url.Scheme, requester.TLS)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpClient := &http.Client{Transport: transport}
+
+	retries := requester.getRetries(method)
+	for retry := 0; ; retry++ {
+		resp, respBody, err = requester.do(ctx, httpClient, method, url, body)
+		if retry >= retries || !shouldRetry(resp, err) {
+			return resp, respBody, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, emperror.Wrap(ctx.Err(), "failed to request API")
+		case <-time.After(requester.getBackoff(retry + 1)):
+		}
+	}
+}
+
+// do sends the request once, the response body is read before the timeout of the attempt
+func (requester *Requester) do(ctx context.Context, httpClient *http.Client, method string, url url.URL, body []byte) (*http.Response, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, requester.getTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url.String(), bytes.NewReader(body))
 	if err != nil {
 		return nil, nil, emperror.Wrap(err, "failed to create request")
 	}
 	req.SetBasicAuth(requester.GetUsername(), requester.GetPassword())
-	req.Close = true
-	resp, err = httpClient.Do(req)
+	start := time.Now()
+	resp, err := httpClient.Do(req)
 	if err != nil {
+		metrics.ObserveAPIRequest(requester.Kind, requester.Namespace, requester.Instance, method, url.Path, 0, time.Since(start))
 		return nil, nil, emperror.Wrap(err, "failed to request API")
 	}
+	metrics.ObserveAPIRequest(requester.Kind, requester.Namespace, requester.Instance, method, url.Path, resp.StatusCode, time.Since(start))
 
 	defer resp.Body.Close()
-	body, err = io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return resp, nil, emperror.Wrap(err, "failed to read response body")
 	}
-	return resp, body, nil
+	return resp, respBody, nil
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		// The caller gives up, e.g. the reconcile is canceled
+		if emperror.Is(err, context.Canceled) {
+			return false
+		}
+		// The certificate of the server can not be verified, it fails again
+		var verifyErr *tls.CertificateVerificationError
+		return !emperror.As(err, &verifyErr)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// transports are shared by the requesters to keep the connections alive,
+// the HTTPS transports are keyed by the TLS config, and the key of the transport used by each EMQX cluster
+// is recorded, so that the transport is dropped once no cluster uses it, e.g. the certificates are rotated
+var transports = struct {
+	sync.Mutex
+	m        map[string]*http.Transport
+	clusters map[string]string
+}{m: map[string]*http.Transport{}, clusters: map[string]string{}}
+
+// getTransport returns the transport of the scheme and the TLS config, the cluster is empty if the requester
+// is not labeled with the EMQX cluster
+func getTransport(cluster, scheme string, config *TLSConfig) (*http.Transport, error) {
+	key := scheme
+	if scheme == "https" {
+		key += "/" + config.hash()
+	}
+
+	transports.Lock()
+	defer transports.Unlock()
+	if cluster != "" && transports.clusters[cluster] != key {
+		releaseTransport(cluster)
+		transports.clusters[cluster] = key
+	}
+	if transport, ok := transports.m[key]; ok {
+		return transport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 10
+	if scheme == "https" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if config != nil {
+			var err error
+			if tlsConfig, err = config.build(); err != nil {
+				delete(transports.clusters, cluster)
+				return nil, err
+			}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	transports.m[key] = transport
+	return transport, nil
+}
+
+// releaseTransport drops the transport used by the cluster if no other cluster uses it, the caller holds the lock
+func releaseTransport(cluster string) {
+	key, ok := transports.clusters[cluster]
+	if !ok {
+		return
+	}
+	delete(transports.clusters, cluster)
+	for _, k := range transports.clusters {
+		if k == key {
+			return
+		}
+	}
+	if transport, ok := transports.m[key]; ok {
+		transport.CloseIdleConnections()
+		delete(transports.m, key)
+	}
+}
+
+func (c *TLSConfig) hash() string {
+	if c == nil {
+		return ""
+	}
+	h := sha256.New()
+	for _, b := range [][]byte{c.CACert, c.ClientCert, c.ClientKey, []byte(c.ServerName)} {
+		_, _ = h.Write([]byte(strconv.Itoa(len(b)) + ":"))
+		_, _ = h.Write(b)
+	}
+	_, _ = h.Write([]byte(strconv.FormatBool(c.InsecureSkipVerify)))
+	return hex.EncodeToString(h.Sum(nil))
 }
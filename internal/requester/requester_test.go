@@ -0,0 +1,258 @@
+package requester
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestOverHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v5/nodes", r.URL.Path)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	t.Run("verify the server by the CA bundle", func(t *testing.T) {
+		r := &Requester{
+			Scheme: "https",
+			Host:   server.Listener.Addr().String(),
+			// The certificate of httptest is issued for example.com
+			TLS: &TLSConfig{CACert: caCert, ServerName: "example.com"},
+		}
+		resp, body, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `[]`, string(body))
+	})
+
+	t.Run("unknown authority", func(t *testing.T) {
+		r := &Requester{Scheme: "https", Host: server.Listener.Addr().String()}
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.ErrorContains(t, err, "certificate")
+	})
+
+	t.Run("skip verify", func(t *testing.T) {
+		r := &Requester{
+			Scheme: "https",
+			Host:   server.Listener.Addr().String(),
+			TLS:    &TLSConfig{InsecureSkipVerify: true},
+		}
+		resp, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("invalid CA bundle", func(t *testing.T) {
+		r := &Requester{
+			Scheme: "https",
+			Host:   server.Listener.Addr().String(),
+			TLS:    &TLSConfig{CACert: []byte("fake")},
+		}
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.ErrorContains(t, err, "failed to parse CA certificate")
+	})
+}
+
+func TestGetScheme(t *testing.T) {
+	assert.Equal(t, "http", (&Requester{}).GetScheme())
+	assert.Equal(t, "https", (&Requester{Scheme: "https"}).GetScheme())
+}
+
+func TestRequestTimeout(t *testing.T) {
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-blocked:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(blocked)
+
+	t.Run("timeout of each attempt", func(t *testing.T) {
+		r := &Requester{Host: server.Listener.Addr().String(), Timeout: 50 * time.Millisecond, Retries: -1}
+		start := time.Now()
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("deadline of the context", func(t *testing.T) {
+		r := &Requester{Host: server.Listener.Addr().String(), Timeout: 50 * time.Millisecond, Backoff: time.Second}
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _, err := r.Request(ctx, "GET", "api/v5/nodes", nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		// The backoff is longer than the deadline, so the request is not retried
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		r := &Requester{Host: server.Listener.Addr().String()}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := r.Request(ctx, "GET", "api/v5/nodes", nil)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRequestRetry(t *testing.T) {
+	var count int32
+	var failures int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= atomic.LoadInt32(&failures) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/api/v5/bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`ok`))
+	}))
+	defer server.Close()
+
+	newRequester := func(retries int) *Requester {
+		return &Requester{Host: server.Listener.Addr().String(), Retries: retries, Backoff: time.Millisecond}
+	}
+	reset := func(f int32) {
+		atomic.StoreInt32(&count, 0)
+		atomic.StoreInt32(&failures, f)
+	}
+
+	t.Run("retry until success", func(t *testing.T) {
+		reset(2)
+		resp, body, err := newRequester(0).Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "ok", string(body))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		reset(5)
+		resp, _, err := newRequester(3).Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&count))
+	})
+
+	t.Run("no retry if disabled", func(t *testing.T) {
+		reset(1)
+		resp, _, err := newRequester(-1).Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	})
+
+	t.Run("no retry for non-idempotent requests", func(t *testing.T) {
+		reset(1)
+		resp, _, err := newRequester(0).Request(context.Background(), "POST", "api/v5/nodes", []byte(`{}`))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	})
+
+	t.Run("no retry for client errors", func(t *testing.T) {
+		reset(0)
+		resp, _, err := newRequester(0).Request(context.Background(), "GET", "api/v5/bad", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	})
+
+	t.Run("retry if the server is unreachable", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		start := time.Now()
+		r := &Requester{Host: closed.Listener.Addr().String(), Retries: 2, Backoff: 20 * time.Millisecond}
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.ErrorContains(t, err, "failed to request API")
+		// Wait 20ms and 40ms before the retries
+		assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+	})
+}
+
+func TestGetBackoff(t *testing.T) {
+	r := &Requester{}
+	assert.Equal(t, DefaultBackoff, r.getBackoff(1))
+	assert.Equal(t, 2*DefaultBackoff, r.getBackoff(2))
+	assert.Equal(t, 4*DefaultBackoff, r.getBackoff(3))
+	assert.Equal(t, maxBackoff, r.getBackoff(10))
+}
+
+func TestSharedTransport(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`ok`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		r := &Requester{Host: server.Listener.Addr().String()}
+		_, _, err := r.Request(context.Background(), "GET", "api/v5/nodes", nil)
+		assert.Nil(t, err)
+	}
+	// The connection is kept alive and reused by the requesters
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+
+	a, err := getTransport("", "https", &TLSConfig{ServerName: "example.com"})
+	assert.Nil(t, err)
+	b, err := getTransport("", "https", &TLSConfig{ServerName: "example.com"})
+	assert.Nil(t, err)
+	c, err := getTransport("", "https", &TLSConfig{ServerName: "example.org"})
+	assert.Nil(t, err)
+	assert.Same(t, a, b)
+	assert.NotSame(t, a, c)
+}
+
+func TestReleaseTransport(t *testing.T) {
+	oldKey := "https/" + (&TLSConfig{ServerName: "old.example.com"}).hash()
+	newKey := "https/" + (&TLSConfig{ServerName: "new.example.com"}).hash()
+	getKeys := func() (keys []string) {
+		transports.Lock()
+		defer transports.Unlock()
+		for key := range transports.m {
+			keys = append(keys, key)
+		}
+		return keys
+	}
+
+	t.Run("the transport is dropped when the TLS config of the cluster changes", func(t *testing.T) {
+		_, err := getTransport(clusterKey("EMQX", "default", "emqx"), "https", &TLSConfig{ServerName: "old.example.com"})
+		assert.Nil(t, err)
+		assert.Contains(t, getKeys(), oldKey)
+
+		_, err = getTransport(clusterKey("EMQX", "default", "emqx"), "https", &TLSConfig{ServerName: "new.example.com"})
+		assert.Nil(t, err)
+		assert.NotContains(t, getKeys(), oldKey)
+		assert.Contains(t, getKeys(), newKey)
+	})
+
+	t.Run("the transport used by another cluster is kept", func(t *testing.T) {
+		_, err := getTransport(clusterKey("EMQX", "default", "other"), "https", &TLSConfig{ServerName: "new.example.com"})
+		assert.Nil(t, err)
+
+		ForgetCluster("EMQX", "default", "emqx")
+		assert.Contains(t, getKeys(), newKey)
+
+		ForgetCluster("EMQX", "default", "other")
+		assert.NotContains(t, getKeys(), newKey)
+	})
+}
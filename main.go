@@ -41,6 +41,7 @@ import (
 	appsv2alpha2 "github.com/emqx/emqx-operator/apis/apps/v2alpha2"
 	appscontrollersv1beta4 "github.com/emqx/emqx-operator/controllers/apps/v1beta4"
 	appscontrollersv2alpha2 "github.com/emqx/emqx-operator/controllers/apps/v2alpha2"
+	innerReq "github.com/emqx/emqx-operator/internal/requester"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -71,23 +72,35 @@ func init() {
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=endpoints,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
 //+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;create;update
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var emqxResyncInterval time.Duration
+	var emqxAPIQPS float64
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&emqxResyncInterval, "emqx-resync-interval", appscontrollersv2alpha2.DefaultResyncInterval,
+		"The interval to reconcile the EMQX custom resources periodically, "+
+			"the EMQX cluster status from the management API is refreshed in this interval.")
+	flag.Float64Var(&emqxAPIQPS, "emqx-api-qps", float64(innerReq.QPS),
+		"The max number of requests per second to the management API of each EMQX cluster.")
+	flag.IntVar(&innerReq.Burst, "emqx-api-burst", innerReq.Burst,
+		"The max burst of requests to the management API of each EMQX cluster.")
 	opts := zap.Options{
 		TimeEncoder: zapcore.RFC3339TimeEncoder,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
+	innerReq.QPS = float32(emqxAPIQPS)
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
@@ -125,11 +138,18 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = appscontrollersv2alpha2.NewEMQXReconciler(mgr).SetupWithManager(mgr); err != nil {
+	emqxReconciler := appscontrollersv2alpha2.NewEMQXReconciler(mgr)
+	emqxReconciler.ResyncInterval = emqxResyncInterval
+	if err = emqxReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "EMQX")
 		os.Exit(1)
 	}
 
+	if err = appscontrollersv2alpha2.NewEMQXAutoscalerReconciler(mgr).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "EMQXAutoscaler")
+		os.Exit(1)
+	}
+
 	if err = appscontrollersv1beta4.NewRebalanceReconciler(mgr).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Rebalance")
 	}